@@ -44,7 +44,7 @@ func buildEventTimeCache(ht *historyType, idoColumns []string) {
 
 		// Stream source data...
 		sliceIdoHistory(
-			ht,
+			ht.snapshot, ht.fromId, ht.toId,
 			"SELECT "+strings.Join(idoColumns, ", ")+" FROM "+ht.idoTable+
 				// For actual migration icinga_objects will be joined anyway,
 				// so it makes no sense to take vanished objects into account.
@@ -155,7 +155,7 @@ func buildPreviousHardStateCache(ht *historyType, idoColumns []string) {
 
 		// Stream source data...
 		sliceIdoHistory(
-			ht,
+			ht.snapshot, ht.fromId, ht.toId,
 			"SELECT "+strings.Join(idoColumns, ", ")+" FROM "+ht.idoTable+
 				// For actual migration icinga_objects will be joined anyway,
 				// so it makes no sense to take vanished objects into account.
@@ -236,6 +236,15 @@ func buildPreviousHardStateCache(ht *historyType, idoColumns []string) {
 	ht.bar.SetTotal(ht.bar.Current(), true)
 }
 
+// markCacheFilled records in ht.cache that it has been filled up to ht.toId,
+// so that following runs don't have to fill it again. (On non-recoverable errors the whole program exits.)
+func markCacheFilled(ht *historyType) {
+	chunkCacheTx(ht.cache, func(tx **sqlx.Tx, _ func()) {
+		cacheExec(*tx, "DELETE FROM cache_filled")
+		cacheExec(*tx, "INSERT INTO cache_filled(to_id) VALUES (?)", ht.toId)
+	})
+}
+
 // chunkCacheTx rationale: during do operate on cache via *tx. After every completed operation call commitPeriodically()
 // which periodically commits *tx and starts a new tx. (That's why tx is a **, not just a *.)
 // (On non-recoverable errors the whole program exits.)
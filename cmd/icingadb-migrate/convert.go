@@ -10,7 +10,6 @@ import (
 	"github.com/icinga/icingadb/pkg/common"
 	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
 	"github.com/icinga/icingadb/pkg/icingadb/v1/history"
-	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	"math"
 	"strconv"
@@ -52,12 +51,13 @@ type commentRow = struct {
 
 func convertCommentRows(
 	env string, envId types.Binary,
-	_ func(any, string, ...any), _ *sqlx.Tx, idoRows []commentRow,
+	_ func(any, string, ...any), _ *idoSnapshot, idoRows []commentRow,
 ) (stages []icingaDbOutputStage, checkpoint any) {
 	var commentHistory, acknowledgementHistory, allHistoryComment, allHistoryAck []database.Entity
 
 	for _, row := range idoRows {
 		checkpoint = row.CommenthistoryId
+		row.Name1, row.Name2 = renames.rename(row.Name1, row.Name2)
 
 		if !row.EntryTime.Valid {
 			continue
@@ -238,12 +238,13 @@ type downtimeRow = struct {
 
 func convertDowntimeRows(
 	env string, envId types.Binary,
-	_ func(any, string, ...any), _ *sqlx.Tx, idoRows []downtimeRow,
+	_ func(any, string, ...any), _ *idoSnapshot, idoRows []downtimeRow,
 ) (stages []icingaDbOutputStage, checkpoint any) {
 	var downtimeHistory, allHistory, sla []database.Entity
 
 	for _, row := range idoRows {
 		checkpoint = row.DowntimehistoryId
+		row.Name1, row.Name2 = renames.rename(row.Name1, row.Name2)
 
 		if !row.ScheduledStartTime.Valid || row.WasStarted == 0 {
 			continue
@@ -395,7 +396,7 @@ type flappingRow = struct {
 
 func convertFlappingRows(
 	env string, envId types.Binary,
-	selectCache func(dest any, query string, args ...any), _ *sqlx.Tx, idoRows []flappingRow,
+	selectCache func(dest any, query string, args ...any), _ *idoSnapshot, idoRows []flappingRow,
 ) (stages []icingaDbOutputStage, checkpoint any) {
 	if len(idoRows) < 1 {
 		return
@@ -420,6 +421,7 @@ func convertFlappingRows(
 	var flappingHistory, flappingHistoryUpserts, allHistory []database.Entity
 	for _, row := range idoRows {
 		checkpoint = row.FlappinghistoryId
+		row.Name1, row.Name2 = renames.rename(row.Name1, row.Name2)
 
 		if !row.EventTime.Valid {
 			continue
@@ -553,7 +555,7 @@ type notificationRow = struct {
 
 func convertNotificationRows(
 	env string, envId types.Binary,
-	selectCache func(dest any, query string, args ...any), ido *sqlx.Tx, idoRows []notificationRow,
+	selectCache func(dest any, query string, args ...any), ido *idoSnapshot, idoRows []notificationRow,
 ) (stages []icingaDbOutputStage, checkpoint any) {
 	if len(idoRows) < 1 {
 		return
@@ -604,6 +606,7 @@ func convertNotificationRows(
 	var notificationHistory, userNotificationHistory, allHistory []database.Entity
 	for _, row := range idoRows {
 		checkpoint = row.NotificationId
+		row.Name1, row.Name2 = renames.rename(row.Name1, row.Name2)
 
 		if !row.EndTime.Valid {
 			continue
@@ -761,7 +764,7 @@ type stateRow = struct {
 
 func convertStateRows(
 	env string, envId types.Binary,
-	selectCache func(dest any, query string, args ...any), _ *sqlx.Tx, idoRows []stateRow,
+	selectCache func(dest any, query string, args ...any), _ *idoSnapshot, idoRows []stateRow,
 ) (stages []icingaDbOutputStage, checkpoint any) {
 	if len(idoRows) < 1 {
 		return
@@ -784,6 +787,7 @@ func convertStateRows(
 	var stateHistory, allHistory, sla []database.Entity
 	for _, row := range idoRows {
 		checkpoint = row.StatehistoryId
+		row.Name1, row.Name2 = renames.rename(row.Name1, row.Name2)
 
 		if !row.StateTime.Valid {
 			continue
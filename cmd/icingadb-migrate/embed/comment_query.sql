@@ -1,13 +1,13 @@
 SELECT
   ch.commenthistory_id,
-  UNIX_TIMESTAMP(ch.entry_time) AS entry_time,
+  {{ unixTimestamp "ch.entry_time" }} AS entry_time,
   ch.entry_time_usec,
   ch.entry_type,
   ch.author_name,
   ch.comment_data,
   ch.is_persistent,
-  COALESCE(UNIX_TIMESTAMP(ch.expiration_time), 0) AS expiration_time,
-  COALESCE(UNIX_TIMESTAMP(ch.deletion_time), 0) AS deletion_time,
+  COALESCE({{ unixTimestamp "ch.expiration_time" }}, 0) AS expiration_time,
+  COALESCE({{ unixTimestamp "ch.deletion_time" }}, 0) AS deletion_time,
   ch.deletion_time_usec,
   COALESCE(ch.name, CONCAT(o.name1, '!', COALESCE(o.name2, ''), '!', ch.commenthistory_id, '-', ch.object_id)) AS name,
   o.objecttype_id, o.name1, COALESCE(o.name2, '') AS name2
@@ -1,19 +1,19 @@
 SELECT
   dh.downtimehistory_id,
-  UNIX_TIMESTAMP(dh.entry_time) AS entry_time,
+  {{ unixTimestamp "dh.entry_time" }} AS entry_time,
   dh.author_name,
   dh.comment_data,
   dh.is_fixed,
   dh.duration,
-  UNIX_TIMESTAMP(dh.scheduled_start_time) AS scheduled_start_time,
-  COALESCE(UNIX_TIMESTAMP(dh.scheduled_end_time), 0) AS scheduled_end_time,
+  {{ unixTimestamp "dh.scheduled_start_time" }} AS scheduled_start_time,
+  COALESCE({{ unixTimestamp "dh.scheduled_end_time" }}, 0) AS scheduled_end_time,
   dh.was_started,
-  COALESCE(UNIX_TIMESTAMP(dh.actual_start_time), 0) AS actual_start_time,
+  COALESCE({{ unixTimestamp "dh.actual_start_time" }}, 0) AS actual_start_time,
   dh.actual_start_time_usec,
-  COALESCE(UNIX_TIMESTAMP(dh.actual_end_time), 0) AS actual_end_time,
+  COALESCE({{ unixTimestamp "dh.actual_end_time" }}, 0) AS actual_end_time,
   dh.actual_end_time_usec,
   dh.was_cancelled,
-  COALESCE(UNIX_TIMESTAMP(dh.trigger_time), 0) AS trigger_time,
+  COALESCE({{ unixTimestamp "dh.trigger_time" }}, 0) AS trigger_time,
   COALESCE(dh.name, CONCAT(o.name1, '!', COALESCE(o.name2, ''), '!', dh.downtimehistory_id, '-', dh.object_id)) AS name,
   o.objecttype_id,
   o.name1,
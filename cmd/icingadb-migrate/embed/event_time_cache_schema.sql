@@ -13,3 +13,8 @@ CREATE TABLE IF NOT EXISTS last_start_time (
     event_time      INT NOT NULL,
     event_time_usec INT NOT NULL
 );
+
+-- The IDO row ID up to which the cache has been filled completely, if so. Not to fill it again on resume.
+CREATE TABLE IF NOT EXISTS cache_filled (
+    to_id INT NOT NULL
+);
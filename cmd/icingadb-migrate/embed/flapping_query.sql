@@ -1,6 +1,6 @@
 SELECT
   fh.flappinghistory_id,
-  UNIX_TIMESTAMP(fh.event_time) AS event_time,
+  {{ unixTimestamp "fh.event_time" }} AS event_time,
   fh.event_time_usec,
   fh.event_type,
   fh.percent_state_change,
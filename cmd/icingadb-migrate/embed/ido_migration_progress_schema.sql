@@ -4,6 +4,7 @@ CREATE TABLE IF NOT EXISTS ido_migration_progress (
     from_ts        BIGINT      NOT NULL,
     to_ts          BIGINT      NOT NULL,
     last_ido_id    BIGINT      NOT NULL,
+    slice_to_id    BIGINT      NOT NULL DEFAULT 0, -- Last ID of the aligned slice range or 0 if not sliced
 
-    CONSTRAINT pk_ido_migration_progress PRIMARY KEY (environment_id, history_type, from_ts, to_ts)
+    CONSTRAINT pk_ido_migration_progress PRIMARY KEY (environment_id, history_type, from_ts, to_ts, slice_to_id)
 );
@@ -1,7 +1,7 @@
 SELECT
   n.notification_id,
   n.notification_reason,
-  UNIX_TIMESTAMP(n.end_time) AS end_time,
+  {{ unixTimestamp "n.end_time" }} AS end_time,
   n.end_time_usec,
   n.state,
   COALESCE(n.output, '') AS output,
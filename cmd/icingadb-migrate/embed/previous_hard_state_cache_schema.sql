@@ -20,3 +20,8 @@ CREATE TABLE IF NOT EXISTS next_ids (
 
 CREATE INDEX IF NOT EXISTS next_ids_object_id ON next_ids (object_id);
 CREATE INDEX IF NOT EXISTS next_ids_history_id ON next_ids (history_id);
+
+-- The IDO row ID up to which the cache has been filled completely, if so. Not to fill it again on resume.
+CREATE TABLE IF NOT EXISTS cache_filled (
+    to_id INT NOT NULL
+);
@@ -1,6 +1,6 @@
 SELECT
   sh.statehistory_id,
-  UNIX_TIMESTAMP(sh.state_time) AS state_time,
+  {{ unixTimestamp "sh.state_time" }} AS state_time,
   sh.state_time_usec,
   sh.state,
   sh.state_type,
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/icinga/icinga-go-library/config"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// idoPgsqlEnvPrefix prefixes the environment variables configuring the IDO PostgreSQL database of
+// TestIdoQueries_PostgreSQL like the ido section of the config file, e.g. ICINGADB_MIGRATE_TESTS_IDO_PGSQL_HOST.
+const idoPgsqlEnvPrefix = "ICINGADB_MIGRATE_TESTS_IDO_PGSQL_"
+
+// TestIdoQueries_PostgreSQL performs the IDO queries against a PostgreSQL database with the IDO schema imported,
+// e.g. from /usr/share/icinga2-ido-pgsql/schema/pgsql.sql, to catch errors in the PostgreSQL dialect which the
+// rendered query strings don't show. It is skipped unless ICINGADB_MIGRATE_TESTS_IDO_PGSQL_HOST is set.
+// The database is only read from, so it may contain any data.
+func TestIdoQueries_PostgreSQL(t *testing.T) {
+	if os.Getenv(idoPgsqlEnvPrefix+"HOST") == "" {
+		t.Skip("IDO PostgreSQL database not configured via " + idoPgsqlEnvPrefix + "* environment variables")
+	}
+
+	c := &database.Config{}
+	require.NoError(t, config.FromEnv(c, config.EnvOptions{Prefix: idoPgsqlEnvPrefix}))
+	c.Type = "pgsql"
+
+	ido, err := database.NewDbFromConfig(c, logging.NewLogger(zap.NewNop().Sugar(), time.Second), database.RetryConnectorCallbacks{})
+	require.NoError(t, err)
+	defer func() { _ = ido.Close() }()
+
+	snapshot := beginIdoSnapshot(ido)
+	defer func() { _ = snapshot.Rollback() }()
+
+	t.Run("unixTimestamp", func(t *testing.T) {
+		query := snapshot.Rebind(renderIdoQuery(database.PostgreSQL, `SELECT {{ unixTimestamp (fromUnixtime "?") }}`))
+
+		for _, ts := range []int32{1, 1700000000, math.MaxInt32} {
+			var actual int64
+			require.NoError(t, snapshot.Get(&actual, query, ts))
+			require.Equal(t, int64(ts), actual)
+		}
+	})
+
+	t.Run("computeIdRange", func(t *testing.T) {
+		startIdoTx(ido)
+		defer typesToMigrate.forEach(func(ht *historyType) {
+			_ = ht.snapshot.Rollback()
+			ht.snapshot, ht.fromId, ht.toId = nil, 0, 0
+		})
+
+		cfg := &Config{}
+		cfg.IDO.From, cfg.IDO.To = 0, math.MaxInt32
+
+		// Exits the whole test binary with a logged error if any of the queries fails.
+		computeIdRange(cfg)
+
+		for _, ht := range typesToMigrate {
+			if ht.toId > 0 {
+				require.LessOrEqual(t, ht.fromId, ht.toId, ht.name)
+			}
+		}
+	})
+
+	for _, ht := range typesToMigrate {
+		t.Run("migrationQuery/"+ht.name, func(t *testing.T) {
+			// Preparing the query makes PostgreSQL check it against the schema, including the types of all expressions.
+			stmt, err := snapshot.PrepareNamed(renderIdoQuery(database.PostgreSQL, ht.migrationQuery))
+			require.NoError(t, err)
+			require.NoError(t, stmt.Close())
+		})
+	}
+}
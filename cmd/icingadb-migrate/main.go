@@ -36,6 +36,8 @@ type Flags struct {
 	Config string `short:"c" long:"config" description:"path to config file" required:"true"`
 	// Cache is a (not necessarily yet existing) directory for caching.
 	Cache string `short:"t" long:"cache" description:"path for caching" required:"true"`
+	// Verify enables comparing the already migrated history with the IDO instead of migrating.
+	Verify bool `long:"verify" description:"verify the migrated history instead of migrating, without writing anything"`
 }
 
 // Config defines the YAML config structure.
@@ -44,6 +46,12 @@ type Config struct {
 		database.Config `yaml:"-,inline"`
 		From            int32 `yaml:"from"`
 		To              int32 `yaml:"to" default:"2147483647"`
+		// SliceSize splits the IDO rows of every history type by ID into slices of that many IDs, 0 disables slicing.
+		SliceSize uint64 `yaml:"slice_size"`
+		// Parallel limits the slices migrated in parallel per history type.
+		Parallel int `yaml:"parallel" default:"1"`
+		// MaxRowsPerSecond limits the IDO rows read per second in total, 0 means unlimited.
+		MaxRowsPerSecond int `yaml:"max_rows_per_second"`
 	} `yaml:"ido"`
 	IcingaDB database.Config `yaml:"icingadb"`
 	// Icinga2 specifies information the IDO doesn't provide.
@@ -51,6 +59,21 @@ type Config struct {
 		// Env specifies the environment ID, hex.
 		Env string `yaml:"env"`
 	} `yaml:"icinga2"`
+	// Rename translates IDO object names which differ from the Icinga DB ones.
+	Rename RenameConfig `yaml:"rename"`
+}
+
+// Validate checks constraints in the supplied config and returns an error if they are violated.
+func (c *Config) Validate() error {
+	if c.IDO.Parallel < 1 {
+		return errors.New("ido.parallel must be at least 1")
+	}
+
+	if c.IDO.MaxRowsPerSecond < 0 {
+		return errors.New("ido.max_rows_per_second must not be negative")
+	}
+
+	return nil
 }
 
 // main validates the CLI, parses the config and migrates history from IDO to Icinga DB (see comments below).
@@ -76,6 +99,14 @@ func main() {
 		os.Exit(2)
 	}
 
+	renames, err = newRenamer(&c.Rename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "bad rename config: %s\n", err.Error())
+		os.Exit(2)
+	}
+
+	idoThrottle = newThrottle(c.IDO.MaxRowsPerSecond)
+
 	defer func() { _ = log.Sync() }()
 
 	log.Info("Starting IDO to Icinga DB history migration")
@@ -86,6 +117,8 @@ func main() {
 		log.Fatalf("%+v", err)
 	}
 
+	reportUnmatchedObjects(c, ido, idb, envId)
+
 	// Start repeatable-read-isolated transactions (consistent SELECTs)
 	// not to have to care for IDO data changes during migration.
 	startIdoTx(ido)
@@ -98,6 +131,21 @@ func main() {
 	// Convert Config#IDO.From and .To to IDs to restrict data by PK.
 	computeIdRange(c)
 
+	if f.Verify {
+		computeCacheProgress()
+
+		log.Info("Filling cache")
+		fillCache()
+
+		log.Info("Verifying migrated history")
+		if !verify(c, idb, envId) {
+			_ = log.Sync()
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	// computeProgress figures out which data has already been migrated
 	// not to start from the beginning every time in the following migrate().
 	computeProgress(c, idb, envId)
@@ -107,7 +155,7 @@ func main() {
 	fillCache()
 
 	log.Info("Actually migrating")
-	migrate(c, idb, envId)
+	migrate(c, ido, idb, envId)
 
 	log.Info("Cleaning up cache")
 	cleanupCache(f)
@@ -135,6 +183,11 @@ func parseConfig(f *Flags) (_ *Config, exit int) {
 		return nil, 2
 	}
 
+	if err := c.Validate(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "invalid config: %s\n", err.Error())
+		return nil, 2
+	}
+
 	return c, -1
 }
 
@@ -218,15 +271,21 @@ func connect(which string, cfg *database.Config) *database.DB {
 // (On non-recoverable errors the whole program exits.)
 func startIdoTx(ido *database.DB) {
 	typesToMigrate.forEach(func(ht *historyType) {
-		tx, err := ido.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
-		if err != nil {
-			log.Fatalf("%+v", errors.Wrap(err, "can't begin snapshot transaction"))
-		}
-
-		ht.snapshot = tx
+		ht.snapshot = beginIdoSnapshot(ido)
 	})
 }
 
+// beginIdoSnapshot starts a new repeatable-read-isolated ido transaction.
+// (On non-recoverable errors the whole program exits.)
+func beginIdoSnapshot(ido *database.DB) *idoSnapshot {
+	tx, err := ido.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		log.Fatalf("%+v", errors.Wrap(err, "can't begin snapshot transaction"))
+	}
+
+	return &idoSnapshot{Tx: tx}
+}
+
 // computeIdRange initializes typesToMigrate[*].fromId and typesToMigrate[*].toId.
 // (On non-recoverable errors the whole program exits.)
 func computeIdRange(c *Config) {
@@ -246,10 +305,11 @@ func computeIdRange(c *Config) {
 				timeExpr = deZeroFied[0]
 			}
 
-			query := ht.snapshot.Rebind(
-				"SELECT " + ht.idoIdColumn + " FROM " + ht.idoTable + " WHERE " + timeExpr + " " + compOperator +
-					" COALESCE(FROM_UNIXTIME(?), FROM_UNIXTIME(?)) ORDER BY " + ht.idoIdColumn + " " + sortOrder + " LIMIT 1",
-			)
+			query := ht.snapshot.Rebind(renderIdoQuery(
+				ht.snapshot.DriverName(),
+				"SELECT "+ht.idoIdColumn+" FROM "+ht.idoTable+" WHERE "+timeExpr+" "+compOperator+
+					` COALESCE({{ fromUnixtime "?" }}, {{ fromUnixtime "?" }}) ORDER BY `+ht.idoIdColumn+" "+sortOrder+" LIMIT 1",
+			))
 
 			switch err := ht.snapshot.Get(id, query, borderTime, fallbackTime); err {
 			case nil, sql.ErrNoRows:
@@ -269,30 +329,119 @@ func computeIdRange(c *Config) {
 //go:embed embed/ido_migration_progress_schema.sql
 var idoMigrationProgressSchema string
 
-// computeProgress initializes typesToMigrate[*].lastId, typesToMigrate[*].total and typesToMigrate[*].done.
+// computeProgress initializes typesToMigrate[*].slices, typesToMigrate[*].total and typesToMigrate[*].done.
 // (On non-recoverable errors the whole program exits.)
 func computeProgress(c *Config, idb *database.DB, envId []byte) {
 	if _, err := idb.Exec(idoMigrationProgressSchema); err != nil {
 		log.Fatalf("%+v", errors.Wrap(err, "can't create table ido_migration_progress"))
 	}
 
+	upgradeIdoMigrationProgress(idb)
+
 	envIdHex := hex.EncodeToString(envId)
 	typesToMigrate.forEach(func(ht *historyType) {
+		var progress []struct {
+			SliceToId uint64
+			LastIdoId uint64
+		}
+
 		var query = idb.Rebind(
-			"SELECT last_ido_id FROM ido_migration_progress" +
+			"SELECT slice_to_id, last_ido_id FROM ido_migration_progress" +
 				" WHERE environment_id=? AND history_type=? AND from_ts=? AND to_ts=?",
 		)
 
 		args := []any{envIdHex, ht.name, c.IDO.From, c.IDO.To}
 
-		if err := idb.Get(&ht.lastId, query, args...); err != nil && err != sql.ErrNoRows {
+		if err := idb.Select(&progress, query, args...); err != nil {
 			log.With("backend", "Icinga DB", "query", query, "args", args).
 				Fatalf("%+v", errors.Wrap(err, "can't perform query"))
 		}
+
+		lastIds := make(map[uint64]uint64, len(progress))
+		for _, p := range progress {
+			lastIds[p.SliceToId] = p.LastIdoId
+		}
+
+		ht.slices = splitIdRange(ht.fromId, ht.toId, c.IDO.SliceSize)
+		resumeSlices(ht.slices, lastIds)
 	})
 
+	computeCacheProgress()
+
+	typesToMigrate.forEach(func(ht *historyType) {
+		query := ht.snapshot.Rebind(
+			// For actual migration icinga_objects will be joined anyway,
+			// so it makes no sense to take vanished objects into account.
+			"SELECT COUNT(*) FROM " + ht.idoTable + " xh INNER JOIN icinga_objects o ON o.object_id=xh.object_id" +
+				" WHERE xh." + ht.idoIdColumn + " BETWEEN ? AND ?",
+		)
+
+		count := func(fromId, toId uint64) (cnt int64) {
+			if err := ht.snapshot.Get(&cnt, query, fromId, toId); err != nil {
+				log.Fatalf("%+v", errors.Wrap(err, "can't count query"))
+			}
+
+			return
+		}
+
+		ht.total = count(ht.fromId, ht.toId)
+
+		for _, slice := range ht.slices {
+			if slice.lastId >= slice.fromId {
+				ht.done += count(slice.fromId, min(slice.lastId, slice.toId))
+			}
+		}
+
+		log.Infow("Counted migrated IDO events",
+			"type", ht.name, "migrated", ht.done, "total", ht.total, "slices", len(ht.slices))
+	})
+}
+
+// upgradeIdoMigrationProgress adds the column slice_to_id to the table ido_migration_progress
+// if it has been created by an older version. (On non-recoverable errors the whole program exits.)
+func upgradeIdoMigrationProgress(idb *database.DB) {
+	schema := "DATABASE()"
+	dropPk := "DROP PRIMARY KEY"
+	if idb.DriverName() == database.PostgreSQL {
+		schema = "CURRENT_SCHEMA()"
+		dropPk = "DROP CONSTRAINT pk_ido_migration_progress"
+	}
+
+	var columns int
+	query := "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema=" + schema +
+		" AND table_name='ido_migration_progress' AND column_name='slice_to_id'"
+
+	if err := idb.Get(&columns, query); err != nil {
+		log.With("backend", "Icinga DB", "query", query).Fatalf("%+v", errors.Wrap(err, "can't perform query"))
+	}
+
+	if columns > 0 {
+		return
+	}
+
+	ddl := "ALTER TABLE ido_migration_progress ADD COLUMN slice_to_id BIGINT NOT NULL DEFAULT 0, " + dropPk +
+		", ADD CONSTRAINT pk_ido_migration_progress PRIMARY KEY (environment_id, history_type, from_ts, to_ts, slice_to_id)"
+
+	if _, err := idb.Exec(ddl); err != nil {
+		log.With("backend", "Icinga DB", "ddl", ddl).
+			Fatalf("%+v", errors.Wrap(err, "can't upgrade table ido_migration_progress"))
+	}
+}
+
+// computeCacheProgress initializes typesToMigrate[*].cacheTotal and typesToMigrate[*].cacheFilled.
+// (On non-recoverable errors the whole program exits.)
+func computeCacheProgress() {
 	typesToMigrate.forEach(func(ht *historyType) {
 		if ht.cacheFiller != nil {
+			var filledTo sql.NullInt64
+			cacheGet(ht.cache, &filledTo, "SELECT MAX(to_id) FROM cache_filled")
+
+			// A previous run has already filled the cache, so there's no need to stream the IDO again.
+			if filledTo.Valid && uint64(filledTo.Int64) >= ht.toId {
+				ht.cacheFilled = true
+				return
+			}
+
 			err := ht.snapshot.Get(
 				&ht.cacheTotal,
 				ht.snapshot.Rebind(
@@ -308,52 +457,21 @@ func computeProgress(c *Config, idb *database.DB, envId []byte) {
 			}
 		}
 	})
-
-	typesToMigrate.forEach(func(ht *historyType) {
-		var rows []struct {
-			Migrated uint8
-			Cnt      int64
-		}
-
-		err := ht.snapshot.Select(
-			&rows,
-			ht.snapshot.Rebind(
-				// For actual migration icinga_objects will be joined anyway,
-				// so it makes no sense to take vanished objects into account.
-				"SELECT CASE WHEN xh."+ht.idoIdColumn+"<=? THEN 1 ELSE 0 END migrated, COUNT(*) cnt FROM "+
-					ht.idoTable+" xh INNER JOIN icinga_objects o ON o.object_id=xh.object_id WHERE xh."+
-					ht.idoIdColumn+" BETWEEN ? AND ? GROUP BY migrated",
-			),
-			ht.lastId, ht.fromId, ht.toId,
-		)
-		if err != nil {
-			log.Fatalf("%+v", errors.Wrap(err, "can't count query"))
-		}
-
-		for _, row := range rows {
-			ht.total += row.Cnt
-
-			if row.Migrated == 1 {
-				ht.done = row.Cnt
-			}
-		}
-
-		log.Infow("Counted migrated IDO events", "type", ht.name, "migrated", ht.done, "total", ht.total)
-	})
 }
 
 // fillCache fills <f.Cache>/<history type>.sqlite3 (actually typesToMigrate[*].cacheFiller does).
 func fillCache() {
 	progress := mpb.New()
 	for _, ht := range typesToMigrate {
-		if ht.cacheFiller != nil {
+		if ht.cacheFiller != nil && !ht.cacheFilled {
 			ht.setupBar(progress, ht.cacheTotal)
 		}
 	}
 
 	typesToMigrate.forEach(func(ht *historyType) {
-		if ht.cacheFiller != nil {
+		if ht.cacheFiller != nil && !ht.cacheFilled {
 			ht.cacheFiller(ht)
+			markCacheFilled(ht)
 		}
 	})
 
@@ -361,36 +479,122 @@ func fillCache() {
 }
 
 // migrate does the actual migration.
-func migrate(c *Config, idb *database.DB, envId []byte) {
+func migrate(c *Config, ido, idb *database.DB, envId []byte) {
 	progress := mpb.New()
 	for _, ht := range typesToMigrate {
 		ht.setupBar(progress, ht.total)
 	}
 
 	typesToMigrate.forEach(func(ht *historyType) {
-		ht.migrate(c, idb, envId, ht)
+		ht.migrate(c, ido, idb, envId, ht)
 	})
 
 	progress.Wait()
 }
 
 // migrate does the actual migration for one history type.
+// Up to Config#IDO.Parallel of ht.slices are migrated in parallel. All workers read from ht.snapshot, so that
+// they see exactly the IDO data the ID range, the progress and the cache have been computed from.
 func migrateOneType[IdoRow any](
-	c *Config, idb *database.DB, envId []byte, ht *historyType,
+	c *Config, _, idb *database.DB, envId []byte, ht *historyType,
 	convertRows func(env string, envId types.Binary,
-		selectCache func(dest any, query string, args ...any), ido *sqlx.Tx,
+		selectCache func(dest any, query string, args ...any), ido *idoSnapshot,
 		idoRows []IdoRow) (stages []icingaDbOutputStage, checkpoint any),
 ) {
+	args := cacheLimitArgs(ht)
+
+	upsertProgress, _ := idb.BuildUpsertStmt(&IdoMigrationProgress{})
+	envIdHex := hex.EncodeToString(envId)
+
+	migrateSlice := func(selectCache func(dest any, query string, args ...any), slice *idoSlice) {
+		// Stream IDO rows, ...
+		sliceIdoHistory(
+			ht.snapshot, slice.fromId, slice.toId, ht.migrationQuery, args, slice.lastId,
+			func(idoRows []IdoRow) (checkpoint any) {
+				// ... convert them, ...
+				stages, lastIdoId := convertRows(c.Icinga2.Env, envId, selectCache, ht.snapshot, idoRows)
+
+				// ... and insert them:
+
+				for _, stage := range stages {
+					if len(stage.insert) > 0 {
+						ch := utils.ChanFromSlice(stage.insert)
+
+						if err := idb.CreateIgnoreStreamed(context.Background(), ch); err != nil {
+							log.With("backend", "Icinga DB", "op", "INSERT IGNORE", "table", database.TableName(stage.insert[0])).
+								Fatalf("%+v", errors.Wrap(err, "can't perform DML"))
+						}
+					}
+
+					if len(stage.upsert) > 0 {
+						ch := utils.ChanFromSlice(stage.upsert)
+
+						if err := idb.UpsertStreamed(context.Background(), ch); err != nil {
+							log.With("backend", "Icinga DB", "op", "UPSERT", "table", database.TableName(stage.upsert[0])).
+								Fatalf("%+v", errors.Wrap(err, "can't perform DML"))
+						}
+					}
+				}
+
+				if lastIdoId != nil {
+					args := map[string]any{"history_type": ht.name, "slice_to_id": slice.id, "last_ido_id": lastIdoId}
+
+					_, err := idb.NamedExec(upsertProgress, &IdoMigrationProgress{
+						IdoMigrationProgressUpserter{lastIdoId}, envIdHex, ht.name, c.IDO.From, c.IDO.To, slice.id,
+					})
+					if err != nil {
+						log.With("backend", "Icinga DB", "dml", upsertProgress, "args", args).
+							Fatalf("%+v", errors.Wrap(err, "can't perform DML"))
+					}
+				}
+
+				ht.bar.IncrBy(len(idoRows))
+				return lastIdoId
+			},
+		)
+	}
+
+	ht.bar.SetCurrent(ht.done)
+
+	slices := make(chan *idoSlice, len(ht.slices))
+	for _, slice := range ht.slices {
+		slices <- slice
+	}
+	close(slices)
+
+	eg, _ := errgroup.WithContext(context.Background())
+	for range min(c.IDO.Parallel, len(ht.slices)) {
+		eg.Go(func() error {
+			selectCache, closeCache := prepareCacheSelect(ht)
+			defer closeCache()
+
+			for slice := range slices {
+				migrateSlice(selectCache, slice)
+			}
+
+			return nil
+		})
+	}
+
+	_ = eg.Wait()
+
+	ht.bar.SetTotal(ht.bar.Current(), true)
+}
+
+// prepareCacheSelect returns a function performing SELECT queries on ht.cache which re-uses the last prepared
+// statement as long as the query doesn't change and a function closing it.
+// (On non-recoverable errors the whole program exits.)
+func prepareCacheSelect(ht *historyType) (selectCache func(dest any, query string, args ...any), closeCache func()) {
 	var lastQuery string
 	var lastStmt *sqlx.Stmt
 
-	defer func() {
+	closeCache = func() {
 		if lastStmt != nil {
 			_ = lastStmt.Close()
 		}
-	}()
+	}
 
-	selectCache := func(dest any, query string, args ...any) {
+	selectCache = func(dest any, query string, args ...any) {
 		// Prepare new one, if old one doesn't fit anymore.
 		if query != lastQuery {
 			if lastStmt != nil {
@@ -399,7 +603,7 @@ func migrateOneType[IdoRow any](
 
 			var err error
 
-			lastStmt, err = ht.cache.Preparex(query) //nolint:sqlclosecheck // either closed in the if block above or in the defer func
+			lastStmt, err = ht.cache.Preparex(query) //nolint:sqlclosecheck // either closed in the if block above or by closeCache
 			if err != nil {
 				log.With("backend", "cache", "query", query).
 					Fatalf("%+v", errors.Wrap(err, "can't prepare query"))
@@ -414,68 +618,21 @@ func migrateOneType[IdoRow any](
 		}
 	}
 
-	var args map[string]any
+	return
+}
 
+// cacheLimitArgs returns the query args limiting the source data set of ht to the cache, if necessary.
+func cacheLimitArgs(ht *historyType) map[string]any {
 	// For the case that the cache was older that the IDO,
 	// but ht.cacheFiller couldn't update it, limit (WHERE) our source data set.
-	if ht.cacheLimitQuery != "" {
-		var limit sql.NullInt64
-		cacheGet(ht.cache, &limit, ht.cacheLimitQuery)
-		args = map[string]any{"cache_limit": limit.Int64}
+	if ht.cacheLimitQuery == "" {
+		return nil
 	}
 
-	upsertProgress, _ := idb.BuildUpsertStmt(&IdoMigrationProgress{})
-	envIdHex := hex.EncodeToString(envId)
-
-	ht.bar.SetCurrent(ht.done)
-
-	// Stream IDO rows, ...
-	sliceIdoHistory(
-		ht, ht.migrationQuery, args, ht.lastId,
-		func(idoRows []IdoRow) (checkpoint any) {
-			// ... convert them, ...
-			stages, lastIdoId := convertRows(c.Icinga2.Env, envId, selectCache, ht.snapshot, idoRows)
-
-			// ... and insert them:
+	var limit sql.NullInt64
+	cacheGet(ht.cache, &limit, ht.cacheLimitQuery)
 
-			for _, stage := range stages {
-				if len(stage.insert) > 0 {
-					ch := utils.ChanFromSlice(stage.insert)
-
-					if err := idb.CreateIgnoreStreamed(context.Background(), ch); err != nil {
-						log.With("backend", "Icinga DB", "op", "INSERT IGNORE", "table", database.TableName(stage.insert[0])).
-							Fatalf("%+v", errors.Wrap(err, "can't perform DML"))
-					}
-				}
-
-				if len(stage.upsert) > 0 {
-					ch := utils.ChanFromSlice(stage.upsert)
-
-					if err := idb.UpsertStreamed(context.Background(), ch); err != nil {
-						log.With("backend", "Icinga DB", "op", "UPSERT", "table", database.TableName(stage.upsert[0])).
-							Fatalf("%+v", errors.Wrap(err, "can't perform DML"))
-					}
-				}
-			}
-
-			if lastIdoId != nil {
-				args := map[string]any{"history_type": ht.name, "last_ido_id": lastIdoId}
-
-				_, err := idb.NamedExec(upsertProgress, &IdoMigrationProgress{
-					IdoMigrationProgressUpserter{lastIdoId}, envIdHex, ht.name, c.IDO.From, c.IDO.To,
-				})
-				if err != nil {
-					log.With("backend", "Icinga DB", "dml", upsertProgress, "args", args).
-						Fatalf("%+v", errors.Wrap(err, "can't perform DML"))
-				}
-			}
-
-			ht.bar.IncrBy(len(idoRows))
-			return lastIdoId
-		},
-	)
-
-	ht.bar.SetTotal(ht.bar.Current(), true)
+	return map[string]any{"cache_limit": limit.Int64}
 }
 
 // cleanupCache removes <f.Cache>/<history type>.sqlite3 files.
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	c := &Config{}
+	require.NoError(t, defaults.Set(c))
+	require.NoError(t, c.Validate())
+	require.Equal(t, 1, c.IDO.Parallel)
+
+	c.IDO.Parallel = 0
+	require.ErrorContains(t, c.Validate(), "ido.parallel must be at least 1")
+
+	c.IDO.Parallel = 4
+	c.IDO.MaxRowsPerSecond = -1
+	require.ErrorContains(t, c.Validate(), "ido.max_rows_per_second must not be negative")
+}
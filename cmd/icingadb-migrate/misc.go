@@ -12,7 +12,10 @@ import (
 	"github.com/vbauerster/mpb/v6/decor"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
+	"maps"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 )
 
@@ -31,6 +34,7 @@ type IdoMigrationProgress struct {
 	HistoryType                  string `json:"history_type"`
 	FromTs                       int32  `json:"from_ts"`
 	ToTs                         int32  `json:"to_ts"`
+	SliceToId                    uint64 `json:"slice_to_id"`
 }
 
 // Assert interface compliance.
@@ -89,42 +93,83 @@ func calcServiceId(env, name1, name2 string) []byte {
 	return hashAny([2]string{env, name1 + "!" + name2})
 }
 
-// sliceIdoHistory performs query with args+fromid,toid,checkpoint,bulk on ht.snapshot
+// objectName returns the host name name1 or, if name2 is not empty, the full service name.
+func objectName(name1, name2 string) string {
+	if name2 == "" {
+		return name1
+	}
+
+	return name1 + "!" + name2
+}
+
+// idoQueryFuncs are the functions available in IDO query templates per SQL driver, abstracting dialect differences.
+var idoQueryFuncs = map[string]template.FuncMap{
+	database.MySQL: {
+		// unixTimestamp converts a timestamp column to a *nix timestamp.
+		"unixTimestamp": func(column string) string { return "UNIX_TIMESTAMP(" + column + ")" },
+		// fromUnixtime converts a *nix timestamp, usually a placeholder, to a timestamp.
+		"fromUnixtime": func(expr string) string { return "FROM_UNIXTIME(" + expr + ")" },
+	},
+	database.PostgreSQL: {
+		"unixTimestamp": func(column string) string { return "CAST(EXTRACT(EPOCH FROM " + column + ") AS bigint)" },
+		"fromUnixtime":  func(expr string) string { return "TO_TIMESTAMP(" + expr + ")" },
+	},
+}
+
+// renderIdoQuery renders the IDO query template for the SQL driver driverName.
+// (On non-recoverable errors the whole program exits.)
+func renderIdoQuery(driverName, query string) string {
+	funcs, ok := idoQueryFuncs[driverName]
+	if !ok {
+		log.With("driver", driverName).Fatal("unsupported IDO database type")
+	}
+
+	tmpl, err := template.New("query").Funcs(funcs).Parse(query)
+	if err != nil {
+		log.With("query", query).Fatalf("%+v", errors.Wrap(err, "can't parse query template"))
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, nil); err != nil {
+		log.With("query", query).Fatalf("%+v", errors.Wrap(err, "can't render query template"))
+	}
+
+	query = rendered.String()
+	if driverName != database.MySQL {
+		query = strings.ReplaceAll(query, " USE INDEX (PRIMARY)", "")
+	}
+
+	return query
+}
+
+// sliceIdoHistory renders the query template (see renderIdoQuery) and performs it with args+fromid,toid,checkpoint,bulk on snapshot
 // and passes the results to onRows until either an empty result set or onRows() returns nil.
 // Rationale: split the likely large result set of a query by adding a WHERE condition and a LIMIT,
 // both with :named placeholders (:checkpoint, :bulk).
 // checkpoint is the initial value for the WHERE condition, onRows() returns follow-up ones.
-// (On non-recoverable errors the whole program exits.)
+// The rows read are accounted to idoThrottle. (On non-recoverable errors the whole program exits.)
 func sliceIdoHistory[Row any](
-	ht *historyType, query string, args map[string]any,
+	snapshot *idoSnapshot, fromId, toId uint64, query string, args map[string]any,
 	checkpoint any, onRows func([]Row) (checkpoint any),
 ) {
+	// Don't modify the caller's args, they may be shared among slices.
+	args = maps.Clone(args)
 	if args == nil {
 		args = map[string]any{}
 	}
 
-	args["fromid"] = ht.fromId
-	args["toid"] = ht.toId
+	args["fromid"] = fromId
+	args["toid"] = toId
 	args["checkpoint"] = checkpoint
 	args["bulk"] = 20000
 
-	if ht.snapshot.DriverName() != database.MySQL {
-		query = strings.ReplaceAll(query, " USE INDEX (PRIMARY)", "")
-	}
+	query = renderIdoQuery(snapshot.DriverName(), query)
 
 	for {
-		// TODO: use Tx#SelectNamed() one nice day (https://github.com/jmoiron/sqlx/issues/779)
-		stmt, err := ht.snapshot.PrepareNamed(query)
-		if err != nil {
-			log.With("query", query).Fatalf("%+v", errors.Wrap(err, "can't prepare query"))
-		}
-
 		var rows []Row
-		if err := stmt.Select(&rows, args); err != nil {
-			log.With("query", query).Fatalf("%+v", errors.Wrap(err, "can't perform query"))
-		}
+		snapshot.selectNamed(&rows, query, args)
 
-		_ = stmt.Close() //nolint:sqlclosecheck // no function exit between stmt creation and here, no reason for deferred close
+		idoThrottle.wait(len(rows))
 
 		if len(rows) < 1 {
 			break
@@ -141,6 +186,8 @@ func sliceIdoHistory[Row any](
 type progressBar struct {
 	*mpb.Bar
 
+	// mu protects lastUpdate as multiple slices of a history type are migrated in parallel.
+	mu         sync.Mutex
 	lastUpdate time.Time
 }
 
@@ -148,6 +195,9 @@ type progressBar struct {
 func (pb *progressBar) IncrBy(n int) {
 	pb.Bar.IncrBy(n)
 
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
 	now := time.Now()
 
 	if !pb.lastUpdate.IsZero() {
@@ -178,14 +228,16 @@ type historyType struct {
 	// migrationQuery SELECTs source data for actual migration.
 	migrationQuery string
 	// migrate does the actual migration.
-	migrate func(c *Config, idb *database.DB, envId []byte, ht *historyType)
+	migrate func(c *Config, ido, idb *database.DB, envId []byte, ht *historyType)
+	// verify compares the source data with the already migrated one.
+	verify func(c *Config, idb *database.DB, envId []byte, ht *historyType)
 
 	// cacheFile locates <name>.sqlite3.
 	cacheFile string
 	// cache represents <cacheFile>.
 	cache *sqlx.DB
 	// snapshot represents the data source.
-	snapshot *sqlx.Tx
+	snapshot *idoSnapshot
 	// fromId is the first IDO row ID to migrate.
 	fromId uint64
 	// toId is the last IDO row ID to migrate.
@@ -194,12 +246,16 @@ type historyType struct {
 	total int64
 	// cacheTotal summarizes the cache source data.
 	cacheTotal int64
+	// cacheFilled tells whether the cache has already been filled up to toId by a previous run.
+	cacheFilled bool
 	// done summarizes the migrated data.
 	done int64
 	// bar represents the current progress bar.
 	bar *progressBar
-	// lastId is the last already migrated ID.
-	lastId uint64
+	// slices split the range from fromId to toId for parallel migration.
+	slices []*idoSlice
+	// verification summarizes the result of verify.
+	verification *verification
 }
 
 // setupBar (re-)initializes ht.bar.
@@ -219,6 +275,142 @@ func (ht *historyType) setupBar(progress *mpb.Progress, total int64) {
 	)}
 }
 
+// idoSlice is a range of IDO row IDs of a history type with its own migration progress.
+type idoSlice struct {
+	// id identifies the slice in ido_migration_progress. It's 0 if the history type isn't sliced.
+	id uint64
+	// fromId is the first IDO row ID of the slice.
+	fromId uint64
+	// toId is the last IDO row ID of the slice.
+	toId uint64
+	// lastId is the last already migrated ID.
+	lastId uint64
+}
+
+// idoSnapshot is a repeatable-read-isolated IDO transaction shared among goroutines.
+// A transaction can't perform multiple queries at once, so mu serializes them.
+type idoSnapshot struct {
+	*sqlx.Tx
+
+	mu sync.Mutex
+}
+
+// Get wraps sqlx.Tx#Get.
+func (s *idoSnapshot) Get(dest any, query string, args ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Tx.Get(dest, query, args...)
+}
+
+// Select wraps sqlx.Tx#Select.
+func (s *idoSnapshot) Select(dest any, query string, args ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Tx.Select(dest, query, args...)
+}
+
+// selectNamed performs the SELECT query with named args and scans the resulting rows into dest.
+// (On non-recoverable errors the whole program exits.)
+func (s *idoSnapshot) selectNamed(dest any, query string, args any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// TODO: use Tx#SelectNamed() one nice day (https://github.com/jmoiron/sqlx/issues/779)
+	stmt, err := s.PrepareNamed(query)
+	if err != nil {
+		log.With("query", query).Fatalf("%+v", errors.Wrap(err, "can't prepare query"))
+	}
+	defer func() { _ = stmt.Close() }()
+
+	if err := stmt.Select(dest, args); err != nil {
+		log.With("query", query).Fatalf("%+v", errors.Wrap(err, "can't perform query"))
+	}
+}
+
+// resumeSlices sets the lastId of the slices from the last IDO row IDs migrated so far by slice ID.
+// The progress of an unsliced run (slice ID 0) covers the slices up to its last ID,
+// so that enabling slicing doesn't start over.
+func resumeSlices(slices []*idoSlice, lastIds map[uint64]uint64) {
+	unsliced, hasUnsliced := lastIds[0]
+
+	for _, slice := range slices {
+		slice.lastId = lastIds[slice.id]
+
+		if hasUnsliced && unsliced >= slice.fromId {
+			slice.lastId = max(slice.lastId, min(unsliced, slice.toId))
+		}
+	}
+}
+
+// splitIdRange splits the IDO row IDs from fromId to toId into slices of up to size IDs.
+// The slices are aligned to multiples of size and identified by the last ID of their aligned range
+// not to depend on fromId which may change over time, e.g. due to IDO history cleanup.
+// A size of 0 disables splitting and returns one slice with the id 0.
+func splitIdRange(fromId, toId, size uint64) []*idoSlice {
+	if fromId > toId {
+		return nil
+	}
+
+	if size == 0 {
+		return []*idoSlice{{fromId: fromId, toId: toId}}
+	}
+
+	var slices []*idoSlice
+	for end := (fromId-1)/size*size + size; ; end += size {
+		slices = append(slices, &idoSlice{id: end, fromId: max(end-size+1, fromId), toId: min(end, toId)})
+
+		if end >= toId {
+			return slices
+		}
+	}
+}
+
+// throttle limits the rate of something per second. A nil *throttle doesn't limit anything.
+type throttle struct {
+	perSecond int
+
+	mu sync.Mutex
+	// next is the time from which on something may happen again.
+	next time.Time
+}
+
+// idoThrottle limits the IDO rows read per second.
+var idoThrottle *throttle
+
+// newThrottle returns a new throttle for perSecond things per second or nil if perSecond is 0.
+func newThrottle(perSecond int) *throttle {
+	if perSecond < 1 {
+		return nil
+	}
+
+	return &throttle{perSecond: perSecond}
+}
+
+// wait accounts n things which have just happened and blocks until more things may happen.
+func (t *throttle) wait(n int) {
+	if t == nil || n < 1 {
+		return
+	}
+
+	time.Sleep(t.delay(time.Now(), n))
+}
+
+// delay accounts n things which have happened at now and returns the time to wait until more things may happen.
+func (t *throttle) delay(now time.Time, n int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.next.Before(now) {
+		t.next = now
+	}
+
+	t.next = t.next.Add(time.Duration(n) * time.Second / time.Duration(t.perSecond))
+
+	return t.next.Sub(now)
+}
+
 type historyTypes []*historyType
 
 // forEach performs f per hts in parallel.
@@ -247,8 +439,11 @@ var typesToMigrate = historyTypes{
 		// Manual deletion time wins vs. time of expiration which never happens due to manual deletion.
 		idoEndColumns:  []string{"deletion_time", "expiration_time"},
 		migrationQuery: commentMigrationQuery,
-		migrate: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
-			migrateOneType(c, idb, envId, ht, convertCommentRows)
+		migrate: func(c *Config, ido, idb *database.DB, envId []byte, ht *historyType) {
+			migrateOneType(c, ido, idb, envId, ht, convertCommentRows)
+		},
+		verify: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
+			verifyOneType(c, idb, envId, ht, convertCommentRows)
 		},
 	},
 	{
@@ -259,8 +454,11 @@ var typesToMigrate = historyTypes{
 		idoStartColumns: []string{"actual_start_time", "scheduled_start_time"},
 		idoEndColumns:   []string{"actual_end_time", "scheduled_end_time"},
 		migrationQuery:  downtimeMigrationQuery,
-		migrate: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
-			migrateOneType(c, idb, envId, ht, convertDowntimeRows)
+		migrate: func(c *Config, ido, idb *database.DB, envId []byte, ht *historyType) {
+			migrateOneType(c, ido, idb, envId, ht, convertDowntimeRows)
+		},
+		verify: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
+			verifyOneType(c, idb, envId, ht, convertDowntimeRows)
 		},
 	},
 	{
@@ -272,13 +470,16 @@ var typesToMigrate = historyTypes{
 		cacheSchema:     eventTimeCacheSchema,
 		cacheFiller: func(ht *historyType) {
 			buildEventTimeCache(ht, []string{
-				"xh.flappinghistory_id id", "UNIX_TIMESTAMP(xh.event_time) event_time",
+				"xh.flappinghistory_id id", `{{ unixTimestamp "xh.event_time" }} event_time`,
 				"xh.event_time_usec", "1001-xh.event_type event_is_start", "xh.object_id",
 			})
 		},
 		migrationQuery: flappingMigrationQuery,
-		migrate: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
-			migrateOneType(c, idb, envId, ht, convertFlappingRows)
+		migrate: func(c *Config, ido, idb *database.DB, envId []byte, ht *historyType) {
+			migrateOneType(c, ido, idb, envId, ht, convertFlappingRows)
+		},
+		verify: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
+			verifyOneType(c, idb, envId, ht, convertFlappingRows)
 		},
 	},
 	{
@@ -295,8 +496,11 @@ var typesToMigrate = historyTypes{
 		},
 		cacheLimitQuery: "SELECT MAX(history_id) FROM previous_hard_state",
 		migrationQuery:  notificationMigrationQuery,
-		migrate: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
-			migrateOneType(c, idb, envId, ht, convertNotificationRows)
+		migrate: func(c *Config, ido, idb *database.DB, envId []byte, ht *historyType) {
+			migrateOneType(c, ido, idb, envId, ht, convertNotificationRows)
+		},
+		verify: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
+			verifyOneType(c, idb, envId, ht, convertNotificationRows)
 		},
 	},
 	{
@@ -311,8 +515,11 @@ var typesToMigrate = historyTypes{
 		},
 		cacheLimitQuery: "SELECT MAX(history_id) FROM previous_hard_state",
 		migrationQuery:  stateMigrationQuery,
-		migrate: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
-			migrateOneType(c, idb, envId, ht, convertStateRows)
+		migrate: func(c *Config, ido, idb *database.DB, envId []byte, ht *historyType) {
+			migrateOneType(c, ido, idb, envId, ht, convertStateRows)
+		},
+		verify: func(c *Config, idb *database.DB, envId []byte, ht *historyType) {
+			verifyOneType(c, idb, envId, ht, convertStateRows)
 		},
 	},
 }
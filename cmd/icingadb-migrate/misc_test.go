@@ -0,0 +1,131 @@
+package main
+
+import (
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRenderIdoQuery(t *testing.T) {
+	const query = `SELECT {{ unixTimestamp "xh.event_time" }} event_time FROM icinga_flappinghistory xh USE INDEX (PRIMARY)` +
+		` WHERE xh.event_time >= {{ fromUnixtime "?" }}`
+
+	require.Equal(t,
+		"SELECT UNIX_TIMESTAMP(xh.event_time) event_time FROM icinga_flappinghistory xh USE INDEX (PRIMARY)"+
+			" WHERE xh.event_time >= FROM_UNIXTIME(?)",
+		renderIdoQuery(database.MySQL, query))
+
+	require.Equal(t,
+		"SELECT CAST(EXTRACT(EPOCH FROM xh.event_time) AS bigint) event_time FROM icinga_flappinghistory xh"+
+			" WHERE xh.event_time >= TO_TIMESTAMP(?)",
+		renderIdoQuery(database.PostgreSQL, query))
+}
+
+func TestRenderIdoQuery_migrationQueries(t *testing.T) {
+	for _, ht := range typesToMigrate {
+		t.Run(ht.name, func(t *testing.T) {
+			mysql := renderIdoQuery(database.MySQL, ht.migrationQuery)
+			require.NotContains(t, mysql, "{{")
+			require.Contains(t, mysql, "UNIX_TIMESTAMP(")
+			require.Contains(t, mysql, "USE INDEX (PRIMARY)")
+
+			pgsql := renderIdoQuery(database.PostgreSQL, ht.migrationQuery)
+			require.NotContains(t, pgsql, "{{")
+			require.NotContains(t, pgsql, "UNIX_TIMESTAMP(")
+			require.NotContains(t, pgsql, "USE INDEX")
+			require.Contains(t, pgsql, "EXTRACT(EPOCH FROM ")
+		})
+	}
+}
+
+func TestSplitIdRange(t *testing.T) {
+	subtests := []struct {
+		name           string
+		fromId, toId   uint64
+		size           uint64
+		expectedSlices []idoSlice
+	}{
+		{name: "empty", fromId: math.MaxInt64, toId: 0, size: 10},
+		{name: "unsliced", fromId: 5, toId: 42, expectedSlices: []idoSlice{{fromId: 5, toId: 42}}},
+		{name: "single", fromId: 3, toId: 7, size: 10, expectedSlices: []idoSlice{{id: 10, fromId: 3, toId: 7}}},
+		{name: "one-id", fromId: 10, toId: 10, size: 10, expectedSlices: []idoSlice{{id: 10, fromId: 10, toId: 10}}},
+		{
+			name: "aligned", fromId: 1, toId: 30, size: 10,
+			expectedSlices: []idoSlice{{id: 10, fromId: 1, toId: 10}, {id: 20, fromId: 11, toId: 20}, {id: 30, fromId: 21, toId: 30}},
+		},
+		{
+			name: "unaligned", fromId: 15, toId: 31, size: 10,
+			expectedSlices: []idoSlice{{id: 20, fromId: 15, toId: 20}, {id: 30, fromId: 21, toId: 30}, {id: 40, fromId: 31, toId: 31}},
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			var slices []idoSlice
+			for _, slice := range splitIdRange(st.fromId, st.toId, st.size) {
+				slices = append(slices, *slice)
+			}
+
+			require.Equal(t, st.expectedSlices, slices)
+		})
+	}
+}
+
+func TestResumeSlices(t *testing.T) {
+	subtests := []struct {
+		name            string
+		fromId, toId    uint64
+		size            uint64
+		lastIds         map[uint64]uint64
+		expectedLastIds []uint64
+	}{
+		{name: "fresh", fromId: 1, toId: 30, size: 10, expectedLastIds: []uint64{0, 0, 0}},
+		{
+			name: "sliced", fromId: 1, toId: 30, size: 10, lastIds: map[uint64]uint64{10: 10, 30: 25},
+			expectedLastIds: []uint64{10, 0, 25},
+		},
+		{name: "unsliced", fromId: 5, toId: 42, lastIds: map[uint64]uint64{0: 23}, expectedLastIds: []uint64{23}},
+		{
+			name: "unsliced-to-first-slice", fromId: 1, toId: 30, size: 10, lastIds: map[uint64]uint64{0: 7},
+			expectedLastIds: []uint64{7, 0, 0},
+		},
+		{
+			name: "unsliced-across-slices", fromId: 1, toId: 30, size: 10, lastIds: map[uint64]uint64{0: 14},
+			expectedLastIds: []uint64{10, 14, 0},
+		},
+		{
+			name: "unsliced-and-sliced", fromId: 1, toId: 30, size: 10, lastIds: map[uint64]uint64{0: 14, 20: 17, 30: 28},
+			expectedLastIds: []uint64{10, 17, 28},
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			slices := splitIdRange(st.fromId, st.toId, st.size)
+			resumeSlices(slices, st.lastIds)
+
+			var lastIds []uint64
+			for _, slice := range slices {
+				lastIds = append(lastIds, slice.lastId)
+			}
+
+			require.Equal(t, st.expectedLastIds, lastIds)
+		})
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	require.Nil(t, newThrottle(0))
+
+	th := newThrottle(100)
+	now := time.Now()
+
+	require.Equal(t, time.Second, th.delay(now, 100))
+	require.Equal(t, 1500*time.Millisecond, th.delay(now, 50))
+	require.Equal(t, 500*time.Millisecond, th.delay(now.Add(time.Second), 0))
+
+	// Idle time isn't saved up.
+	require.Equal(t, 100*time.Millisecond, th.delay(now.Add(time.Minute), 10))
+}
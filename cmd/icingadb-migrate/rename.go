@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// RenameConfig defines how to translate IDO host and service names before Icinga DB object IDs are computed from them.
+// This is useful if objects have been renamed since the IDO was written.
+type RenameConfig struct {
+	// Csv is the path of a CSV file with two columns per line, an IDO object name and its new name.
+	// Both names are either host names or HOST!SERVICE names. Its entries take precedence over Rules.
+	Csv string `yaml:"csv"`
+	// Rules are applied to all names not in Csv, in order. Per name, the first matching rule wins.
+	Rules []RenameRule `yaml:"rules"`
+}
+
+// RenameRule rewrites host or service names matching a regular expression.
+type RenameRule struct {
+	// Host matches host names. If a host is renamed, its services are moved to the new host name as well.
+	Host string `yaml:"host"`
+	// Service matches service names, without the host name.
+	Service string `yaml:"service"`
+	// Replace is the new name. It may reference capture groups as understood by [regexp.Regexp.Expand], e.g. ${1}.
+	Replace string `yaml:"replace"`
+}
+
+// renameRule is a compiled RenameRule.
+type renameRule struct {
+	match   *regexp.Regexp
+	replace string
+}
+
+// renamer translates IDO host and service names as configured by RenameConfig.
+// A nil *renamer keeps all names as they are.
+type renamer struct {
+	// names maps IDO host and HOST!SERVICE names to their new names.
+	names        map[string]string
+	hostRules    []renameRule
+	serviceRules []renameRule
+}
+
+// renames translates IDO object names before Icinga DB object IDs are computed from them.
+var renames *renamer
+
+// newRenamer compiles c and reads its CSV file, if any. It returns nil if there's nothing to rename.
+func newRenamer(c *RenameConfig) (*renamer, error) {
+	if c.Csv == "" && len(c.Rules) == 0 {
+		return nil, nil
+	}
+
+	r := &renamer{names: map[string]string{}}
+
+	if c.Csv != "" {
+		f, err := os.Open(c.Csv)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't open rename CSV file")
+		}
+		defer func() { _ = f.Close() }()
+
+		if err := r.readCsv(f); err != nil {
+			return nil, errors.Wrapf(err, "can't parse rename CSV file %q", c.Csv)
+		}
+	}
+
+	for i, rule := range c.Rules {
+		if (rule.Host == "") == (rule.Service == "") {
+			return nil, errors.Errorf("rename rule #%d: exactly one of host and service must be set", i+1)
+		}
+
+		pattern := rule.Host
+		if pattern == "" {
+			pattern = rule.Service
+		}
+
+		match, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rename rule #%d: invalid regular expression", i+1)
+		}
+
+		if rule.Replace == "" {
+			return nil, errors.Errorf("rename rule #%d: replace must not be empty", i+1)
+		}
+
+		if rule.Host != "" {
+			r.hostRules = append(r.hostRules, renameRule{match, rule.Replace})
+		} else {
+			r.serviceRules = append(r.serviceRules, renameRule{match, rule.Replace})
+		}
+	}
+
+	return r, nil
+}
+
+// readCsv reads the old and new names from in into r.names.
+func (r *renamer) readCsv(in io.Reader) error {
+	cr := csv.NewReader(in)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		from, to := record[0], record[1]
+		line, _ := cr.FieldPos(0)
+
+		if strings.Contains(from, "!") != strings.Contains(to, "!") {
+			return errors.Errorf("line %d: can't rename a host to a service or vice versa", line)
+		}
+
+		for _, name := range []string{from, to} {
+			if host, service, isService := strings.Cut(name, "!"); host == "" || isService && service == "" {
+				return errors.Errorf("line %d: invalid object name %q", line, name)
+			}
+		}
+
+		if _, ok := r.names[from]; ok {
+			return errors.Errorf("line %d: duplicate object name %q", line, from)
+		}
+
+		r.names[from] = to
+	}
+}
+
+// rename translates the IDO host name name1 and service name name2 (empty for hosts).
+func (r *renamer) rename(name1, name2 string) (string, string) {
+	if r == nil {
+		return name1, name2
+	}
+
+	if name2 != "" {
+		if renamed, ok := r.names[name1+"!"+name2]; ok {
+			host, service, _ := strings.Cut(renamed, "!")
+			return host, service
+		}
+
+		name2 = applyRenameRules(r.serviceRules, name2)
+	}
+
+	if renamed, ok := r.names[name1]; ok {
+		return renamed, name2
+	}
+
+	return applyRenameRules(r.hostRules, name1), name2
+}
+
+// applyRenameRules rewrites name by the first matching rule, if any.
+func applyRenameRules(rules []renameRule, name string) string {
+	for _, rule := range rules {
+		if rule.match.MatchString(name) {
+			return rule.match.ReplaceAllString(name, rule.replace)
+		}
+	}
+
+	return name
+}
+
+// reportUnmatchedObjects logs the IDO hosts and services which - after renaming - don't match any Icinga DB one.
+// The migrated history of these refers to objects which don't exist (anymore).
+// (On non-recoverable errors the whole program exits.)
+func reportUnmatchedObjects(c *Config, ido, idb *database.DB, envId []byte) {
+	existing := map[string]struct{}{}
+	for _, table := range []string{"host", "service"} {
+		var ids []types.Binary
+
+		query := idb.Rebind(`SELECT "id" FROM "` + table + `" WHERE "environment_id" = ?`)
+		if err := idb.Select(&ids, query, envId); err != nil {
+			log.With("backend", "Icinga DB", "query", query).Fatalf("%+v", errors.Wrap(err, "can't perform query"))
+		}
+
+		for _, id := range ids {
+			existing[id.String()] = struct{}{}
+		}
+	}
+
+	var objects []struct {
+		ObjecttypeId uint8
+		Name1        string
+		Name2        string
+	}
+
+	query := "SELECT objecttype_id, name1, COALESCE(name2, '') name2 FROM icinga_objects" +
+		" WHERE objecttype_id IN (1, 2) ORDER BY name1, name2"
+	if err := ido.Select(&objects, query); err != nil {
+		log.With("backend", "IDO", "query", query).Fatalf("%+v", errors.Wrap(err, "can't perform query"))
+	}
+
+	var unmatched int
+	for _, object := range objects {
+		name1, name2 := renames.rename(object.Name1, object.Name2)
+
+		id := calcObjectId(c.Icinga2.Env, name1)
+		if object.ObjecttypeId == 2 {
+			id = calcServiceId(c.Icinga2.Env, name1, name2)
+		}
+
+		if _, ok := existing[hex.EncodeToString(id)]; !ok {
+			unmatched++
+
+			log.Warnw("IDO object doesn't match any Icinga DB object",
+				"type", objectTypes[object.ObjecttypeId],
+				"ido_name", objectName(object.Name1, object.Name2),
+				"name", objectName(name1, name2))
+		}
+	}
+
+	log.Infow("Matched IDO objects with Icinga DB ones", "objects", len(objects), "unmatched", unmatched)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRenamer_rename(t *testing.T) {
+	r, err := newRenamer(&RenameConfig{Rules: []RenameRule{
+		{Host: `^([^.]+)$`, Replace: "${1}.example.com"},
+		{Service: `^ping(4|6)$`, Replace: "ping-ipv${1}"},
+		{Service: `^ping`, Replace: "never applied"},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, r.readCsv(strings.NewReader(
+		"# IDO name,new name\n"+
+			"db1,database.example.org\n"+
+			"web1!http,www.example.com!https\n",
+	)))
+
+	subtests := []struct {
+		name         string
+		name1, name2 string
+		to1, to2     string
+	}{
+		{name: "host-rule", name1: "web1", to1: "web1.example.com"},
+		{name: "host-no-match", name1: "web1.example.net", to1: "web1.example.net"},
+		{name: "host-csv", name1: "db1", to1: "database.example.org"},
+		{name: "service-host-rule", name1: "web1", name2: "disk", to1: "web1.example.com", to2: "disk"},
+		{name: "service-host-csv", name1: "db1", name2: "disk", to1: "database.example.org", to2: "disk"},
+		{name: "service-rule", name1: "web1", name2: "ping6", to1: "web1.example.com", to2: "ping-ipv6"},
+		{name: "service-csv", name1: "web1", name2: "http", to1: "www.example.com", to2: "https"},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			name1, name2 := r.rename(st.name1, st.name2)
+			require.Equal(t, st.to1, name1)
+			require.Equal(t, st.to2, name2)
+		})
+	}
+
+	var none *renamer
+	name1, name2 := none.rename("web1", "http")
+	require.Equal(t, "web1", name1)
+	require.Equal(t, "http", name2)
+}
+
+func TestNewRenamer(t *testing.T) {
+	r, err := newRenamer(&RenameConfig{})
+	require.NoError(t, err)
+	require.Nil(t, r)
+
+	_, err = newRenamer(&RenameConfig{Rules: []RenameRule{{Host: "a", Service: "b", Replace: "c"}}})
+	require.ErrorContains(t, err, "exactly one of host and service")
+
+	_, err = newRenamer(&RenameConfig{Rules: []RenameRule{{Host: "(", Replace: "c"}}})
+	require.ErrorContains(t, err, "invalid regular expression")
+
+	_, err = newRenamer(&RenameConfig{Csv: "/nonexistent/rename.csv"})
+	require.ErrorContains(t, err, "can't open rename CSV file")
+}
+
+func TestRenamer_readCsv(t *testing.T) {
+	for _, in := range []string{"web1\n", "web1,web2!http\n", "web1!,web2!http\n", "web1,web2\nweb1,web3\n"} {
+		require.Error(t, (&renamer{names: map[string]string{}}).readCsv(strings.NewReader(in)), in)
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/vbauerster/mpb/v6"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// verifySampleRate specifies how many of the migrated rows are compared column by column: one in verifySampleRate.
+const verifySampleRate = 100
+
+// verifyMaxReportedMismatches limits the column mismatches reported per history type.
+const verifyMaxReportedMismatches = 100
+
+// verifyKeyColumns maps Icinga DB tables not identified by an id column to their primary key column.
+var verifyKeyColumns = map[string]string{"sla_history_downtime": "downtime_id"}
+
+// verification summarizes the comparison of the IDO history with the already migrated one of one history type.
+type verification struct {
+	// source counts the IDO events in the configured time range.
+	source int64
+	// expected counts the Icinga DB rows converted from the IDO events.
+	expected int64
+	// missing counts the expected Icinga DB rows not present in Icinga DB.
+	missing int64
+	// checked counts the Icinga DB rows compared column by column with the converted ones.
+	checked int64
+	// mismatches counts the columns which differ between the converted and the present Icinga DB rows.
+	mismatches int64
+	// reportedMismatches details the first mismatches.
+	reportedMismatches []columnMismatch
+	// objects maps objectKey()s to the per-object verification results.
+	objects map[string]*objectVerification
+	// vanished maps IDO object IDs missing from icinga_objects to the number of their events.
+	// Such events are not migrated at all.
+	vanished map[uint64]int64
+}
+
+// objectVerification summarizes the verification of one host or service.
+type objectVerification struct {
+	// name is the host name or <host name>!<service name>.
+	name     string
+	source   int64
+	expected int64
+	missing  int64
+}
+
+// columnMismatch describes a column of an Icinga DB row which differs from the one converted from the IDO.
+type columnMismatch struct {
+	table    string
+	id       string
+	column   string
+	expected any
+	actual   any
+}
+
+// ok reports whether all converted rows are present in Icinga DB and no checked one differs.
+func (v *verification) ok() bool {
+	return v.missing == 0 && v.mismatches == 0
+}
+
+// object returns the per-object verification results of the host or service an Icinga DB row refers to.
+// fields are the row's columns. Returns nil if the row doesn't refer to a host or service.
+func (v *verification) object(fields map[string]reflect.Value) *objectVerification {
+	hostId, ok := fields["host_id"]
+	if !ok {
+		return nil
+	}
+
+	var serviceId []byte
+	if field, ok := fields["service_id"]; ok {
+		serviceId = field.Bytes()
+	}
+
+	key := objectKey(hostId.Bytes(), serviceId)
+	if _, ok := v.objects[key]; !ok {
+		v.objects[key] = &objectVerification{name: key}
+	}
+
+	return v.objects[key]
+}
+
+// objectKey identifies the host hostId or the service serviceId, if not empty, in verification.objects.
+func objectKey(hostId, serviceId []byte) string {
+	return hex.EncodeToString(hostId) + "!" + hex.EncodeToString(serviceId)
+}
+
+// verify compares the IDO history with the already migrated one in Icinga DB without writing anything,
+// reports the differences and returns whether there are none.
+// (On non-recoverable errors the whole program exits.)
+func verify(c *Config, idb *database.DB, envId []byte) bool {
+	typesToMigrate.forEach(func(ht *historyType) {
+		countIdoEvents(c, ht)
+	})
+
+	progress := mpb.New()
+	for _, ht := range typesToMigrate {
+		ht.setupBar(progress, ht.total)
+	}
+
+	typesToMigrate.forEach(func(ht *historyType) {
+		ht.verify(c, idb, envId, ht)
+	})
+
+	progress.Wait()
+
+	ok := true
+	for _, ht := range typesToMigrate {
+		v := ht.verification
+
+		for _, id := range slices.Sorted(maps.Keys(v.vanished)) {
+			log.Warnw("IDO events of an object missing from icinga_objects can't be migrated",
+				"type", ht.name, "object_id", id, "events", v.vanished[id])
+		}
+
+		objects := slices.SortedFunc(maps.Values(v.objects), func(a, b *objectVerification) int {
+			return cmp.Compare(a.name, b.name)
+		})
+
+		for _, o := range objects {
+			if o.missing > 0 {
+				log.Warnw("Migrated events are missing",
+					"type", ht.name, "object", o.name, "ido_events", o.source, "expected", o.expected, "missing", o.missing)
+			}
+		}
+
+		for _, m := range v.reportedMismatches {
+			log.Warnw("Migrated event differs from the IDO one",
+				"type", ht.name, "table", m.table, "id", m.id, "column", m.column, "expected", m.expected, "actual", m.actual)
+		}
+
+		log.Infow("Verified migrated IDO events", "type", ht.name, "ido_events", v.source, "expected", v.expected,
+			"missing", v.missing, "checked", v.checked, "mismatches", v.mismatches, "vanished_objects", len(v.vanished))
+
+		ok = ok && v.ok()
+	}
+
+	return ok
+}
+
+// countIdoEvents initializes typesToMigrate[*].total and typesToMigrate[*].verification with the IDO events per object.
+// (On non-recoverable errors the whole program exits.)
+func countIdoEvents(c *Config, ht *historyType) {
+	v := &verification{objects: map[string]*objectVerification{}, vanished: map[uint64]int64{}}
+	ht.verification = v
+
+	var objects []struct {
+		Name1 string
+		Name2 string
+		Cnt   int64
+	}
+
+	query := ht.snapshot.Rebind(
+		"SELECT o.name1, COALESCE(o.name2, '') name2, COUNT(*) cnt FROM " + ht.idoTable +
+			" xh INNER JOIN icinga_objects o ON o.object_id=xh.object_id WHERE xh." + ht.idoIdColumn +
+			" BETWEEN ? AND ? GROUP BY o.name1, o.name2",
+	)
+	if err := ht.snapshot.Select(&objects, query, ht.fromId, ht.toId); err != nil {
+		log.With("backend", "IDO", "query", query).Fatalf("%+v", errors.Wrap(err, "can't count query"))
+	}
+
+	for _, object := range objects {
+		name1, name2 := renames.rename(object.Name1, object.Name2)
+		key := objectKey(calcObjectId(c.Icinga2.Env, name1), calcServiceId(c.Icinga2.Env, name1, name2))
+		if _, ok := v.objects[key]; !ok {
+			v.objects[key] = &objectVerification{name: objectName(object.Name1, object.Name2)}
+		}
+
+		v.objects[key].source += object.Cnt
+		v.source += object.Cnt
+	}
+
+	var vanished []struct {
+		ObjectId uint64
+		Cnt      int64
+	}
+
+	query = ht.snapshot.Rebind(
+		"SELECT xh.object_id, COUNT(*) cnt FROM " + ht.idoTable +
+			" xh LEFT JOIN icinga_objects o ON o.object_id=xh.object_id WHERE xh." + ht.idoIdColumn +
+			" BETWEEN ? AND ? AND o.object_id IS NULL GROUP BY xh.object_id",
+	)
+	if err := ht.snapshot.Select(&vanished, query, ht.fromId, ht.toId); err != nil {
+		log.With("backend", "IDO", "query", query).Fatalf("%+v", errors.Wrap(err, "can't count query"))
+	}
+
+	for _, object := range vanished {
+		v.vanished[object.ObjectId] = object.Cnt
+	}
+
+	ht.total = v.source
+
+	log.Infow("Counted IDO events", "type", ht.name, "total", ht.total, "vanished_objects", len(v.vanished))
+}
+
+// verifyOneType does the actual verification for one history type.
+// It converts the IDO events just like migrateOneType, but instead of inserting the resulting rows
+// it checks whether they are present in Icinga DB and compares a sample of them column by column.
+func verifyOneType[IdoRow any](
+	c *Config, idb *database.DB, envId []byte, ht *historyType,
+	convertRows func(env string, envId types.Binary,
+		selectCache func(dest any, query string, args ...any), ido *idoSnapshot,
+		idoRows []IdoRow) (stages []icingaDbOutputStage, checkpoint any),
+) {
+	selectCache, closeCache := prepareCacheSelect(ht)
+	defer closeCache()
+
+	var sampled int64
+
+	sliceIdoHistory(
+		ht.snapshot, ht.fromId, ht.toId, ht.migrationQuery, cacheLimitArgs(ht), 0,
+		func(idoRows []IdoRow) (checkpoint any) {
+			stages, lastIdoId := convertRows(c.Icinga2.Env, envId, selectCache, ht.snapshot, idoRows)
+
+			for _, stage := range stages {
+				// Upserts just complete rows also converted from other IDO events (and already verified that way).
+				if len(stage.insert) > 0 {
+					verifyRows(idb, ht.verification, stage.insert, &sampled)
+				}
+			}
+
+			ht.bar.IncrBy(len(idoRows))
+			return lastIdoId
+		},
+	)
+
+	ht.bar.SetTotal(ht.bar.Current(), true)
+}
+
+// verifyRows checks whether the Icinga DB rows are present in Icinga DB and updates v accordingly.
+// Every verifySampleRate-th of the present rows (counted by sampled) is also compared column by column.
+// (On non-recoverable errors the whole program exits.)
+func verifyRows(idb *database.DB, v *verification, rows []database.Entity, sampled *int64) {
+	table := database.TableName(rows[0])
+
+	keyColumn := "id"
+	if column, ok := verifyKeyColumns[table]; ok {
+		keyColumn = column
+	}
+
+	for chunk := range slices.Chunk(rows, 1000) {
+		ids := make([]any, 0, len(chunk))
+		for _, row := range chunk {
+			ids = append(ids, row.ID())
+		}
+
+		query, args, err := sqlx.In(fmt.Sprintf(`SELECT "%[1]s" FROM "%[2]s" WHERE "%[1]s" IN (?)`, keyColumn, table), ids)
+		if err != nil {
+			log.With("query", query).Fatalf("%+v", errors.Wrap(err, "can't build query"))
+		}
+
+		query = idb.Rebind(query)
+
+		var present []types.Binary
+		if err := idb.Select(&present, query, args...); err != nil {
+			log.With("backend", "Icinga DB", "query", query).Fatalf("%+v", errors.Wrap(err, "can't perform query"))
+		}
+
+		presentIds := make(map[string]struct{}, len(present))
+		for _, id := range present {
+			presentIds[id.String()] = struct{}{}
+		}
+
+		for _, row := range chunk {
+			fields := idb.Mapper.FieldMap(reflect.ValueOf(row))
+			object := v.object(fields)
+
+			v.expected++
+			if object != nil {
+				object.expected++
+			}
+
+			if _, ok := presentIds[row.ID().String()]; !ok {
+				v.missing++
+				if object != nil {
+					object.missing++
+				}
+
+				continue
+			}
+
+			if *sampled++; *sampled%verifySampleRate == 0 {
+				compareRow(idb, v, table, keyColumn, row, fields)
+			}
+		}
+	}
+}
+
+// compareRow compares all columns of the Icinga DB row with the present one and records mismatches in v.
+// (On non-recoverable errors the whole program exits.)
+func compareRow(
+	idb *database.DB, v *verification, table, keyColumn string, row database.Entity, fields map[string]reflect.Value,
+) {
+	query := idb.Rebind(idb.BuildSelectStmt(row, row) + fmt.Sprintf(` WHERE "%s" = ?`, keyColumn))
+
+	actual := map[string]any{}
+	if err := idb.QueryRowx(query, row.ID()).MapScan(actual); err != nil {
+		log.With("backend", "Icinga DB", "query", query).Fatalf("%+v", errors.Wrap(err, "can't perform query"))
+	}
+
+	v.checked++
+
+	for _, column := range idb.BuildColumns(row) {
+		expected, err := sqlValue(fields[column])
+		if err != nil {
+			log.With("table", table, "column", column).Fatalf("%+v", errors.Wrap(err, "can't convert value"))
+		}
+
+		if sameSqlValue(expected, actual[column]) {
+			continue
+		}
+
+		v.mismatches++
+		if len(v.reportedMismatches) < verifyMaxReportedMismatches {
+			v.reportedMismatches = append(v.reportedMismatches, columnMismatch{
+				table:    table,
+				id:       row.ID().String(),
+				column:   column,
+				expected: formatSqlValue(expected),
+				actual:   formatSqlValue(actual[column]),
+			})
+		}
+	}
+}
+
+// sqlValue returns the value field would be written to the database as.
+func sqlValue(field reflect.Value) (driver.Value, error) {
+	if !field.IsValid() || field.Kind() == reflect.Pointer && field.IsNil() {
+		return nil, nil
+	}
+
+	if field.CanAddr() {
+		field = field.Addr()
+	}
+
+	if valuer, ok := field.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+
+	return reflect.Indirect(field).Interface(), nil
+}
+
+// formatSqlValue returns v as written to or read from a database as string or - for SQL NULL - as invalid.
+func formatSqlValue(v any) types.String {
+	switch v := v.(type) {
+	case nil:
+		return types.String{}
+	case []byte:
+		return types.MakeString(string(v))
+	default:
+		return types.MakeString(fmt.Sprint(v))
+	}
+}
+
+// sameSqlValue reports whether the value written to a database equals the one read from it
+// regardless of the driver-specific representation. Numbers are compared with the precision of float.
+func sameSqlValue(written, read any) bool {
+	w, r := formatSqlValue(written), formatSqlValue(read)
+	if !w.Valid || !r.Valid {
+		return w.Valid == r.Valid
+	}
+
+	if w.String == r.String {
+		return true
+	}
+
+	wf, errW := strconv.ParseFloat(w.String, 64)
+	rf, errR := strconv.ParseFloat(r.String, 64)
+
+	return errW == nil && errR == nil && math.Abs(wf-rf) <= 1e-6*math.Max(math.Abs(wf), math.Abs(rf))
+}
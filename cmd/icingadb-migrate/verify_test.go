@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icingadb/pkg/icingadb/v1/history"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSameSqlValue(t *testing.T) {
+	subtests := []struct {
+		name    string
+		written any
+		read    any
+		same    bool
+	}{
+		{name: "null", written: nil, read: nil, same: true},
+		{name: "null-vs-empty", written: nil, read: []byte{}, same: false},
+		{name: "string-vs-bytes", written: "y", read: []byte("y"), same: true},
+		{name: "string-differs", written: "y", read: "n", same: false},
+		{name: "int-vs-bytes", written: int64(1700000000000), read: []byte("1700000000000"), same: true},
+		{name: "uint8-vs-int", written: uint8(2), read: int64(2), same: true},
+		{name: "float-vs-decimal", written: 12.5, read: []byte("12.5000"), same: true},
+		{name: "float-single-precision", written: 0.1, read: float64(float32(0.1)), same: true},
+		{name: "float-differs", written: 12.5, read: 12.6, same: false},
+		{name: "binary", written: []byte{0, 1, 255}, read: []byte{0, 1, 255}, same: true},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.same, sameSqlValue(st.written, st.read))
+		})
+	}
+}
+
+func TestSqlValue(t *testing.T) {
+	h := &history.StateHistory{
+		EventTime:        types.UnixMilli(time.UnixMilli(1700000000000)),
+		MaxCheckAttempts: 3,
+	}
+
+	v := reflect.ValueOf(h).Elem()
+
+	value, err := sqlValue(v.FieldByName("EventTime"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1700000000000), value)
+
+	value, err = sqlValue(v.FieldByName("Output"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	value, err = sqlValue(v.FieldByName("MaxCheckAttempts"))
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), value)
+
+	value, err = sqlValue(reflect.Value{})
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
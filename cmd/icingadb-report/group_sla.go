@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"github.com/icinga/icingadb/pkg/reporting"
+)
+
+// groupSlaCommand implements the group-sla command.
+type groupSlaCommand struct {
+	Type  reporting.GroupType `short:"t" long:"type" description:"group type" choice:"hostgroup" choice:"servicegroup" required:"true"`
+	Group string              `short:"g" long:"group" description:"group name" required:"true"`
+	Mode  reporting.Mode      `short:"m" long:"mode" description:"aggregation mode: mean of member SLAs, or time during which any/all members had a problem" choice:"mean" choice:"any" choice:"all" default:"mean"`
+	Start Timestamp           `short:"s" long:"start" description:"start of the reporting period (RFC 3339, YYYY-MM-DD or duration relative to now)" required:"true"`
+	End   Timestamp           `long:"end" description:"end of the reporting period (RFC 3339, YYYY-MM-DD or duration relative to now; default: now)"`
+}
+
+// Execute implements the [flags.Commander] interface.
+func (c *groupSlaCommand) Execute([]string) error {
+	return withReporter(func(ctx context.Context, r *reporting.Reporter, env *reporting.Environment) error {
+		group, err := r.Group(ctx, env, c.Type, c.Group)
+		if err != nil {
+			return err
+		}
+
+		sla, err := r.GroupSla(ctx, *group, c.Start.Time, c.End.OrNow(), c.Mode)
+		if err != nil {
+			return err
+		}
+
+		t := table{header: []string{"member", "availability", "total_time", "problem_time"}}
+		for _, m := range sla.Members {
+			t.rows = append(t.rows, []string{
+				m.Name, formatPercent(m.Availability), formatDuration(m.TotalTime), formatDuration(m.ProblemTime),
+			})
+		}
+
+		footer := []string{"(" + string(sla.Mode) + ")", formatPercent(sla.Availability)}
+		if sla.Mode != reporting.ModeMean {
+			footer = append(footer, formatDuration(sla.TotalTime), formatDuration(sla.ProblemTime))
+		}
+		t.footer = footer
+
+		return render(sla, t)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"github.com/icinga/icingadb/pkg/reporting"
+	"github.com/pkg/errors"
+	"strconv"
+)
+
+// incidentsCommand implements the incidents command.
+type incidentsCommand struct {
+	Host    string              `long:"host" description:"host name"`
+	Service string              `long:"service" description:"service name, requires --host"`
+	Type    reporting.GroupType `short:"t" long:"type" description:"group type, instead of --host" choice:"hostgroup" choice:"servicegroup"`
+	Group   string              `short:"g" long:"group" description:"group name, requires --type"`
+	Start   Timestamp           `short:"s" long:"start" description:"start of the reporting period (RFC 3339, YYYY-MM-DD or duration relative to now)" required:"true"`
+	End     Timestamp           `long:"end" description:"end of the reporting period (RFC 3339, YYYY-MM-DD or duration relative to now; default: now)"`
+}
+
+// Execute implements the [flags.Commander] interface.
+func (c *incidentsCommand) Execute([]string) error {
+	if (c.Host == "") == (c.Group == "") || (c.Group != "") != (c.Type != "") || (c.Service != "" && c.Host == "") {
+		return errors.New("either --host (and optionally --service) or --type and --group must be given")
+	}
+
+	return withReporter(func(ctx context.Context, r *reporting.Reporter, env *reporting.Environment) error {
+		start, end := c.Start.Time, c.End.OrNow()
+
+		var checkables []reporting.Member
+		if c.Host != "" {
+			checkable, err := r.Checkable(ctx, env, c.Host, c.Service)
+			if err != nil {
+				return err
+			}
+
+			checkables = append(checkables, *checkable)
+		} else {
+			group, err := r.Group(ctx, env, c.Type, c.Group)
+			if err != nil {
+				return err
+			}
+
+			checkables, err = r.Members(ctx, *group, start, end)
+			if err != nil {
+				return err
+			}
+		}
+
+		report, err := r.IncidentStats(ctx, checkables, start, end)
+		if err != nil {
+			return err
+		}
+
+		row := func(name string, s reporting.IncidentStats) []string {
+			return []string{
+				name,
+				strconv.Itoa(s.Episodes),
+				strconv.Itoa(s.Ongoing),
+				strconv.Itoa(s.Acknowledged),
+				formatDuration(s.MTTR),
+				formatDuration(s.MTBF),
+				formatDuration(s.MTTA),
+				formatDuration(s.LongestOutage),
+			}
+		}
+
+		t := table{header: []string{
+			"checkable", "episodes", "ongoing", "acknowledged", "mttr", "mtbf", "mtta", "longest_outage",
+		}}
+		for _, s := range report.Checkables {
+			t.rows = append(t.rows, row(s.Name, s.IncidentStats))
+		}
+
+		if len(report.Checkables) > 1 {
+			t.footer = row("(total)", report.Total)
+		}
+
+		return render(report, t)
+	})
+}
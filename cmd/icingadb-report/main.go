@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icingadb/internal"
+	"github.com/icinga/icingadb/internal/command"
+	icingadbconfig "github.com/icinga/icingadb/internal/config"
+	"github.com/icinga/icingadb/pkg/icingadb"
+	"github.com/icinga/icingadb/pkg/reporting"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Options defines the CLI flags shared by all report commands.
+type Options struct {
+	// Config is the path to the Icinga DB config file. If not provided, it defaults to DefaultConfigPath.
+	Config string `short:"c" long:"config" description:"path to Icinga DB config file (default: /etc/icingadb/config.yml)"`
+
+	// Environment is the name of the Icinga environment to report on.
+	// It may be omitted if the database contains only a single environment.
+	Environment string `short:"e" long:"environment" description:"name of the Icinga environment (default: the only one)"`
+
+	// Format is the output format.
+	Format string `short:"f" long:"format" description:"output format" choice:"table" choice:"json" choice:"csv" default:"table"`
+
+	// Version decides whether to just print the version and exit.
+	Version func() `long:"version" description:"print version and exit"`
+}
+
+var options = Options{
+	Version: func() {
+		internal.Version.Print("Icinga DB Report")
+		os.Exit(0)
+	},
+}
+
+func main() {
+	parser := flags.NewParser(&options, flags.Default)
+
+	commands := []struct {
+		name, short, long string
+		data              any
+	}{{
+		"group-sla", "Compute the availability of a host or service group",
+		"Compute the availability of a host or service group over a time range from the SLA history.",
+		&groupSlaCommand{},
+	}, {
+		"incidents", "Compute incident statistics such as MTTR, MTBF and MTTA",
+		"Compute incident statistics of a host, service or group over a time range from the SLA and acknowledgement history.",
+		&incidentsCommand{},
+	}}
+
+	for _, c := range commands {
+		if _, err := parser.AddCommand(c.name, c.short, c.long, c.data); err != nil {
+			panic(err)
+		}
+	}
+
+	if _, err := parser.Parse(); err != nil {
+		var flagsErr *flags.Error
+		if errors.As(err, &flagsErr) && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+
+		// The error, including those returned by commands, has already been printed by the parser.
+		os.Exit(1)
+	}
+}
+
+// connect loads the Icinga DB config, connects to the database and verifies its schema.
+func connect(ctx context.Context) (*database.DB, error) {
+	cmd, err := command.Load(icingadbconfig.Flags{Config: options.Config})
+	if err != nil {
+		return nil, errors.Wrap(err, "can't load config")
+	}
+
+	logs, err := logging.NewLoggingFromConfig("icingadb-report", cmd.Config.Logging)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't configure logging")
+	}
+
+	db, err := cmd.Database(logs.GetChildLogger("database"))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create database connection pool from config")
+	}
+
+	if err := icingadb.CheckSchema(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// withReporter calls fn with a Reporter for the configured database and environment.
+func withReporter(fn func(ctx context.Context, r *reporting.Reporter, env *reporting.Environment) error) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	r := reporting.NewReporter(db, nil)
+
+	env, err := r.Environment(ctx, options.Environment)
+	if err != nil {
+		return err
+	}
+
+	return fn(ctx, r, env)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Timestamp is a CLI flag value which accepts RFC 3339 timestamps, dates (YYYY-MM-DD) in local time,
+// and durations relative to now (e.g. -720h).
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalFlag implements the [flags.Unmarshaler] interface.
+func (t *Timestamp) UnmarshalFlag(value string) error {
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		t.Time = ts
+		return nil
+	}
+
+	if ts, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		t.Time = ts
+		return nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		t.Time = time.Now().Add(d)
+		return nil
+	}
+
+	return errors.Errorf("can't parse %q as RFC 3339 timestamp, date or duration", value)
+}
+
+// OrNow returns the timestamp or the current time if it is not set.
+func (t Timestamp) OrNow() time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+
+	return t.Time
+}
+
+// table is a report in tabular form. It is written as-is for the table and CSV formats
+// while the JSON format uses the original value. The optional footer summarizes the rows.
+type table struct {
+	header []string
+	rows   [][]string
+	footer []string
+}
+
+// render writes v in the configured output format to stdout.
+func render(v any, t table) error {
+	return write(os.Stdout, options.Format, v, t)
+}
+
+// write writes v in the given format to w.
+func write(w io.Writer, format string, v any, t table) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return errors.Wrap(enc.Encode(v), "can't write JSON")
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(t.header); err != nil {
+			return errors.Wrap(err, "can't write CSV")
+		}
+
+		rows := t.rows
+		if t.footer != nil {
+			rows = append(rows, t.footer)
+		}
+
+		if err := cw.WriteAll(rows); err != nil {
+			return errors.Wrap(err, "can't write CSV")
+		}
+
+		return nil
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, row := range append(append([][]string{t.header}, t.rows...), t.footer) {
+			if row == nil {
+				continue
+			}
+
+			if _, err := fmt.Fprintln(tw, strings.Join(row, "\t")); err != nil {
+				return errors.Wrap(err, "can't write table")
+			}
+		}
+
+		return errors.Wrap(tw.Flush(), "can't write table")
+	}
+}
+
+// formatPercent formats an optional percentage for tabular output.
+func formatPercent(p *float64) string {
+	if p == nil {
+		return "-"
+	}
+
+	return strconv.FormatFloat(*p, 'f', 4, 64)
+}
+
+// formatDuration formats a duration for tabular output, rounded to seconds.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/creasty/defaults"
+	"github.com/goccy/go-yaml"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icingadb/pkg/icingadb"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Flags defines the CLI flags.
+type Flags struct {
+	// Config is the path to the config file.
+	Config string `short:"c" long:"config" description:"path to config file" required:"true"`
+}
+
+// Config defines the YAML config structure.
+type Config struct {
+	// Source is the Icinga DB database to transfer from.
+	Source database.Config `yaml:"source"`
+	// Destination is the Icinga DB database to transfer to. Its schema must already be imported.
+	Destination database.Config `yaml:"destination"`
+}
+
+// Validate checks constraints in the supplied config and returns an error if they are violated.
+func (c *Config) Validate() error {
+	if err := c.Source.Validate(); err != nil {
+		return errors.Wrap(err, "invalid source")
+	}
+
+	if err := c.Destination.Validate(); err != nil {
+		return errors.Wrap(err, "invalid destination")
+	}
+
+	return nil
+}
+
+// parallelTables limits the tables transferred in parallel.
+const parallelTables = 4
+
+// log is the root logger.
+var log = func() *zap.SugaredLogger {
+	logger, err := zap.NewDevelopmentConfig().Build()
+	if err != nil {
+		panic(err)
+	}
+
+	return logger.Sugar()
+}()
+
+// main validates the CLI, parses the config and transfers all tables from the source to the destination database.
+// Tables already partially transferred are resumed after the last transferred row.
+func main() {
+	f := &Flags{}
+	if _, err := flags.NewParser(f, flags.Default).Parse(); err != nil {
+		os.Exit(2)
+	}
+
+	c, ex := parseConfig(f)
+	if c == nil {
+		os.Exit(ex)
+	}
+
+	defer func() { _ = log.Sync() }()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	log.Info("Starting Icinga DB database transfer")
+
+	src := connect("source", &c.Source)
+	dest := connect("destination", &c.Destination)
+
+	for backend, db := range map[string]*database.DB{"source": src, "destination": dest} {
+		if err := icingadb.CheckSchema(ctx, db); err != nil {
+			log.With("backend", backend).Fatalf("%+v", err)
+		}
+	}
+
+	tables, err := listTables(ctx, src)
+	if err != nil {
+		log.With("backend", "source").Fatalf("%+v", err)
+	}
+
+	destTables, err := listTables(ctx, dest)
+	if err != nil {
+		log.With("backend", "destination").Fatalf("%+v", err)
+	}
+
+	destByName := make(map[string]*table, len(destTables))
+	for _, t := range destTables {
+		destByName[t.name] = t
+	}
+
+	for _, t := range tables {
+		d, ok := destByName[t.name]
+		if !ok {
+			log.With("backend", "destination", "table", t.name).Fatal("Table doesn't exist, please import the schema")
+		}
+
+		if err := t.checkColumns(d); err != nil {
+			log.With("table", t.name).Fatalf("%+v", errors.Wrap(err, "tables differ"))
+		}
+	}
+
+	// Tables with foreign keys are transferred after all others which they may refer to.
+	for _, referencing := range []bool{false, true} {
+		g, ctx := errgroup.WithContext(ctx)
+		g.SetLimit(parallelTables)
+
+		for _, t := range tables {
+			if t.referencing != referencing {
+				continue
+			}
+
+			g.Go(func() error {
+				start := time.Now()
+
+				transferred, err := transferTable(ctx, src, dest, t)
+				if err != nil {
+					return errors.Wrapf(err, "can't transfer table %q", t.name)
+				}
+
+				if err := resetSequences(ctx, dest, t); err != nil {
+					return errors.Wrapf(err, "can't reset sequences of table %q", t.name)
+				}
+
+				log.Infow("Transferred table", "table", t.name, "rows", transferred, "took", time.Since(start))
+
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			log.Fatalf("%+v", err)
+		}
+	}
+
+	log.Info("Verifying row counts")
+
+	if !verifyCounts(ctx, src, dest, tables) {
+		_ = log.Sync()
+		os.Exit(1)
+	}
+
+	log.Info("Transfer completed successfully")
+}
+
+// parseConfig validates the f.Config file and returns the config and -1 or - on failure - nil and an exit code.
+func parseConfig(f *Flags) (_ *Config, exit int) {
+	cf, err := os.Open(f.Config)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "can't open config file: %s\n", err.Error())
+		return nil, 2
+	}
+	defer func() { _ = cf.Close() }()
+
+	c := &Config{}
+	if err := defaults.Set(c); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "can't set config defaults: %s\n", err.Error())
+		return nil, 2
+	}
+
+	if err := yaml.NewDecoder(cf, yaml.DisallowUnknownField()).Decode(c); err != nil {
+		// #nosec G705 -- this error message should do no harm in the output, being printed to stderr
+		// on a terminal and not used as an HTML page or the like
+		_, _ = fmt.Fprintf(os.Stderr, "can't parse config file: %s\n", err.Error())
+		return nil, 2
+	}
+
+	if err := c.Validate(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "invalid config: %s\n", err.Error())
+		return nil, 2
+	}
+
+	return c, -1
+}
+
+// connect connects to which DB as cfg specifies. (On non-recoverable errors the whole program exits.)
+func connect(which string, cfg *database.Config) *database.DB {
+	connectLog := log.With("backend", which)
+
+	db, err := database.NewDbFromConfig(
+		cfg,
+		logging.NewLogger(connectLog, 20*time.Second),
+		database.RetryConnectorCallbacks{},
+	)
+	if err != nil {
+		connectLog.Fatalf("%+v", errors.Wrap(err, "can't connect to database"))
+	}
+
+	if err := db.Ping(); err != nil {
+		connectLog.Fatalf("%+v", errors.Wrap(err, "can't connect to database"))
+	}
+
+	return db
+}
+
+// verifyCounts compares the number of rows of all tables in both databases,
+// logs the differences and returns whether there are none.
+func verifyCounts(ctx context.Context, src, dest *database.DB, tables []*table) bool {
+	ok := true
+
+	for _, t := range tables {
+		var counts [2]int64
+		for i, db := range []*database.DB{src, dest} {
+			query := `SELECT COUNT(*) FROM "` + t.name + `"`
+			if err := db.GetContext(ctx, &counts[i], query); err != nil {
+				log.Fatalf("%+v", database.CantPerformQuery(err, query))
+			}
+		}
+
+		if counts[0] != counts[1] {
+			ok = false
+			log.Errorw("Row counts differ", "table", t.name, "source", counts[0], "destination", counts[1])
+		}
+	}
+
+	return ok
+}
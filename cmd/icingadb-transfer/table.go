@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/icinga/icinga-go-library/com"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// pageSize is the number of rows selected from the source database at once.
+const pageSize = 10000
+
+// columnKind classifies column types. It tells how to scan a column's values so that both MySQL and PostgreSQL
+// accept them on insert and whether the columns of both databases are compatible.
+//
+// Rather than using the entity models of Icinga DB, the tables are copied generically based on the schema of the
+// source database. This way, all tables and columns are transferred, including those without a model, such as the
+// performance data rollups, or with columns not mapped by a model. Values of all kinds except integers and binaries
+// are copied in their textual representation, which is the same in both databases, e.g. MySQL's enum('n', 'y') and
+// PostgreSQL's boolenum. To make sure that this holds, [table.checkColumns] requires the same kind on both sides.
+type columnKind uint8
+
+const (
+	// otherColumn covers all types not classified otherwise. The types must be the same on both sides.
+	otherColumn columnKind = iota
+	// textColumn covers all character types, including PostgreSQL's citext.
+	textColumn
+	// enumColumn covers MySQL's enum and PostgreSQL's enum types.
+	enumColumn
+	// floatColumn covers all floating point and decimal types.
+	floatColumn
+	// integerColumn covers all integer types. They are kept numeric to compare them correctly as primary keys.
+	integerColumn
+	// binaryColumn covers MySQL's binary(n) and PostgreSQL's bytea(n) columns, e.g. object IDs.
+	binaryColumn
+)
+
+// String implements the [fmt.Stringer] interface.
+func (k columnKind) String() string {
+	switch k {
+	case otherColumn:
+		return "other"
+	case textColumn:
+		return "text"
+	case enumColumn:
+		return "enum"
+	case floatColumn:
+		return "float"
+	case integerColumn:
+		return "integer"
+	case binaryColumn:
+		return "binary"
+	default:
+		return fmt.Sprintf("columnKind(%d)", uint8(k))
+	}
+}
+
+// integerType matches MySQL's and PostgreSQL's integer column types.
+var integerType = regexp.MustCompile(`^(tiny|small|medium|big)?int(eger|[248])?\b`)
+
+// columnKindOf returns the kind of columns of the MySQL column_type or PostgreSQL udt_name typ,
+// where the latter is "enum" for PostgreSQL enum types.
+func columnKindOf(typ string) columnKind {
+	switch typ = strings.ToLower(typ); {
+	case strings.HasPrefix(typ, "binary"), strings.HasPrefix(typ, "varbinary"),
+		strings.HasSuffix(typ, "blob"), typ == "bytea":
+		return binaryColumn
+	case integerType.MatchString(typ):
+		return integerColumn
+	case strings.HasPrefix(typ, "float"), strings.HasPrefix(typ, "double"), strings.HasPrefix(typ, "real"),
+		strings.HasPrefix(typ, "decimal"), strings.HasPrefix(typ, "numeric"):
+		return floatColumn
+	case strings.HasPrefix(typ, "enum"):
+		return enumColumn
+	case strings.Contains(typ, "char"), strings.HasSuffix(typ, "text"):
+		return textColumn
+	default:
+		return otherColumn
+	}
+}
+
+// column is a column of a table.
+type column struct {
+	name string
+	typ  string
+	kind columnKind
+}
+
+// scanDest returns a pointer to scan a value of c into.
+func (c column) scanDest() any {
+	switch c.kind {
+	case binaryColumn:
+		return new([]byte)
+	case integerColumn:
+		return new(sql.NullInt64)
+	default:
+		return new(sql.NullString)
+	}
+}
+
+// table is a table of the Icinga DB schema.
+type table struct {
+	name    string
+	columns []column
+	// key are the primary key columns.
+	key []column
+	// referencing tells whether the table has foreign keys to other tables.
+	referencing bool
+}
+
+// row is a table row by column name. It's a [database.Entity] to bulk insert it via [database.DB.NamedBulkExec].
+type row map[string]any
+
+// Fingerprint implements the [database.Fingerprinter] interface.
+func (r row) Fingerprint() database.Fingerprinter {
+	return r
+}
+
+// ID implements part of the [database.IDer] interface.
+func (r row) ID() database.ID {
+	return nil
+}
+
+// SetID implements part of the [database.IDer] interface.
+func (r row) SetID(database.ID) {
+}
+
+// Assert interface compliance.
+var _ database.Entity = row(nil)
+
+// schemaFunc returns the SQL function returning the current schema of db.
+func schemaFunc(db *database.DB) (string, error) {
+	switch db.DriverName() {
+	case database.MySQL:
+		return "DATABASE()", nil
+	case database.PostgreSQL:
+		return "CURRENT_SCHEMA()", nil
+	default:
+		return "", errors.Errorf("unsupported database driver %q", db.DriverName())
+	}
+}
+
+// listTables returns all tables of the Icinga DB schema in db except for icingadb_schema.
+func listTables(ctx context.Context, db *database.DB) ([]*table, error) {
+	schema, err := schemaFunc(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	query := "SELECT table_name AS name FROM information_schema.tables WHERE table_schema=" + schema +
+		" AND table_type='BASE TABLE' AND table_name <> 'icingadb_schema' ORDER BY table_name"
+	if err := db.SelectContext(ctx, &names, query); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	tables := make([]*table, 0, len(names))
+	byName := make(map[string]*table, len(names))
+	for _, name := range names {
+		t := &table{name: name}
+		tables = append(tables, t)
+		byName[name] = t
+	}
+
+	var columns []struct {
+		Tbl  string
+		Name string
+		Type string
+	}
+
+	query = "SELECT table_name AS tbl, column_name AS name, column_type AS type FROM information_schema.columns" +
+		" WHERE table_schema=" + schema + " ORDER BY table_name, ordinal_position"
+	if db.DriverName() == database.PostgreSQL {
+		// udt_name is the underlying type of domains, e.g. int8 for biguint, and the name of user-defined types.
+		query = "SELECT c.table_name AS tbl, c.column_name AS name," +
+			" CASE WHEN t.typtype = 'e' THEN 'enum' ELSE c.udt_name END AS type FROM information_schema.columns c" +
+			" LEFT JOIN pg_namespace n ON n.nspname = c.udt_schema" +
+			" LEFT JOIN pg_type t ON t.typnamespace = n.oid AND t.typname = c.udt_name" +
+			" WHERE c.table_schema=" + schema + " ORDER BY c.table_name, c.ordinal_position"
+	}
+
+	if err := db.SelectContext(ctx, &columns, query); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	for _, c := range columns {
+		if t, ok := byName[c.Tbl]; ok {
+			t.columns = append(t.columns, column{name: c.Name, typ: c.Type, kind: columnKindOf(c.Type)})
+		}
+	}
+
+	var keys []struct {
+		Tbl  string
+		Name string
+	}
+
+	query = "SELECT tc.table_name AS tbl, kcu.column_name AS name FROM information_schema.table_constraints tc" +
+		" INNER JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema=tc.constraint_schema" +
+		" AND kcu.constraint_name=tc.constraint_name AND kcu.table_name=tc.table_name" +
+		" WHERE tc.table_schema=" + schema + " AND tc.constraint_type='PRIMARY KEY'" +
+		" ORDER BY tc.table_name, kcu.ordinal_position"
+	if err := db.SelectContext(ctx, &keys, query); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	for _, k := range keys {
+		if t, ok := byName[k.Tbl]; ok {
+			for _, c := range t.columns {
+				if c.name == k.Name {
+					t.key = append(t.key, c)
+				}
+			}
+		}
+	}
+
+	var referencing []string
+	query = "SELECT DISTINCT table_name FROM information_schema.table_constraints WHERE table_schema=" + schema +
+		" AND constraint_type='FOREIGN KEY'"
+	if err := db.SelectContext(ctx, &referencing, query); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	for _, name := range referencing {
+		if t, ok := byName[name]; ok {
+			t.referencing = true
+		}
+	}
+
+	for _, t := range tables {
+		if len(t.key) == 0 {
+			return nil, errors.Errorf("table %q has no primary key", t.name)
+		}
+	}
+
+	return tables, nil
+}
+
+// checkColumns returns an error if the columns of t in the destination database, dest, differ from the ones of t
+// in the source database by name or kind, as the values are copied by column name in a representation of their kind.
+func (t *table) checkColumns(dest *table) error {
+	destColumns := make(map[string]column, len(dest.columns))
+	for _, c := range dest.columns {
+		destColumns[c.name] = c
+	}
+
+	for _, c := range t.columns {
+		d, ok := destColumns[c.name]
+		if !ok {
+			return errors.Errorf("column %q doesn't exist in the destination", c.name)
+		}
+
+		if c.kind != d.kind || (c.kind == otherColumn && !strings.EqualFold(c.typ, d.typ)) {
+			return errors.Errorf("column %q has incompatible types %q (%s) and %q (%s) in the source and destination",
+				c.name, c.typ, c.kind, d.typ, d.kind)
+		}
+
+		delete(destColumns, c.name)
+	}
+
+	if len(destColumns) > 0 {
+		return errors.Errorf("columns %q don't exist in the source", slices.Sorted(maps.Keys(destColumns)))
+	}
+
+	return nil
+}
+
+// selectStmt returns a query selecting the next pageSize rows of t ordered by the primary key.
+// If after is true, the query takes the primary key values to select the rows after as arguments.
+func (t *table) selectStmt(after bool) string {
+	columns := make([]string, 0, len(t.columns))
+	for _, c := range t.columns {
+		columns = append(columns, `"`+c.name+`"`)
+	}
+
+	key := make([]string, 0, len(t.key))
+	for _, c := range t.key {
+		key = append(key, `"`+c.name+`"`)
+	}
+
+	var where string
+	if after {
+		where = " WHERE (" + strings.Join(key, ", ") + ") > (" + strings.TrimSuffix(strings.Repeat("?, ", len(key)), ", ") + ")"
+	}
+
+	return "SELECT " + strings.Join(columns, ", ") + ` FROM "` + t.name + `"` + where +
+		" ORDER BY " + strings.Join(key, ", ") + " LIMIT " + strconv.Itoa(pageSize)
+}
+
+// lastKeyStmt returns a query selecting the greatest primary key of t.
+func (t *table) lastKeyStmt() string {
+	key := make([]string, 0, len(t.key))
+	desc := make([]string, 0, len(t.key))
+	for _, c := range t.key {
+		key = append(key, `"`+c.name+`"`)
+		desc = append(desc, `"`+c.name+`" DESC`)
+	}
+
+	return "SELECT " + strings.Join(key, ", ") + ` FROM "` + t.name + `" ORDER BY ` + strings.Join(desc, ", ") + " LIMIT 1"
+}
+
+// insertStmt returns a statement inserting rows of t into a database of the given driver,
+// ignoring rows with primary keys which already exist.
+func (t *table) insertStmt(driver string) string {
+	columns := make([]string, 0, len(t.columns))
+	values := make([]string, 0, len(t.columns))
+	for _, c := range t.columns {
+		columns = append(columns, `"`+c.name+`"`)
+		values = append(values, ":"+c.name)
+	}
+
+	into := `INTO "` + t.name + `" (` + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ")"
+
+	if driver == database.MySQL {
+		return "INSERT IGNORE " + into
+	}
+
+	return "INSERT " + into + " ON CONFLICT DO NOTHING"
+}
+
+// lastKey returns the greatest primary key of t in db or nil if t is empty there.
+func (t *table) lastKey(ctx context.Context, db *database.DB) ([]any, error) {
+	dest := make([]any, 0, len(t.key))
+	for _, c := range t.key {
+		dest = append(dest, c.scanDest())
+	}
+
+	query := t.lastKeyStmt()
+	if err := db.QueryRowxContext(ctx, query).Scan(dest...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	return derefAll(dest), nil
+}
+
+// transferTable copies all rows of t from src to dest, starting after the greatest primary key already in dest,
+// and returns the number of rows copied.
+func transferTable(ctx context.Context, src, dest *database.DB, t *table) (int64, error) {
+	after, err := t.lastKey(ctx, dest)
+	if err != nil {
+		return 0, err
+	}
+
+	insertStmt := t.insertStmt(dest.DriverName())
+	batchSize := dest.BatchSizeByPlaceholders(len(t.columns))
+
+	var transferred int64
+	for {
+		query := src.Rebind(t.selectStmt(after != nil))
+
+		page, err := selectPage(ctx, src, t, query, after)
+		if err != nil {
+			return transferred, err
+		}
+
+		if len(page) == 0 {
+			return transferred, nil
+		}
+
+		// A weight of 1 inserts the rows in order. So the rows in dest always continue right after the last key.
+		err = dest.NamedBulkExec(
+			ctx, insertStmt, batchSize, semaphore.NewWeighted(1),
+			utils.ChanFromSlice(page), com.NeverSplit[database.Entity],
+		)
+		if err != nil {
+			return transferred, err
+		}
+
+		transferred += int64(len(page))
+
+		last := page[len(page)-1].(row)
+		after = make([]any, 0, len(t.key))
+		for _, c := range t.key {
+			after = append(after, last[c.name])
+		}
+	}
+}
+
+// selectPage performs query on src with the args after and returns the selected rows of t.
+func selectPage(ctx context.Context, src *database.DB, t *table, query string, after []any) ([]database.Entity, error) {
+	rows, err := src.QueryxContext(ctx, query, after...)
+	if err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var page []database.Entity
+	for rows.Next() {
+		dest := make([]any, 0, len(t.columns))
+		for _, c := range t.columns {
+			dest = append(dest, c.scanDest())
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errors.Wrap(err, "can't scan row")
+		}
+
+		r := make(row, len(t.columns))
+		for i, v := range derefAll(dest) {
+			r[t.columns[i].name] = v
+		}
+
+		page = append(page, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	return page, nil
+}
+
+// derefAll dereferences all pointers returned by [column.scanDest].
+func derefAll(pointers []any) []any {
+	values := make([]any, 0, len(pointers))
+	for _, p := range pointers {
+		switch p := p.(type) {
+		case *[]byte:
+			values = append(values, *p)
+		case *sql.NullInt64:
+			values = append(values, *p)
+		case *sql.NullString:
+			values = append(values, *p)
+		}
+	}
+
+	return values
+}
+
+// nextvalDefault matches PostgreSQL column defaults taking the next value of a sequence.
+var nextvalDefault = regexp.MustCompile(`^nextval\('([^']+)'`)
+
+// resetSequences sets the PostgreSQL sequences of t in db right after the greatest values of their columns,
+// so that rows inserted later don't collide with the transferred ones. On MySQL it does nothing.
+func resetSequences(ctx context.Context, db *database.DB, t *table) error {
+	if db.DriverName() != database.PostgreSQL {
+		return nil
+	}
+
+	var columns []struct {
+		Name string
+		Dflt string
+	}
+
+	query := "SELECT column_name AS name, column_default AS dflt FROM information_schema.columns" +
+		" WHERE table_schema=CURRENT_SCHEMA() AND table_name=$1 AND column_default LIKE 'nextval(%'"
+	if err := db.SelectContext(ctx, &columns, query, t.name); err != nil {
+		return database.CantPerformQuery(err, query)
+	}
+
+	for _, c := range columns {
+		if match := nextvalDefault.FindStringSubmatch(c.Dflt); match != nil {
+			query := `SELECT setval($1, COALESCE(MAX("` + c.Name + `"), 0) + 1, false) FROM "` + t.name + `"`
+			if _, err := db.ExecContext(ctx, query, match[1]); err != nil {
+				return database.CantPerformQuery(err, query)
+			}
+		}
+	}
+
+	return nil
+}
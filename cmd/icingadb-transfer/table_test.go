@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestColumnKindOf(t *testing.T) {
+	subtests := []struct {
+		typ  string
+		kind columnKind
+	}{
+		{typ: "binary(20)", kind: binaryColumn},
+		{typ: "varbinary(255)", kind: binaryColumn},
+		{typ: "mediumblob", kind: binaryColumn},
+		{typ: "bytea", kind: binaryColumn},
+		{typ: "bigint unsigned", kind: integerColumn},
+		{typ: "tinyint(3) unsigned", kind: integerColumn},
+		{typ: "int", kind: integerColumn},
+		{typ: "int2", kind: integerColumn},
+		{typ: "int8", kind: integerColumn},
+		{typ: "integer", kind: integerColumn},
+		{typ: "smallint", kind: integerColumn},
+		{typ: "enum('n','y')", kind: enumColumn},
+		{typ: "enum", kind: enumColumn},
+		{typ: "float", kind: floatColumn},
+		{typ: "float8", kind: floatColumn},
+		{typ: "double", kind: floatColumn},
+		{typ: "text", kind: textColumn},
+		{typ: "longtext", kind: textColumn},
+		{typ: "varchar(255)", kind: textColumn},
+		{typ: "varchar", kind: textColumn},
+		{typ: "citext", kind: textColumn},
+		{typ: "interval", kind: otherColumn},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.typ, func(t *testing.T) {
+			require.Equal(t, st.kind, columnKindOf(st.typ))
+		})
+	}
+}
+
+func TestTable_checkColumns(t *testing.T) {
+	src := &table{name: "host", columns: []column{
+		{name: "id", typ: "binary(20)", kind: binaryColumn},
+		{name: "name", typ: "varchar(255)", kind: textColumn},
+		{name: "active", typ: "enum('n','y')", kind: enumColumn},
+	}}
+
+	dest := &table{name: "host", columns: []column{
+		{name: "active", typ: "enum", kind: enumColumn},
+		{name: "id", typ: "bytea", kind: binaryColumn},
+		{name: "name", typ: "citext", kind: textColumn},
+	}}
+	require.NoError(t, src.checkColumns(dest))
+
+	dest.columns[0] = column{name: "active", typ: "int2", kind: integerColumn}
+	require.ErrorContains(t, src.checkColumns(dest), `column "active" has incompatible types`)
+
+	dest.columns[0] = column{name: "enabled", typ: "enum", kind: enumColumn}
+	require.ErrorContains(t, src.checkColumns(dest), `column "active" doesn't exist in the destination`)
+
+	src.columns[2] = column{name: "enabled", typ: "interval", kind: otherColumn}
+	dest.columns[0] = column{name: "enabled", typ: "timestamp", kind: otherColumn}
+	require.ErrorContains(t, src.checkColumns(dest), `column "enabled" has incompatible types`)
+
+	src.columns = src.columns[:2]
+	require.ErrorContains(t, src.checkColumns(dest), `columns ["enabled"] don't exist in the source`)
+}
+
+func TestTable_Stmts(t *testing.T) {
+	tbl := &table{
+		name:    "comment_history",
+		columns: []column{{name: "comment_id", kind: binaryColumn}, {name: "author", kind: textColumn}},
+		key:     []column{{name: "comment_id", kind: binaryColumn}},
+	}
+
+	require.Equal(t,
+		`SELECT "comment_id", "author" FROM "comment_history" ORDER BY "comment_id" LIMIT 10000`,
+		tbl.selectStmt(false))
+	require.Equal(t,
+		`SELECT "comment_id", "author" FROM "comment_history" WHERE ("comment_id") > (?) ORDER BY "comment_id" LIMIT 10000`,
+		tbl.selectStmt(true))
+	require.Equal(t,
+		`SELECT "comment_id" FROM "comment_history" ORDER BY "comment_id" DESC LIMIT 1`,
+		tbl.lastKeyStmt())
+	require.Equal(t,
+		`INSERT IGNORE INTO "comment_history" ("comment_id", "author") VALUES (:comment_id, :author)`,
+		tbl.insertStmt(database.MySQL))
+	require.Equal(t,
+		`INSERT INTO "comment_history" ("comment_id", "author") VALUES (:comment_id, :author) ON CONFLICT DO NOTHING`,
+		tbl.insertStmt(database.PostgreSQL))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icingadb/pkg/icingadb/archive"
+	"github.com/icinga/icingadb/pkg/reporting"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"time"
+)
+
+// archiveTimestamp is a CLI flag value which accepts RFC 3339 timestamps and dates (YYYY-MM-DD) in local time.
+type archiveTimestamp struct {
+	time.Time
+}
+
+// UnmarshalFlag implements the [flags.Unmarshaler] interface.
+func (t *archiveTimestamp) UnmarshalFlag(value string) error {
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		t.Time = ts
+		return nil
+	}
+
+	if ts, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		t.Time = ts
+		return nil
+	}
+
+	return errors.Errorf("can't parse %q as RFC 3339 timestamp or date", value)
+}
+
+// exportCommand implements the export command.
+type exportCommand struct {
+	options *commandOptions
+
+	Environment string           `short:"e" long:"environment" description:"name of the Icinga environment (default: the only one)"`
+	From        archiveTimestamp `long:"from" description:"start of the time range to export (RFC 3339 or YYYY-MM-DD)" required:"true"`
+	To          archiveTimestamp `long:"to" description:"end of the time range to export, exclusive (RFC 3339 or YYYY-MM-DD; default: now)"`
+	Output      string           `short:"o" long:"output" description:"archive file to write (default: standard output)"`
+}
+
+// Execute implements the [flags.Commander] interface.
+func (c *exportCommand) Execute([]string) error {
+	to := c.To.Time
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if !c.From.Before(to) {
+		return errors.New("--from must be before --to")
+	}
+
+	return withDatabase(c.options, func(ctx context.Context, db *database.DB) error {
+		env, err := reporting.NewReporter(db, nil).Environment(ctx, c.Environment)
+		if err != nil {
+			return err
+		}
+
+		out := os.Stdout
+		if c.Output != "" {
+			f, err := os.Create(c.Output)
+			if err != nil {
+				return errors.Wrap(err, "can't create archive file")
+			}
+			defer func() { _ = f.Close() }()
+
+			out = f
+		}
+
+		counts, err := archive.Export(ctx, db, out, env.Id, archive.Window{From: c.From.Time, To: to})
+		if err != nil {
+			return err
+		}
+
+		if c.Output != "" {
+			if err := out.Close(); err != nil {
+				return errors.Wrap(err, "can't write archive file")
+			}
+		}
+
+		printArchiveCounts("Exported", counts)
+
+		return nil
+	})
+}
+
+// importCommand implements the import command.
+type importCommand struct {
+	options *commandOptions
+
+	Args struct {
+		File string `positional-arg-name:"FILE" description:"archive file to read (default: standard input)"`
+	} `positional-args:"yes"`
+}
+
+// Execute implements the [flags.Commander] interface.
+func (c *importCommand) Execute([]string) error {
+	return withDatabase(c.options, func(ctx context.Context, db *database.DB) error {
+		var in io.Reader = os.Stdin
+		if c.Args.File != "" {
+			f, err := os.Open(c.Args.File)
+			if err != nil {
+				return errors.Wrap(err, "can't open archive file")
+			}
+			defer func() { _ = f.Close() }()
+
+			in = f
+		}
+
+		header, counts, err := archive.Import(ctx, db, in)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(
+			os.Stderr, "Imported history of environment %s from %s to %s\n",
+			header.EnvironmentId, header.From.Time().Format(time.RFC3339), header.To.Time().Format(time.RFC3339),
+		)
+		printArchiveCounts("Imported", counts)
+
+		return nil
+	})
+}
+
+// printArchiveCounts prints the number of rows per history table to standard error.
+func printArchiveCounts(verb string, counts map[string]int) {
+	for _, table := range archive.Tables() {
+		_, _ = fmt.Fprintf(os.Stderr, "%s %d rows of table %s\n", verb, counts[table], table)
+	}
+}
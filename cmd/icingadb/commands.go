@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icingadb/internal/command"
+	icingadbconfig "github.com/icinga/icingadb/internal/config"
+	"github.com/icinga/icingadb/pkg/icingadb"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// commandOptions defines the CLI flags shared by all commands.
+type commandOptions struct {
+	// Config is the path to the config file. If not provided, it defaults to DefaultConfigPath.
+	Config string `short:"c" long:"config" description:"path to config file (default: /etc/icingadb/config.yml)"`
+}
+
+// isCommand returns whether name is one of the commands run by runCommand instead of the daemon.
+func isCommand(name string) bool {
+	switch name {
+	case "export", "import", "environment":
+		return true
+	default:
+		return false
+	}
+}
+
+// runCommand runs the command given on the command line and returns the exit code.
+func runCommand() int {
+	options := &commandOptions{}
+	parser := flags.NewParser(options, flags.Default)
+
+	commands := []struct {
+		name, short, long string
+		data              any
+	}{{
+		"export", "Export history to an archive",
+		"Export the history of an Icinga environment within a time range to a compressed JSON Lines archive.",
+		&exportCommand{options: options},
+	}, {
+		"import", "Import history from an archive",
+		"Import the history from an archive written by the export command. Rows which already exist are skipped.",
+		&importCommand{options: options},
+	}, {
+		"environment", "Manage Icinga environments",
+		"List, rename and purge the Icinga environments in the database.",
+		&struct{}{},
+	}}
+
+	for _, c := range commands {
+		cmd, err := parser.AddCommand(c.name, c.short, c.long, c.data)
+		if err != nil {
+			panic(err)
+		}
+
+		if c.name == "environment" {
+			addEnvironmentCommands(cmd, options)
+		}
+	}
+
+	if _, err := parser.Parse(); err != nil {
+		var flagsErr *flags.Error
+		if errors.As(err, &flagsErr) && flagsErr.Type == flags.ErrHelp {
+			return ExitSuccess
+		}
+
+		// The error, including those returned by commands, has already been printed by the parser.
+		return ExitFailure
+	}
+
+	return ExitSuccess
+}
+
+// withDatabase calls fn with a connection to the database configured in the config file of options.
+func withDatabase(options *commandOptions, fn func(ctx context.Context, db *database.DB) error) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cmd, err := command.Load(icingadbconfig.Flags{Config: options.Config})
+	if err != nil {
+		return errors.Wrap(err, "can't load config")
+	}
+
+	logs, err := logging.NewLoggingFromConfig("icingadb", cmd.Config.Logging)
+	if err != nil {
+		return errors.Wrap(err, "can't configure logging")
+	}
+
+	db, err := cmd.Database(logs.GetChildLogger("database"))
+	if err != nil {
+		return errors.Wrap(err, "can't create database connection pool from config")
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := icingadb.CheckSchema(ctx, db); err != nil {
+		return err
+	}
+
+	return fn(ctx, db)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icingadb/pkg/icingadb"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+)
+
+// addEnvironmentCommands adds the subcommands of the environment command to cmd.
+func addEnvironmentCommands(cmd *flags.Command, options *commandOptions) {
+	commands := []struct {
+		name, short, long string
+		data              any
+	}{{
+		"list", "List environments with their number of rows per table",
+		"List all environments, including those only referenced by rows of former environments, " +
+			"with their number of rows per table.",
+		&environmentListCommand{options: options},
+	}, {
+		"rename", "Set the name of an environment",
+		"Set the human-readable name of an environment, which is its hex encoded ID after creation.",
+		&environmentRenameCommand{options: options},
+	}, {
+		"purge", "Delete all data of an environment",
+		"Delete all config, state and history rows of an environment in batches, and finally the environment itself.",
+		&environmentPurgeCommand{options: options},
+	}}
+
+	for _, c := range commands {
+		if _, err := cmd.AddCommand(c.name, c.short, c.long, c.data); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// environmentListCommand implements the environment list command.
+type environmentListCommand struct {
+	options *commandOptions
+}
+
+// Execute implements the [flags.Commander] interface.
+func (c *environmentListCommand) Execute([]string) error {
+	return withDatabase(c.options, func(ctx context.Context, db *database.DB) error {
+		envs, err := icingadb.Environments(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		tables, err := icingadb.EnvironmentTables(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		counts, err := icingadb.CountEnvironmentRows(ctx, db, tables)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		printEnv := func(id, name string) {
+			var total uint64
+			for _, count := range counts[id] {
+				total += count
+			}
+
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\n", id, name, total)
+
+			for _, table := range slices.Sorted(maps.Keys(counts[id])) {
+				_, _ = fmt.Fprintf(w, "\t  %s\t%d\n", table, counts[id][table])
+			}
+		}
+
+		_, _ = fmt.Fprintln(w, "ID\tNAME\tROWS")
+
+		for _, env := range envs {
+			printEnv(env.Id.String(), env.Name.String)
+			delete(counts, env.Id.String())
+		}
+
+		// Rows of environments which don't exist anymore.
+		for _, id := range slices.Sorted(maps.Keys(counts)) {
+			printEnv(id, "-")
+		}
+
+		return w.Flush()
+	})
+}
+
+// environmentRenameCommand implements the environment rename command.
+type environmentRenameCommand struct {
+	options *commandOptions
+
+	Args struct {
+		Environment string `positional-arg-name:"ENVIRONMENT" description:"current name or hex encoded ID" required:"yes"`
+		Name        string `positional-arg-name:"NAME" description:"new name" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+// Execute implements the [flags.Commander] interface.
+func (c *environmentRenameCommand) Execute([]string) error {
+	if c.Args.Name == "" {
+		return errors.New("the new name must not be empty")
+	}
+
+	return withDatabase(c.options, func(ctx context.Context, db *database.DB) error {
+		env, err := icingadb.FindEnvironment(ctx, db, c.Args.Environment)
+		if err != nil {
+			return err
+		}
+
+		if other, err := icingadb.FindEnvironment(ctx, db, c.Args.Name); err == nil && other.Name.Valid {
+			return errors.Errorf("environment %s is already named %q", other.Id, c.Args.Name)
+		}
+
+		if err := icingadb.RenameEnvironment(ctx, db, env.Id, c.Args.Name); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(os.Stderr, "Renamed environment %s to %q\n", env.Id, c.Args.Name)
+
+		return nil
+	})
+}
+
+// environmentPurgeCommand implements the environment purge command.
+type environmentPurgeCommand struct {
+	options *commandOptions
+
+	Count uint64 `long:"count" description:"number of rows to delete at once" default:"5000"`
+	Yes   bool   `long:"yes" description:"actually delete the data, otherwise only show what would be deleted"`
+
+	Args struct {
+		Environment string `positional-arg-name:"ENVIRONMENT" description:"name or hex encoded ID" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+// Execute implements the [flags.Commander] interface.
+func (c *environmentPurgeCommand) Execute([]string) error {
+	if c.Count == 0 {
+		return errors.New("--count must be greater than zero")
+	}
+
+	return withDatabase(c.options, func(ctx context.Context, db *database.DB) error {
+		env, err := icingadb.FindEnvironment(ctx, db, c.Args.Environment)
+		if err != nil {
+			return err
+		}
+
+		tables, err := icingadb.EnvironmentTables(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		if !c.Yes {
+			counts, err := icingadb.CountEnvironmentRows(ctx, db, tables)
+			if err != nil {
+				return err
+			}
+
+			var total uint64
+			for _, count := range counts[env.Id.String()] {
+				total += count
+			}
+
+			_, _ = fmt.Fprintf(
+				os.Stderr, "Would delete %d rows of environment %s. Run again with --yes to delete them.\n",
+				total, environmentLabel(env),
+			)
+
+			return nil
+		}
+
+		err = icingadb.PurgeEnvironment(ctx, db, env.Id, tables, c.Count, func(table string, deleted uint64) {
+			if deleted > 0 {
+				_, _ = fmt.Fprintf(os.Stderr, "Deleted %d rows of table %s\n", deleted, table)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(os.Stderr, "Purged environment %s\n", environmentLabel(env))
+
+		return nil
+	})
+}
+
+// environmentLabel returns the name and ID of env for messages.
+func environmentLabel(env *v1.Environment) string {
+	if env.Name.Valid {
+		return fmt.Sprintf("%q (%s)", env.Name.String, env.Id)
+	}
+
+	return env.Id.String()
+}
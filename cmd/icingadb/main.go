@@ -3,15 +3,19 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/icinga/icinga-go-library/backoff"
+	"github.com/icinga/icinga-go-library/database"
 	"github.com/icinga/icinga-go-library/logging"
 	"github.com/icinga/icinga-go-library/redis"
 	"github.com/icinga/icinga-go-library/utils"
 	"github.com/icinga/icingadb/internal"
 	"github.com/icinga/icingadb/internal/command"
+	icingadbconfig "github.com/icinga/icingadb/internal/config"
 	"github.com/icinga/icingadb/pkg/common"
 	"github.com/icinga/icingadb/pkg/icingadb"
 	"github.com/icinga/icingadb/pkg/icingadb/history"
 	"github.com/icinga/icingadb/pkg/icingadb/overdue"
+	"github.com/icinga/icingadb/pkg/icingadb/perfdata"
 	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
 	"github.com/icinga/icingadb/pkg/icingaredis"
 	"github.com/icinga/icingadb/pkg/icingaredis/telemetry"
@@ -29,9 +33,8 @@ import (
 )
 
 const (
-	ExitSuccess                = 0
-	ExitFailure                = 1
-	expectedRedisSchemaVersion = "6"
+	ExitSuccess = 0
+	ExitFailure = 1
 )
 
 func main() {
@@ -39,6 +42,10 @@ func main() {
 }
 
 func run() int {
+	if len(os.Args) > 1 && isCommand(os.Args[1]) {
+		return runCommand()
+	}
+
 	cmd := command.New()
 
 	logs, err := logging.NewLoggingFromConfig(utils.AppName(), cmd.Config.Logging)
@@ -93,29 +100,145 @@ func run() int {
 		logger.Fatalf("%+v", err)
 	}
 
-	rc, err := cmd.Redis(logs.GetChildLogger("redis"))
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	// Cancelling stopCtx lets all environments shut down, while ctx is kept until they are done.
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	// SIGUSR1 and SIGUSR2 start and release a manual handover of all environments, e.g. for maintenance of this node.
+	// They are handled here, not per environment, so that the standby persists across environment restarts.
+	standbySig := make(chan os.Signal, 1)
+	signal.Notify(standbySig, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(standbySig)
+
+	envs := cmd.Config.RedisEnvironments()
+	standbys := make([]icingadb.StandbyState, len(envs))
+	go func() {
+		for {
+			select {
+			case s := <-standbySig:
+				if s == syscall.SIGUSR1 {
+					logger.WithOptions(logs.ForceLog()).Infow("Entering standby due to signal",
+						zap.String("signal", s.String()), zap.Duration("duration", cmd.Config.HA.StandbyDuration))
+
+					for i := range standbys {
+						standbys[i].Start(cmd.Config.HA.StandbyDuration)
+					}
+				} else {
+					logger.WithOptions(logs.ForceLog()).Infow("Releasing standby due to signal",
+						zap.String("signal", s.String()))
+
+					for i := range standbys {
+						standbys[i].Release()
+					}
+				}
+			case <-stopCtx.Done():
+				return
+			}
+		}
+	}()
+
+	exited := make(chan int, len(envs))
+	wg := sync.WaitGroup{}
+
+	for i := range envs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			logs := environmentLogging{logs, envs[i].Name}
+			if len(envs) == 1 {
+				if err := runEnvironment(ctx, stopCtx, cmd, &envs[i], &standbys[i], db, logs); err != nil {
+					logs.GetLogger().Errorf("%+v", err)
+					exited <- ExitFailure
+				}
+
+				return
+			}
+
+			// With multiple environments, a failing environment is restarted without affecting the others.
+			restartBackoff := backoff.NewExponentialWithJitter(time.Second, time.Minute)
+			for attempt := uint64(1); ; attempt++ {
+				start := time.Now()
+				err := runEnvironment(ctx, stopCtx, cmd, &envs[i], &standbys[i], db, logs)
+				if err == nil {
+					return
+				}
+
+				if time.Since(start) > time.Minute {
+					// The environment ran properly for a while, so start over with a short delay.
+					attempt = 1
+				}
+
+				delay := restartBackoff(attempt)
+				logs.GetLogger().Errorw("Environment failed, restarting it",
+					zap.Error(err), zap.Duration("after", delay), zap.Uint64("attempt", attempt))
+
+				select {
+				case <-time.After(delay):
+				case <-stopCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	var exitCode int
+	select {
+	case s := <-sig:
+		logger.Infow("Exiting due to signal", zap.String("signal", s.String()))
+		exitCode = ExitSuccess
+	case exitCode = <-exited:
+	}
+
+	stop()
+	wg.Wait()
+
+	return exitCode
+}
+
+// runEnvironment runs the HA, sync, history and retention stack of env until stopCtx is done or it fails
+// and returns the error, if any. All environments share the database connection pool db.
+// The manual standby of env's HA is tracked in standby, which outlives restarts of env.
+func runEnvironment(
+	ctx, stopCtx context.Context, cmd *command.Command, env *icingadbconfig.EnvironmentConfig,
+	standby *icingadb.StandbyState, db *database.DB, logs environmentLogging,
+) error {
+	logger := logs.GetLogger()
+
+	// Cancelling ctx with an error stops all components of this environment and lets runEnvironment return it.
+	ctx, fail := context.WithCancelCause(ctx)
+	defer fail(nil)
+
+	rc, err := cmd.Redis(env, logs.GetChildLogger("redis"))
 	if err != nil {
-		logger.Fatalw("Can't create Redis client from config", zap.Error(err))
+		return errors.Wrap(err, "can't create Redis client from config")
 	}
+	defer func() { _ = rc.Close() }()
 	{
 		logger.Infof("Connecting to Redis at '%s'", rc.GetAddr())
-		_, err := rc.Ping(context.Background()).Result()
+		_, err := rc.Ping(ctx).Result()
 		if err != nil {
-			logger.Fatalw("Can't create Redis client from config", zap.Error(err))
+			return errors.Wrap(err, "can't connect to Redis")
 		}
 	}
 
-	{
-		pos, err := checkRedisSchema(logger, rc, "0-0")
-		if err != nil {
-			logger.Fatalf("%+v", err)
+	schemaPos, schemaCompatibility, err := checkRedisSchema(stopCtx, logger, rc, "0-0")
+	if err != nil {
+		if utils.IsContextCanceled(err) {
+			return nil
 		}
 
-		go monitorRedisSchema(logger, rc, pos)
+		return err
 	}
 
-	ctx, cancelCtx := context.WithCancel(context.Background())
-	defer cancelCtx()
+	var stats telemetry.Counters
+	ctx = stats.NewContext(ctx)
 
 	// Use dedicated connections for heartbeat and HA to ensure that heartbeats are always processed and
 	// the instance table is updated. Otherwise, the connections can be too busy due to the synchronization of
@@ -124,23 +247,31 @@ func run() int {
 	var heartbeat *icingaredis.Heartbeat
 	var ha *icingadb.HA
 	var telemetrySyncStats *atomic.Pointer[telemetry.SuccessfulSync]
+	var ongoingSyncStartMilli atomic.Int64
 	{
-		rc, err := cmd.Redis(logs.GetChildLogger("redis"))
+		rc, err := cmd.Redis(env, logs.GetChildLogger("redis"))
 		if err != nil {
-			logger.Fatalw("Can't connect to Redis", zap.Error(err))
+			return errors.Wrap(err, "can't create Redis client from config")
 		}
+		defer func() { _ = rc.Close() }()
 		heartbeat = icingaredis.NewHeartbeat(ctx, rc, logs.GetChildLogger("heartbeat"))
 
 		db, err := cmd.Database(logs.GetChildLogger("database"))
 		if err != nil {
-			logger.Fatalw("Can't create database connection pool from config", zap.Error(err))
+			return errors.Wrap(err, "can't create database connection pool from config")
 		}
 		defer func() { _ = db.Close() }()
 		db.SetMaxOpenConns(1)
-		ha = icingadb.NewHA(ctx, db, heartbeat, logs.GetChildLogger("high-availability"))
+		clock := icingadb.NewClockSkew(ctx, db, rc, cmd.Config.HA.MaxClockSkew, logs.GetChildLogger("high-availability"))
+		ha = icingadb.NewHA(
+			ctx, db, heartbeat, clock, standby, cmd.Config.HA, logs.GetChildLogger("high-availability"),
+		)
+		ha.SetDegraded(schemaCompatibility == icingaredis.SchemaHistoryOnly)
 
 		telemetryLogger := logs.GetChildLogger("telemetry")
-		telemetrySyncStats = telemetry.StartHeartbeat(ctx, rc, telemetryLogger, ha, heartbeat)
+		telemetrySyncStats = telemetry.StartHeartbeat(
+			ctx, rc, telemetryLogger, ha, heartbeat, clock, &ongoingSyncStartMilli,
+		)
 		telemetry.WriteStats(ctx, rc, telemetryLogger)
 	}
 	// Closing ha on exit ensures that this instance retracts its heartbeat
@@ -152,6 +283,12 @@ func run() int {
 		_ = ha.Close(ctx)
 		cancelCtx()
 	}()
+	go func() {
+		if err := monitorRedisSchema(ctx, logger, rc, schemaPos, ha); !utils.IsContextCanceled(err) {
+			fail(err)
+		}
+	}()
+
 	s := icingadb.NewSync(db, rc, logs.GetChildLogger("config-sync"))
 	hs := history.NewSync(db, rc, logs.GetChildLogger("history-sync"))
 	rt := icingadb.NewRuntimeUpdates(db, rc, logs.GetChildLogger("runtime-updates"))
@@ -160,15 +297,13 @@ func run() int {
 		db,
 		cmd.Config.Retention.HistoryDays,
 		cmd.Config.Retention.SlaDays,
+		cmd.Config.Retention.PerfdataDays,
 		cmd.Config.Retention.Interval,
 		cmd.Config.Retention.Count,
 		cmd.Config.Retention.Options,
 		logs.GetChildLogger("retention"),
 	)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
-
 	var notificationsSource *notifications.Client
 	if cfg := cmd.Config.Notifications; cfg.Url != "" {
 		logger.Info("Starting Icinga Notifications source")
@@ -177,25 +312,65 @@ func run() int {
 			db,
 			rc,
 			logs.GetChildLogger("notifications"),
-			cfg,
+			cfg.Config,
+			cfg.Options,
 			ha.NotificationsHeartbeat())
 		if err != nil {
-			logger.Fatalw("Can't create Icinga Notifications client from config", zap.Error(err))
+			return errors.Wrap(err, "can't create Icinga Notifications client from config")
+		}
+	}
+
+	var perfdataWriter *perfdata.Writer
+	if cfg := cmd.Config.Perfdata; cfg.Enabled {
+		perfdataWriter = perfdata.NewWriter(db, logs.GetChildLogger("perfdata"), cfg.RollupInterval)
+	}
+
+	var perfdataExporter *perfdata.Exporter
+	if cfg := cmd.Config.Perfdata.Export; cfg.Type != "" {
+		perfdataExporter, err = perfdata.NewExporter(db, logs.GetChildLogger("perfdata-export"), cfg)
+		if err != nil {
+			return errors.Wrap(err, "can't create performance data exporter from config")
 		}
 	}
 
+	var webhooks *notifications.Webhooks
+	if len(cmd.Config.Webhooks) > 0 {
+		webhooks, err = notifications.NewWebhooks(db, rc, logs.GetChildLogger("webhooks"), cmd.Config.Webhooks)
+		if err != nil {
+			return errors.Wrap(err, "can't create webhooks from config")
+		}
+
+		go func() {
+			logger.Info("Starting webhooks")
+
+			if err := webhooks.Run(ctx); err != nil && !utils.IsContextCanceled(err) {
+				fail(err)
+			}
+		}()
+	}
+
 	go func() {
 		logger.Info("Starting history sync")
 
-		var extraStages map[string]history.StageFunc
-		//if notificationsSource != nil {
-		// We don't need to subscribe to the history pipelines anymore, so disabling them temporarily until
-		// someone works on implementing https://github.com/Icinga/icinga-notifications/issues/409.
-		//extraStages = notificationsSource.SyncExtraStages(ctx)
-		//}
+		isResponsible := func() bool {
+			_, responsible, _ := ha.State()
+			return responsible
+		}
+
+		extraStages := make(map[string][]history.StageFunc)
+		if notificationsSource != nil {
+			for key, stage := range notificationsSource.SyncExtraStages(ctx, isResponsible) {
+				extraStages[key] = append(extraStages[key], stage)
+			}
+		}
+		if webhooks != nil {
+			for key, stage := range webhooks.SyncExtraStages(isResponsible) {
+				extraStages[key] = append(extraStages[key], stage)
+			}
+		}
 
 		if err := hs.Sync(ctx, extraStages); err != nil && !utils.IsContextCanceled(err) {
-			logger.Fatalf("%+v", err)
+			fail(err)
 		}
 	}()
 
@@ -220,7 +395,12 @@ func run() int {
 						// otherwise updates may be lost.
 						runtimeConfigUpdateStreams, runtimeStateUpdateStreams, err := rt.ClearStreams(synctx)
 						if err != nil {
-							logger.Fatalf("%+v", err)
+							cancelSynctx()
+							if !utils.IsContextCanceled(err) {
+								fail(err)
+							}
+
+							return
 						}
 
 						dump := icingadb.NewDumpSignals(rc, logs.GetChildLogger("dump-signals"))
@@ -249,7 +429,7 @@ func run() int {
 						})
 
 						syncStart := time.Now()
-						telemetry.OngoingSyncStartMilli.Store(syncStart.UnixMilli())
+						ongoingSyncStartMilli.Store(syncStart.UnixMilli())
 
 						logger.Info("Starting config sync")
 						for _, factory := range v1.ConfigFactories {
@@ -297,7 +477,7 @@ func run() int {
 
 						g.Go(func() error {
 							configInitSync.Wait()
-							telemetry.OngoingSyncStartMilli.Store(0)
+							ongoingSyncStartMilli.Store(0)
 
 							syncEnd := time.Now()
 							elapsed := syncEnd.Sub(syncStart)
@@ -310,6 +490,7 @@ func run() int {
 								})
 
 								logger.Infof("Finished config sync in %s", elapsed)
+								ha.RecordSyncDuration(synctx, elapsed)
 							} else {
 								logger.Warnf("Aborted config sync after %s", elapsed)
 							}
@@ -354,7 +535,27 @@ func run() int {
 
 							runtimeUpdatesOpts := []icingadb.RUOption{icingadb.WithAllowParallel()}
 							if notificationsSource != nil {
-								runtimeUpdatesOpts = append(runtimeUpdatesOpts, icingadb.WithRUUpsert(notificationsSource.Submit))
+								runtimeUpdatesOpts = append(runtimeUpdatesOpts, icingadb.WithRUUpsert(
+									"Icinga Notifications", notificationsSource.Enqueue,
+								))
+								if cmd.Config.Notifications.CheckOutputSync == notifications.CheckOutputSyncEvents {
+									runtimeUpdatesOpts = append(runtimeUpdatesOpts, icingadb.WithRUUpsert(
+										"Icinga Notifications outputs", notificationsSource.UpdateCheckOutput,
+									))
+								}
+							}
+							if webhooks != nil {
+								runtimeUpdatesOpts = append(runtimeUpdatesOpts, icingadb.WithLossyRUUpsert("webhooks", webhooks.Submit))
+							}
+							if perfdataWriter != nil {
+								runtimeUpdatesOpts = append(runtimeUpdatesOpts, icingadb.WithLossyRUUpsert(
+									"performance data", perfdataWriter.Submit,
+								))
+							}
+							if perfdataExporter != nil {
+								runtimeUpdatesOpts = append(runtimeUpdatesOpts, icingadb.WithLossyRUUpsert(
+									"performance data export", perfdataExporter.Submit,
+								))
 							}
 
 							return rt.Sync(synctx, v1.StateFactories, runtimeStateUpdateStreams, runtimeUpdatesOpts...)
@@ -374,6 +575,34 @@ func run() int {
 							return ret.Start(synctx)
 						})
 
+						if perfdataWriter != nil {
+							g.Go(func() error {
+								stateInitSync.Wait()
+
+								if err := synctx.Err(); err != nil {
+									return err
+								}
+
+								logger.Info("Starting performance data sync")
+
+								return perfdataWriter.Run(synctx)
+							})
+						}
+
+						if perfdataExporter != nil {
+							g.Go(func() error {
+								stateInitSync.Wait()
+
+								if err := synctx.Err(); err != nil {
+									return err
+								}
+
+								logger.Info("Starting performance data export")
+
+								return perfdataExporter.Run(synctx)
+							})
+						}
+
 						if notificationsSource != nil {
 							g.Go(func() error {
 								stateInitSync.Wait()
@@ -382,14 +611,29 @@ func run() int {
 									return err
 								}
 
-								logger.Info("Starting Icinga Notifications periodic outputs sync")
+								logger.Infow("Starting Icinga Notifications outputs sync",
+									zap.String("mode", cmd.Config.Notifications.CheckOutputSync))
 
 								return notificationsSource.SyncCheckOutputs(synctx)
 							})
 						}
 
+						if notificationsSource != nil {
+							g.Go(func() error {
+								stateInitSync.Wait()
+
+								if err := synctx.Err(); err != nil {
+									return err
+								}
+
+								logger.Info("Starting Icinga Notifications outbox")
+
+								return notificationsSource.SendOutbox(synctx)
+							})
+						}
+
 						if err := g.Wait(); err != nil && !utils.IsContextCanceled(err) {
-							logger.Fatalf("%+v", err)
+							fail(err)
 						}
 					}
 				}()
@@ -399,25 +643,27 @@ func run() int {
 				cancelHactx()
 			case <-hactx.Done():
 				if ctx.Err() != nil {
-					logger.Fatalw("Main context closed unexpectedly", zap.Error(ctx.Err()))
+					cancelHactx()
+
+					return environmentErr(ctx, stopCtx)
 				}
 				// Otherwise, there is nothing to do here, surrounding loop will terminate now.
 			case <-ha.Done():
+				cancelHactx()
+
 				if err := ha.Err(); err != nil {
-					logger.Fatalw("HA exited with an error", zap.Error(err))
+					return errors.Wrap(err, "HA exited with an error")
 				} else if ctx.Err() == nil {
-					// ha is created as a single instance once. It should only exit if the main context is cancelled,
-					// otherwise there is no way to get Icinga DB back into a working state.
-					logger.Fatal("HA exited without an error but main context isn't cancelled")
+					// ha is created as a single instance once. It should only exit if the context is cancelled,
+					// otherwise there is no way to get the environment back into a working state.
+					return errors.New("HA exited without an error but context isn't cancelled")
 				}
-				cancelHactx()
 
-				return ExitFailure
-			case s := <-sig:
-				logger.Infow("Exiting due to signal", zap.String("signal", s.String()))
+				return environmentErr(ctx, stopCtx)
+			case <-stopCtx.Done():
 				cancelHactx()
 
-				return ExitSuccess
+				return nil
 			}
 		}
 
@@ -425,20 +671,43 @@ func run() int {
 	}
 }
 
-// monitorRedisSchema monitors rc's icinga:schema version validity.
-func monitorRedisSchema(logger *logging.Logger, rc *redis.Client, pos string) {
+// environmentErr returns the error with which runEnvironment's ctx was cancelled,
+// or nil if the environment was stopped via stopCtx.
+func environmentErr(ctx, stopCtx context.Context) error {
+	if stopCtx.Err() != nil {
+		return nil
+	}
+
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+
+	return errors.New("context closed unexpectedly")
+}
+
+// monitorRedisSchema monitors rc's icinga:schema version validity until ctx is done or the version is incompatible
+// and switches ha into degraded mode while only the history is supported.
+func monitorRedisSchema(
+	ctx context.Context, logger *logging.Logger, rc *redis.Client, pos string, ha *icingadb.HA,
+) error {
 	for {
+		var compatibility icingaredis.SchemaCompatibility
 		var err error
-		pos, err = checkRedisSchema(logger, rc, pos)
+		pos, compatibility, err = checkRedisSchema(ctx, logger, rc, pos)
 
 		if err != nil {
-			logger.Fatalf("%+v", err)
+			return err
 		}
+
+		ha.SetDegraded(compatibility == icingaredis.SchemaHistoryOnly)
 	}
 }
 
-// checkRedisSchema verifies rc's icinga:schema version.
-func checkRedisSchema(logger *logging.Logger, rc *redis.Client, pos string) (newPos string, err error) {
+// checkRedisSchema verifies rc's icinga:schema version and returns to which extent Icinga DB supports it.
+// An error is returned if the version is incompatible.
+func checkRedisSchema(
+	ctx context.Context, logger *logging.Logger, rc *redis.Client, pos string,
+) (newPos string, compatibility icingaredis.SchemaCompatibility, err error) {
 	if pos == "0-0" {
 		defer time.AfterFunc(3*time.Second, func() {
 			logger.Info("Waiting for Icinga 2 to write into Redis, please make sure you have started Icinga 2 and the Icinga DB feature is enabled")
@@ -447,24 +716,61 @@ func checkRedisSchema(logger *logging.Logger, rc *redis.Client, pos string) (new
 		logger.Debug("Checking Icinga 2 and Icinga DB compatibility")
 	}
 
-	streams, err := rc.XReadUntilResult(context.Background(), &redis.XReadArgs{
+	streams, err := rc.XReadUntilResult(ctx, &redis.XReadArgs{
 		Streams: []string{"icinga:schema", pos},
 	})
 	if err != nil {
-		return "", errors.Wrap(err, "can't read Redis schema version")
+		return "", icingaredis.SchemaIncompatible, errors.Wrap(err, "can't read Redis schema version")
 	}
 
 	message := streams[0].Messages[0]
-	if version := message.Values["version"]; version != expectedRedisSchemaVersion {
+	version, err := icingaredis.ParseSchemaVersion(fmt.Sprint(message.Values["version"]))
+	if err != nil {
+		return "", icingaredis.SchemaIncompatible, err
+	}
+
+	switch compatibility = version.Compatibility(); compatibility {
+	case icingaredis.SchemaCompatible:
+		logger.Debugw("Redis schema version is compatible", zap.Uint64("version", uint64(version)))
+	case icingaredis.SchemaHistoryOnly:
+		logger.Warnw("Redis schema version is newer than supported, only syncing the history until Icinga DB is upgraded",
+			zap.Uint64("version", uint64(version)), zap.Uint64("max_version", uint64(icingaredis.MaxSchemaVersion)))
+	default:
 		// Since these error messages are trivial and mostly caused by users, we don't need
 		// to print a stack trace here. However, since errors.Errorf() does this automatically,
 		// we need to use fmt instead.
-		return "", fmt.Errorf(
-			"unexpected Redis schema version: %q (expected %q), please make sure you are running compatible"+
-				" versions of Icinga 2 and Icinga DB", version, expectedRedisSchemaVersion,
+		return "", compatibility, fmt.Errorf(
+			"unexpected Redis schema version: %d (expected %d to %d), please make sure you are running compatible"+
+				" versions of Icinga 2 and Icinga DB", version, icingaredis.MinSchemaVersion, icingaredis.MaxSchemaVersion,
 		)
 	}
 
-	logger.Debug("Redis schema version is correct")
-	return message.ID, nil
+	return message.ID, compatibility, nil
+}
+
+// environmentLogging provides the loggers of an environment,
+// which carry its name as field if multiple environments are configured.
+type environmentLogging struct {
+	*logging.Logging
+
+	name string
+}
+
+// GetChildLogger returns a named child logger of the environment.
+func (l environmentLogging) GetChildLogger(name string) *logging.Logger {
+	return l.withName(l.Logging.GetChildLogger(name))
+}
+
+// GetLogger returns the root logger of the environment.
+func (l environmentLogging) GetLogger() *logging.Logger {
+	return l.withName(l.Logging.GetLogger())
+}
+
+// withName adds the environment name to logger, if any.
+func (l environmentLogging) withName(logger *logging.Logger) *logging.Logger {
+	if l.name == "" {
+		return logger
+	}
+
+	return logging.NewLogger(logger.With(zap.String("environment", l.name)), logger.Interval())
 }
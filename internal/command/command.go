@@ -37,11 +37,8 @@ func New() *Command {
 		os.Exit(0)
 	}
 
-	var cfg icingadbconfig.Config
-	if err := config.Load(&cfg, config.LoadOptions{
-		Flags:      flags,
-		EnvOptions: config.EnvOptions{Prefix: "ICINGADB_"},
-	}); err != nil {
+	cmd, err := Load(flags)
+	if err != nil {
 		if errors.Is(err, config.ErrInvalidArgument) {
 			panic(err)
 		}
@@ -49,10 +46,24 @@ func New() *Command {
 		utils.PrintErrorThenExit(err, 1)
 	}
 
+	return cmd
+}
+
+// Load loads the YAML configuration referenced by the given flags and returns a new Command.
+// Unlike New, Load does not parse the CLI itself, so that auxiliary tools can bring their own flags.
+func Load(flags icingadbconfig.Flags) (*Command, error) {
+	var cfg icingadbconfig.Config
+	if err := config.Load(&cfg, config.LoadOptions{
+		Flags:      flags,
+		EnvOptions: config.EnvOptions{Prefix: "ICINGADB_"},
+	}); err != nil {
+		return nil, err
+	}
+
 	return &Command{
 		Flags:  flags,
 		Config: cfg,
-	}
+	}, nil
 }
 
 // Database creates and returns a new icingadb.DB connection from config.Config.
@@ -67,7 +78,8 @@ func (c Command) Database(l *logging.Logger) (*database.DB, error) {
 	})
 }
 
-// Redis creates and returns a new icingaredis.Client connection from config.Config.
-func (c Command) Redis(l *logging.Logger) (*redis.Client, error) {
-	return redis.NewClientFromConfig(&c.Config.Redis, l)
+// Redis creates and returns a new icingaredis.Client connection to the Redis of env,
+// which is one of config.Config.RedisEnvironments.
+func (c Command) Redis(env *icingadbconfig.EnvironmentConfig, l *logging.Logger) (*redis.Client, error) {
+	return redis.NewClientFromConfig(&env.Redis, l)
 }
@@ -6,9 +6,11 @@ import (
 	"github.com/icinga/icinga-go-library/logging"
 	"github.com/icinga/icinga-go-library/notifications/source"
 	"github.com/icinga/icinga-go-library/redis"
+	"github.com/icinga/icingadb/pkg/icingadb"
 	"github.com/icinga/icingadb/pkg/icingadb/history"
+	"github.com/icinga/icingadb/pkg/icingadb/perfdata"
+	"github.com/icinga/icingadb/pkg/notifications"
 	"github.com/pkg/errors"
-	"github.com/theory/jsonpath"
 	"time"
 )
 
@@ -17,11 +19,19 @@ const DefaultConfigPath = "/etc/icingadb/config.yml"
 
 // Config defines Icinga DB config.
 type Config struct {
-	Database      database.Config `yaml:"database" envPrefix:"DATABASE_"`
-	Redis         redis.Config    `yaml:"redis" envPrefix:"REDIS_"`
-	Logging       logging.Config  `yaml:"logging" envPrefix:"LOGGING_"`
-	Retention     RetentionConfig `yaml:"retention" envPrefix:"RETENTION_"`
-	Notifications source.Config   `yaml:"notifications" envPrefix:"NOTIFICATIONS_"`
+	Database      database.Config     `yaml:"database" envPrefix:"DATABASE_"`
+	Redis         redis.Config        `yaml:"redis" envPrefix:"REDIS_"`
+	Logging       logging.Config      `yaml:"logging" envPrefix:"LOGGING_"`
+	Retention     RetentionConfig     `yaml:"retention" envPrefix:"RETENTION_"`
+	Notifications NotificationsConfig `yaml:"notifications" envPrefix:"NOTIFICATIONS_"`
+	Perfdata      PerfdataConfig      `yaml:"perfdata" envPrefix:"PERFDATA_"`
+	HA            icingadb.HAOptions  `yaml:"ha" envPrefix:"HA_"`
+
+	// Webhooks can only be configured via YAML.
+	Webhooks []notifications.WebhookConfig `yaml:"webhooks"`
+
+	// Environments can only be configured via YAML and replace Redis if set.
+	Environments []EnvironmentConfig `yaml:"environments"`
 }
 
 func (c *Config) SetDefaults() {
@@ -38,8 +48,12 @@ func (c *Config) Validate() error {
 	if err := c.Database.Validate(); err != nil {
 		return errors.Wrap(err, "invalid database configuration")
 	}
-	if err := c.Redis.Validate(); err != nil {
-		return errors.Wrap(err, "invalid redis configuration")
+	if len(c.Environments) == 0 {
+		if err := c.Redis.Validate(); err != nil {
+			return errors.Wrap(err, "invalid redis configuration")
+		}
+	} else if c.Redis.Host != "" {
+		return errors.New("redis and environments are mutually exclusive")
 	}
 	if err := c.Logging.Validate(); err != nil {
 		return errors.Wrap(err, "invalid logging configuration")
@@ -50,19 +64,92 @@ func (c *Config) Validate() error {
 	if err := c.Notifications.Validate(); err != nil {
 		return errors.Wrap(err, "invalid notifications configuration")
 	}
+	if err := c.Perfdata.Validate(); err != nil {
+		return errors.Wrap(err, "invalid perfdata configuration")
+	}
+	if err := c.HA.Validate(); err != nil {
+		return errors.Wrap(err, "invalid ha configuration")
+	}
+
+	webhookNames := make(map[string]struct{}, len(c.Webhooks))
+	for i := range c.Webhooks {
+		if err := c.Webhooks[i].Validate(); err != nil {
+			return errors.Wrapf(err, "invalid configuration of webhook #%d", i+1)
+		}
+
+		if _, ok := webhookNames[c.Webhooks[i].Name]; ok {
+			return errors.Errorf("duplicate webhook name %q", c.Webhooks[i].Name)
+		}
+		webhookNames[c.Webhooks[i].Name] = struct{}{}
+	}
+
+	environmentNames := make(map[string]struct{}, len(c.Environments))
+	for i := range c.Environments {
+		if err := c.Environments[i].Validate(); err != nil {
+			return errors.Wrapf(err, "invalid configuration of environment #%d", i+1)
+		}
+
+		if _, ok := environmentNames[c.Environments[i].Name]; ok {
+			return errors.Errorf("duplicate environment name %q", c.Environments[i].Name)
+		}
+		environmentNames[c.Environments[i].Name] = struct{}{}
+	}
 
 	for _, relation := range c.Notifications.DefaultRelations {
-		// Note: This only validates that the user configured a valid JSONPath, not that the JSONPath makes sense. To do
-		// so, the logic of Client.relationsProvider would have to be copied, which might be an overkill. So, this might
-		// error out during runtime.
-		if _, err := jsonpath.Parse(relation); err != nil {
-			return errors.Wrapf(err, "default relation %q is not a valid JSONPath", relation)
+		if err := notifications.ValidateRelation(relation); err != nil {
+			return errors.Wrap(err, "invalid default relation")
 		}
 	}
 
 	return nil
 }
 
+// RedisEnvironments returns the environments to serve, i.e. Environments or,
+// if not set, a single unnamed environment served by Redis.
+func (c *Config) RedisEnvironments() []EnvironmentConfig {
+	if len(c.Environments) > 0 {
+		return c.Environments
+	}
+
+	return []EnvironmentConfig{{Redis: c.Redis}}
+}
+
+// EnvironmentConfig defines the Redis server of an Icinga 2 environment.
+type EnvironmentConfig struct {
+	// Name is added to all log messages of this environment.
+	Name  string       `yaml:"name"`
+	Redis redis.Config `yaml:"redis"`
+}
+
+// SetDefaults sets the default Redis port, see Config.SetDefaults.
+func (e *EnvironmentConfig) SetDefaults() {
+	if defaults.CanUpdate(e.Redis.Port) {
+		e.Redis.Port = 6380
+	}
+}
+
+// UnmarshalYAML implements yaml.InterfaceUnmarshaler to set the defaults of EnvironmentConfig,
+// as defaults are only set for list items present before parsing the YAML.
+func (e *EnvironmentConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	if err := defaults.Set(e); err != nil {
+		return errors.Wrap(err, "can't set environment defaults")
+	}
+
+	type plain EnvironmentConfig
+
+	return unmarshal((*plain)(e))
+}
+
+// Validate checks constraints in the supplied environment configuration and
+// returns an error if they are violated.
+func (e *EnvironmentConfig) Validate() error {
+	if e.Name == "" {
+		return errors.New("name missing")
+	}
+
+	return errors.Wrap(e.Redis.Validate(), "invalid redis configuration")
+}
+
 // Flags defines CLI flags.
 //
 // Flags implements the [github.com/icinga/icinga-go-library/config.Flags] interface.
@@ -104,11 +191,12 @@ func (f Flags) IsExplicitConfigPath() bool {
 
 // RetentionConfig defines configuration for history retention.
 type RetentionConfig struct {
-	HistoryDays uint16                   `yaml:"history-days" env:"HISTORY_DAYS"`
-	SlaDays     uint16                   `yaml:"sla-days" env:"SLA_DAYS"`
-	Interval    time.Duration            `yaml:"interval" env:"INTERVAL" default:"1h"`
-	Count       uint64                   `yaml:"count" env:"COUNT" default:"5000"`
-	Options     history.RetentionOptions `yaml:"options" env:"OPTIONS"`
+	HistoryDays  uint16                   `yaml:"history-days" env:"HISTORY_DAYS"`
+	SlaDays      uint16                   `yaml:"sla-days" env:"SLA_DAYS"`
+	PerfdataDays uint16                   `yaml:"perfdata-days" env:"PERFDATA_DAYS"`
+	Interval     time.Duration            `yaml:"interval" env:"INTERVAL" default:"1h"`
+	Count        uint64                   `yaml:"count" env:"COUNT" default:"5000"`
+	Options      history.RetentionOptions `yaml:"options" env:"OPTIONS"`
 }
 
 // Validate checks constraints in the supplied retention configuration and
@@ -124,3 +212,36 @@ func (r *RetentionConfig) Validate() error {
 
 	return r.Options.Validate()
 }
+
+// NotificationsConfig defines the configuration of the Icinga Notifications source.
+type NotificationsConfig struct {
+	source.Config         `yaml:",inline"`
+	notifications.Options `yaml:",inline"`
+}
+
+// Validate checks constraints in the supplied notifications configuration and
+// returns an error if they are violated.
+func (n *NotificationsConfig) Validate() error {
+	if err := n.Config.Validate(); err != nil {
+		return err
+	}
+
+	return n.Options.Validate()
+}
+
+// PerfdataConfig defines configuration for persisting performance data.
+type PerfdataConfig struct {
+	Enabled        bool                  `yaml:"enabled" env:"ENABLED"`
+	RollupInterval time.Duration         `yaml:"rollup-interval" env:"ROLLUP_INTERVAL" default:"1h"`
+	Export         perfdata.ExportConfig `yaml:"export" envPrefix:"EXPORT_"`
+}
+
+// Validate checks constraints in the supplied perfdata configuration and
+// returns an error if they are violated.
+func (p *PerfdataConfig) Validate() error {
+	if p.RollupInterval <= 0 {
+		return errors.New("rollup interval must be positive")
+	}
+
+	return errors.Wrap(p.Export.Validate(), "invalid export configuration")
+}
@@ -5,13 +5,18 @@ import (
 	"github.com/icinga/icinga-go-library/config"
 	"github.com/icinga/icinga-go-library/database"
 	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icinga-go-library/notifications/source"
 	"github.com/icinga/icinga-go-library/redis"
 	"github.com/icinga/icinga-go-library/testutils"
+	"github.com/icinga/icingadb/pkg/icingadb"
 	"github.com/icinga/icingadb/pkg/icingadb/history"
+	"github.com/icinga/icingadb/pkg/icingadb/perfdata"
+	"github.com/icinga/icingadb/pkg/notifications"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zapcore"
 	"os"
 	"testing"
+	"time"
 )
 
 // testFlags is a struct that implements the Flags interface.
@@ -182,6 +187,394 @@ retention:
 				},
 			},
 		},
+		{
+			Name: "Perfdata",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+perfdata:
+  enabled: true
+
+retention:
+  perfdata-days: 7
+  options:
+    perfdata_daily: 730
+`,
+				Env: map[string]string{
+					"ICINGADB_PERFDATA_ROLLUP_INTERVAL": "10m",
+				},
+			},
+			Expected: &Config{
+				Database: database.Config{
+					Host:     "192.0.2.1",
+					Database: "icingadb",
+					User:     "icingadb",
+					Password: "icingadb",
+				},
+				Redis: redis.Config{
+					Host: "2001:db8::1",
+				},
+				Retention: RetentionConfig{
+					PerfdataDays: 7,
+					Options: history.RetentionOptions{
+						"perfdata_daily": 730,
+					},
+				},
+				Perfdata: PerfdataConfig{
+					Enabled:        true,
+					RollupInterval: 10 * time.Minute,
+				},
+			},
+		},
+		{
+			Name: "Perfdata export",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+perfdata:
+  export:
+    type: influxdb
+    url: https://influxdb.example.com:8086/write?db=icinga
+    custom-vars: [os, location]
+`,
+				Env: map[string]string{
+					"ICINGADB_PERFDATA_EXPORT_BATCH_SIZE": "100",
+					"ICINGADB_PERFDATA_EXPORT_BUFFER_DIR": "/var/cache/icingadb/perfdata",
+				},
+			},
+			Expected: &Config{
+				Database: database.Config{
+					Host:     "192.0.2.1",
+					Database: "icingadb",
+					User:     "icingadb",
+					Password: "icingadb",
+				},
+				Redis: redis.Config{
+					Host: "2001:db8::1",
+				},
+				Perfdata: PerfdataConfig{
+					Export: perfdata.ExportConfig{
+						Type:       perfdata.ExportInfluxdb,
+						Url:        "https://influxdb.example.com:8086/write?db=icinga",
+						CustomVars: []string{"os", "location"},
+						BatchSize:  100,
+						BufferDir:  "/var/cache/icingadb/perfdata",
+					},
+				},
+			},
+		},
+		{
+			Name: "Perfdata export without address",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+perfdata:
+  export:
+    type: graphite
+`,
+			},
+			Error: testutils.ErrorContains("invalid address"),
+		},
+		{
+			Name: "Notifications filter",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+notifications:
+  url: http://localhost:5680
+  username: icingadb
+  password: insecureinsecure
+  filter:
+    exclude:
+      - host-vars:
+          env: staging
+      - hostgroup: test-*
+`,
+			},
+			Expected: &Config{
+				Database: database.Config{
+					Host:     "192.0.2.1",
+					Database: "icingadb",
+					User:     "icingadb",
+					Password: "icingadb",
+				},
+				Redis: redis.Config{
+					Host: "2001:db8::1",
+				},
+				Notifications: NotificationsConfig{
+					Config: source.Config{
+						Url:      "http://localhost:5680",
+						Username: "icingadb",
+						Password: "insecureinsecure",
+					},
+					Options: notifications.Options{
+						Filter: notifications.Filter{
+							Exclude: []notifications.FilterRule{
+								{HostVars: map[string]string{"env": "staging"}},
+								{Hostgroup: "test-*"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "Notifications filter with empty rule",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+notifications:
+  filter:
+    include:
+      - {}
+`,
+			},
+			Error: testutils.ErrorContains("rule has no criteria"),
+		},
+		{
+			Name: "Notifications options from Env",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig,
+				Env: map[string]string{
+					"ICINGADB_NOTIFICATIONS_UNREACHABLE":                "mute",
+					"ICINGADB_NOTIFICATIONS_CHECK_OUTPUT_SYNC":          "events",
+					"ICINGADB_NOTIFICATIONS_CHECK_OUTPUT_SYNC_INTERVAL": "30s",
+				},
+			},
+			Expected: &Config{
+				Database: database.Config{
+					Host:     "192.0.2.1",
+					Database: "icingadb",
+					User:     "icingadb",
+					Password: "icingadb",
+				},
+				Redis: redis.Config{
+					Host: "2001:db8::1",
+				},
+				Notifications: NotificationsConfig{
+					Options: notifications.Options{
+						Unreachable:             notifications.UnreachableMute,
+						CheckOutputSync:         notifications.CheckOutputSyncEvents,
+						CheckOutputSyncInterval: 30 * time.Second,
+					},
+				},
+			},
+		},
+		{
+			Name: "Notifications with unknown unreachable mode",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+notifications:
+  unreachable: ignore
+`,
+			},
+			Error: testutils.ErrorContains(`invalid unreachable mode "ignore"`),
+		},
+		{
+			Name: "Notifications with unsupported default relation",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+notifications:
+  default_relations:
+    - $.host.vars
+    - $.timeperiod.name
+`,
+			},
+			Error: testutils.ErrorContains(`unsupported relation "timeperiod"`),
+		},
+		{
+			Name: "Notifications with unknown check output sync mode",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+notifications:
+  check_output_sync: push
+`,
+			},
+			Error: testutils.ErrorContains(`invalid check output sync mode "push"`),
+		},
+		{
+			Name: "Webhooks",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+webhooks:
+  - name: alertmanager
+    url: http://alertmanager.example.com:9093/api/v2/alerts
+    format: alertmanager
+    filter:
+      types: [state]
+  - name: chat
+    url: https://chat.example.com/hooks/icinga
+    secret: s3cret
+    filter:
+      services: [http*]
+`,
+			},
+			Expected: &Config{
+				Database: database.Config{
+					Host:     "192.0.2.1",
+					Database: "icingadb",
+					User:     "icingadb",
+					Password: "icingadb",
+				},
+				Redis: redis.Config{
+					Host: "2001:db8::1",
+				},
+				Webhooks: []notifications.WebhookConfig{
+					{
+						Name:   "alertmanager",
+						Url:    "http://alertmanager.example.com:9093/api/v2/alerts",
+						Format: notifications.WebhookFormatAlertmanager,
+						Filter: notifications.WebhookFilter{Types: []string{"state"}},
+					},
+					{
+						Name:   "chat",
+						Url:    "https://chat.example.com/hooks/icinga",
+						Secret: "s3cret",
+						Filter: notifications.WebhookFilter{Services: []string{"http*"}},
+					},
+				},
+			},
+		},
+		{
+			Name: "Webhook with unknown event type",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+webhooks:
+  - name: chat
+    url: https://chat.example.com/hooks/icinga
+    filter:
+      types: [problem]
+`,
+			},
+			Error: testutils.ErrorContains(`unknown type "problem"`),
+		},
+		{
+			Name: "HA",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+ha:
+  priority: 10
+`,
+				Env: map[string]string{
+					"ICINGADB_HA_PREEMPT_DELAY": "5m",
+				},
+			},
+			Expected: &Config{
+				Database: database.Config{
+					Host:     "192.0.2.1",
+					Database: "icingadb",
+					User:     "icingadb",
+					Password: "icingadb",
+				},
+				Redis: redis.Config{
+					Host: "2001:db8::1",
+				},
+				HA: icingadb.HAOptions{
+					Priority:     10,
+					PreemptDelay: 5 * time.Minute,
+				},
+			},
+		},
+		{
+			Name: "HA with negative preempt delay",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+ha:
+  preempt-delay: -1m
+`,
+			},
+			Error: testutils.ErrorContains("preempt-delay must not be negative"),
+		},
+		{
+			Name: "Environments",
+			Data: testutils.ConfigTestData{
+				Yaml: `
+database:
+  host: 192.0.2.1
+  database: icingadb
+  user: icingadb
+  password: icingadb
+
+environments:
+  - name: berlin
+    redis:
+      host: 192.0.2.10
+  - name: nuremberg
+    redis:
+      host: 192.0.2.20
+      port: 6379
+      options:
+        timeout: 1m
+`,
+			},
+			Expected: &Config{
+				Database: database.Config{
+					Host:     "192.0.2.1",
+					Database: "icingadb",
+					User:     "icingadb",
+					Password: "icingadb",
+				},
+				Environments: []EnvironmentConfig{
+					{
+						Name:  "berlin",
+						Redis: redis.Config{Host: "192.0.2.10"},
+					},
+					{
+						Name: "nuremberg",
+						Redis: redis.Config{
+							Host:    "192.0.2.20",
+							Port:    6379,
+							Options: redis.Options{Timeout: time.Minute},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "Environments and Redis",
+			Data: testutils.ConfigTestData{
+				Yaml: yamlConfig + `
+environments:
+  - name: berlin
+    redis:
+      host: 192.0.2.10
+`,
+			},
+			Error: testutils.ErrorContains("redis and environments are mutually exclusive"),
+		},
+		{
+			Name: "Environments with duplicate name",
+			Data: testutils.ConfigTestData{
+				Yaml: `
+database:
+  host: 192.0.2.1
+  database: icingadb
+  user: icingadb
+  password: icingadb
+
+environments:
+  - name: berlin
+    redis:
+      host: 192.0.2.10
+  - name: berlin
+    redis:
+      host: 192.0.2.20
+`,
+			},
+			Error: testutils.ErrorContains(`duplicate environment name "berlin"`),
+		},
+		{
+			Name: "Environment without name",
+			Data: testutils.ConfigTestData{
+				Yaml: `
+database:
+  host: 192.0.2.1
+  database: icingadb
+  user: icingadb
+  password: icingadb
+
+environments:
+  - redis:
+      host: 192.0.2.10
+`,
+			},
+			Error: testutils.ErrorContains("name missing"),
+		},
 		{
 			Name: "Unknown YAML field",
 			Data: testutils.ConfigTestData{
@@ -0,0 +1,258 @@
+// Package archive exports the history of an Icinga environment to and imports it from a portable file format.
+//
+// An archive is a gzip compressed JSON Lines stream. Its first line is the Header, each further line is a Record,
+// i.e. a row of one of the history tables. All records of a table are written in one go and tables referenced by
+// others come first, so that an archive can be imported in a single pass.
+package archive
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icingadb/pkg/contracts"
+	"github.com/icinga/icingadb/pkg/icingadb/v1/history"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Format identifies archives in their Header.
+const Format = "icingadb-history"
+
+// Version is the version of the archive format written by Export.
+// Import rejects archives of other versions.
+const Version = 1
+
+// Header is the first line of an archive.
+type Header struct {
+	Format        string          `json:"format"`
+	Version       int             `json:"version"`
+	EnvironmentId types.Binary    `json:"environment_id"`
+	From          types.UnixMilli `json:"from"`
+	To            types.UnixMilli `json:"to"`
+	Created       types.UnixMilli `json:"created"`
+}
+
+// Validate returns an error if h doesn't describe an archive Import can read.
+func (h *Header) Validate() error {
+	if h.Format != Format {
+		return errors.Errorf("not an Icinga DB history archive, format is %q", h.Format)
+	}
+
+	if h.Version != Version {
+		return errors.Errorf("unsupported archive version %d, expected %d", h.Version, Version)
+	}
+
+	return nil
+}
+
+// Record is a row of a table. Row maps the column names to their values. Binary values are hex encoded.
+type Record struct {
+	Table string         `json:"table"`
+	Row   map[string]any `json:"row"`
+}
+
+// Window is the time range of the history to export.
+// The range includes From, but not To, so that consecutive windows don't overlap.
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// table specifies how to export and import the rows of a history table.
+type table struct {
+	name string
+
+	// where restricts the exported rows to an environment and Window. It may refer to the named arguments
+	// :environment_id, :from and :to.
+	where string
+
+	// newEntity returns a new entity for row. Entity fields are then set from columns of the same name, except for
+	// fields the entity computes column values from. fixup, if not nil, sets such fields afterwards.
+	newEntity func(row map[string]any) (database.Entity, error)
+	fixup     func(entity database.Entity, row map[string]any) error
+}
+
+// historyInWindow selects the history table's rows of the environment and Window.
+const historyInWindow = `environment_id = :environment_id AND event_time >= :from AND event_time < :to`
+
+// tables are all exported tables in the order they are exported and imported.
+// The history table and user_notification_history reference the others, so they come last.
+var tables = []table{
+	{
+		name:      "acknowledgement_history",
+		where:     `id IN (SELECT acknowledgement_history_id FROM history WHERE ` + historyInWindow + `)`,
+		newEntity: newEntity[history.AcknowledgementHistory],
+	},
+	{
+		name:      "comment_history",
+		where:     `comment_id IN (SELECT comment_history_id FROM history WHERE ` + historyInWindow + `)`,
+		newEntity: newEntity[history.CommentHistory],
+	},
+	{
+		name:      "downtime_history",
+		where:     `downtime_id IN (SELECT downtime_history_id FROM history WHERE ` + historyInWindow + `)`,
+		newEntity: newEntity[history.DowntimeHistory],
+	},
+	{
+		name:      "flapping_history",
+		where:     `id IN (SELECT flapping_history_id FROM history WHERE ` + historyInWindow + `)`,
+		newEntity: newEntity[history.FlappingHistory],
+	},
+	{
+		name:      "notification_history",
+		where:     `id IN (SELECT notification_history_id FROM history WHERE ` + historyInWindow + `)`,
+		newEntity: newEntity[history.NotificationHistory],
+	},
+	{
+		name:      "state_history",
+		where:     `id IN (SELECT state_history_id FROM history WHERE ` + historyInWindow + `)`,
+		newEntity: newEntity[history.StateHistory],
+	},
+	{
+		name: "user_notification_history",
+		where: `notification_history_id IN (SELECT notification_history_id FROM history WHERE ` +
+			historyInWindow + `)`,
+		newEntity: newEntity[history.UserNotificationHistory],
+	},
+	{
+		name:      "history",
+		where:     historyInWindow,
+		newEntity: newHistoryEntity,
+		fixup:     fixupHistoryEventTime,
+	},
+	{
+		name:      "sla_history_state",
+		where:     `environment_id = :environment_id AND event_time >= :from AND event_time < :to`,
+		newEntity: newEntity[history.SlaHistoryState],
+	},
+	{
+		name:      "sla_history_downtime",
+		where:     `environment_id = :environment_id AND downtime_start >= :from AND downtime_start < :to`,
+		newEntity: newEntity[history.SlaHistoryDowntime],
+		fixup: func(entity database.Entity, row map[string]any) error {
+			end, err := unixMilli(row["downtime_end"])
+			entity.(*history.SlaHistoryDowntime).EndTime = end
+
+			return err
+		},
+	},
+}
+
+// Tables returns the names of all tables in an archive in the order they are exported.
+func Tables() []string {
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		names = append(names, t.name)
+	}
+
+	return names
+}
+
+// tableByName returns the table specification for name, if any.
+func tableByName(name string) (*table, bool) {
+	for i := range tables {
+		if tables[i].name == name {
+			return &tables[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// newEntity returns a new initialized *T for any row.
+func newEntity[T any, PT interface {
+	*T
+	database.Entity
+}](map[string]any) (database.Entity, error) {
+	var entity PT = new(T)
+	contracts.SafeInit(entity)
+
+	return entity, nil
+}
+
+// newHistoryEntity returns a new entity for a row of the history table based on its event_type.
+func newHistoryEntity(row map[string]any) (database.Entity, error) {
+	switch eventType := row["event_type"]; eventType {
+	case "state_change":
+		return newEntity[history.HistoryState](row)
+	case "ack_set", "ack_clear":
+		return newEntity[history.HistoryAck](row)
+	case "comment_add", "comment_remove":
+		return newEntity[history.HistoryComment](row)
+	case "downtime_start", "downtime_end":
+		return newEntity[history.HistoryDowntime](row)
+	case "flapping_start", "flapping_end":
+		return newEntity[history.HistoryFlapping](row)
+	case "notification":
+		return newEntity[history.HistoryNotification](row)
+	default:
+		return nil, errors.Errorf("unknown event type %v", eventType)
+	}
+}
+
+// fixupHistoryEventTime sets the fields the history entity computes event_time from.
+func fixupHistoryEventTime(entity database.Entity, row map[string]any) error {
+	eventTime, err := unixMilli(row["event_time"])
+	if err != nil {
+		return err
+	}
+
+	switch e := entity.(type) {
+	case *history.HistoryAck:
+		if e.EventType == "ack_set" {
+			e.SetTime = eventTime
+		} else {
+			e.ClearTime = eventTime
+		}
+	case *history.HistoryComment:
+		if e.EventType == "comment_add" {
+			e.EntryTime = eventTime
+		} else {
+			e.RemoveTime = eventTime
+		}
+	case *history.HistoryDowntime:
+		if e.EventType == "downtime_start" {
+			e.StartTime = eventTime
+		} else {
+			e.EndTime = eventTime
+			e.HasBeenCancelled = types.Bool{Bool: false, Valid: true}
+		}
+	case *history.HistoryFlapping:
+		if e.EventType == "flapping_start" {
+			e.StartTime = eventTime
+		} else {
+			e.EndTime = eventTime
+		}
+	}
+
+	return nil
+}
+
+// unixMilli parses a column value of milliseconds since the epoch.
+func unixMilli(v any) (types.UnixMilli, error) {
+	var t types.UnixMilli
+	if v == nil {
+		return t, nil
+	}
+
+	err := t.UnmarshalText([]byte(fmt.Sprint(v)))
+
+	return t, errors.Wrapf(err, "can't parse %v as milliseconds since the epoch", v)
+}
+
+// encodeValue converts a column value as scanned from the database into its archive representation.
+func encodeValue(v any, binary bool) any {
+	switch v := v.(type) {
+	case []byte:
+		if binary {
+			return hex.EncodeToString(v)
+		}
+
+		return string(v)
+	case nil, int64, uint64, float64, float32, string, bool:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
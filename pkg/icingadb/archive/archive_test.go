@@ -0,0 +1,172 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/icinga/icinga-go-library/strcase"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icingadb/pkg/icingadb/v1/history"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var mapper = reflectx.NewMapperFunc("db", strcase.Snake)
+
+// decodeRecord encodes record and decodes it again as Import does.
+func decodeRecord(t *testing.T, record Record) *Record {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, json.NewEncoder(&buf).Encode(record))
+
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+
+	decoded := &Record{}
+	require.NoError(t, dec.Decode(decoded))
+
+	return decoded
+}
+
+func TestRecordToEntity(t *testing.T) {
+	id := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 255}
+	hexId := encodeValue(id, true)
+
+	t.Run("downtime_history", func(t *testing.T) {
+		entity, err := recordToEntity(mapper, decodeRecord(t, Record{Table: "downtime_history", Row: map[string]any{
+			"downtime_id":        hexId,
+			"environment_id":     hexId,
+			"endpoint_id":        nil,
+			"object_type":        "host",
+			"is_flexible":        "y",
+			"has_been_cancelled": "n",
+			"flexible_duration":  uint64(3600000),
+			"entry_time":         int64(1700000000000),
+			"author":             "icingaadmin",
+		}}))
+		require.NoError(t, err)
+
+		dh := entity.(*history.DowntimeHistory)
+		require.Equal(t, types.Binary(id), dh.DowntimeId)
+		require.Nil(t, dh.EndpointId)
+		require.Equal(t, "host", dh.ObjectType)
+		require.Equal(t, types.Bool{Bool: true, Valid: true}, dh.IsFlexible)
+		require.Equal(t, types.Bool{Bool: false, Valid: true}, dh.HasBeenCancelled)
+		require.Equal(t, uint64(3600000), dh.FlexibleDuration)
+		require.Equal(t, time.UnixMilli(1700000000000), dh.EntryTime.Time())
+		require.Equal(t, "icingaadmin", dh.Author)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		for _, eventType := range []string{"downtime_start", "downtime_end"} {
+			entity, err := recordToEntity(mapper, decodeRecord(t, Record{Table: "history", Row: map[string]any{
+				"id":                  hexId,
+				"downtime_history_id": hexId,
+				"event_type":          eventType,
+				"event_time":          int64(1700000000000),
+			}}))
+			require.NoError(t, err)
+
+			hd := entity.(*history.HistoryDowntime)
+			require.Equal(t, types.Binary(id), hd.Id)
+			require.Equal(t, types.Binary(id), hd.DowntimeHistoryId)
+
+			eventTime, err := hd.EventTime.Value()
+			require.NoError(t, err)
+			require.Equal(t, int64(1700000000000), eventTime, eventType)
+		}
+
+		_, err := recordToEntity(mapper, &Record{Table: "history", Row: map[string]any{"event_type": "unknown"}})
+		require.ErrorContains(t, err, "unknown event type")
+	})
+
+	t.Run("sla_history_downtime", func(t *testing.T) {
+		entity, err := recordToEntity(mapper, decodeRecord(t, Record{Table: "sla_history_downtime", Row: map[string]any{
+			"downtime_id":    hexId,
+			"downtime_start": int64(1700000000000),
+			"downtime_end":   "1700000600000",
+		}}))
+		require.NoError(t, err)
+
+		end, err := entity.(*history.SlaHistoryDowntime).DowntimeEnd.Value()
+		require.NoError(t, err)
+		require.Equal(t, int64(1700000600000), end)
+	})
+
+	t.Run("state_history", func(t *testing.T) {
+		entity, err := recordToEntity(mapper, decodeRecord(t, Record{Table: "state_history", Row: map[string]any{
+			"state_type":         "hard",
+			"hard_state":         int64(2),
+			"output":             nil,
+			"max_check_attempts": "3",
+		}}))
+		require.NoError(t, err)
+
+		sh := entity.(*history.StateHistory)
+		require.Equal(t, history.StateType("hard"), sh.StateType)
+		require.Equal(t, uint8(2), sh.HardState)
+		require.False(t, sh.Output.Valid)
+		require.Equal(t, uint32(3), sh.MaxCheckAttempts)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := recordToEntity(mapper, &Record{Table: "host"})
+		require.ErrorContains(t, err, "unknown table")
+
+		_, err = recordToEntity(mapper, &Record{Table: "state_history", Row: map[string]any{"unknown": "x"}})
+		require.ErrorContains(t, err, "unknown column")
+
+		_, err = recordToEntity(mapper, &Record{Table: "state_history", Row: map[string]any{"hard_state": "256"}})
+		require.Error(t, err)
+	})
+}
+
+func TestHeader_Validate(t *testing.T) {
+	require.NoError(t, (&Header{Format: Format, Version: Version}).Validate())
+	require.ErrorContains(t, (&Header{Format: "other", Version: Version}).Validate(), "not an Icinga DB history archive")
+	require.ErrorContains(t, (&Header{Format: Format, Version: Version + 1}).Validate(), "unsupported archive version")
+}
+
+func TestIsBinaryType(t *testing.T) {
+	for _, name := range []string{"BINARY", "VARBINARY", "BLOB", "MEDIUMBLOB", "BYTEA", "bytea"} {
+		require.True(t, isBinaryType(name), name)
+	}
+
+	for _, name := range []string{"CHAR", "VARCHAR", "TEXT", "UNSIGNED BIGINT", "INT8", ""} {
+		require.False(t, isBinaryType(name), name)
+	}
+}
+
+func TestTables(t *testing.T) {
+	seen := map[string]struct{}{}
+	for _, table := range tables {
+		require.NotContains(t, seen, table.name)
+		seen[table.name] = struct{}{}
+
+		found, ok := tableByName(table.name)
+		require.True(t, ok)
+		require.Equal(t, table.name, found.name)
+	}
+
+	// Tables must come after those they reference.
+	index := func(name string) int {
+		for i := range tables {
+			if tables[i].name == name {
+				return i
+			}
+		}
+
+		return -1
+	}
+
+	for _, referenced := range []string{
+		"acknowledgement_history", "comment_history", "downtime_history",
+		"flapping_history", "notification_history", "state_history",
+	} {
+		require.Less(t, index(referenced), index("history"), referenced)
+	}
+
+	require.Less(t, index("notification_history"), index("user_notification_history"))
+}
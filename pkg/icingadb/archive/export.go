@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Export writes the history of the environment envId within window from db to w as an archive
+// and returns the number of rows exported per table.
+func Export(ctx context.Context, db *database.DB, w io.Writer, envId types.Binary, window Window) (map[string]int, error) {
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+
+	header := Header{
+		Format:        Format,
+		Version:       Version,
+		EnvironmentId: envId,
+		From:          types.UnixMilli(window.From),
+		To:            types.UnixMilli(window.To),
+		Created:       types.UnixMilli(time.Now()),
+	}
+	if err := enc.Encode(header); err != nil {
+		return nil, errors.Wrap(err, "can't write archive header")
+	}
+
+	args := map[string]any{
+		"environment_id": envId,
+		"from":           window.From.UnixMilli(),
+		"to":             window.To.UnixMilli(),
+	}
+
+	counts := make(map[string]int, len(tables))
+	for _, t := range tables {
+		count, err := exportTable(ctx, db, enc, t, args)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't export table %q", t.name)
+		}
+
+		counts[t.name] = count
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "can't write archive")
+	}
+
+	return counts, nil
+}
+
+// exportTable writes the rows of t selected by args as records to enc and returns their number.
+func exportTable(ctx context.Context, db *database.DB, enc *json.Encoder, t table, args map[string]any) (int, error) {
+	query, values, err := sqlx.Named(`SELECT * FROM "`+t.name+`" WHERE `+t.where, args)
+	if err != nil {
+		return 0, errors.Wrap(err, "can't bind named arguments")
+	}
+
+	query = db.Rebind(query)
+
+	rows, err := db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return 0, database.CantPerformQuery(err, query)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, errors.Wrap(err, "can't get column types")
+	}
+
+	binary := make([]bool, len(columnTypes))
+	for i, ct := range columnTypes {
+		binary[i] = isBinaryType(ct.DatabaseTypeName())
+	}
+
+	var count int
+	for rows.Next() {
+		values := make([]any, len(columnTypes))
+		dest := make([]any, len(columnTypes))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return count, errors.Wrap(err, "can't scan row")
+		}
+
+		record := Record{Table: t.name, Row: make(map[string]any, len(columnTypes))}
+		for i, ct := range columnTypes {
+			record.Row[ct.Name()] = encodeValue(values[i], binary[i])
+		}
+
+		if err := enc.Encode(record); err != nil {
+			return count, errors.Wrap(err, "can't write record")
+		}
+
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, database.CantPerformQuery(err, query)
+	}
+
+	return count, nil
+}
+
+// isBinaryType returns whether the database type name as reported by the MySQL or PostgreSQL driver
+// is one of binary data.
+func isBinaryType(name string) bool {
+	switch name = strings.ToUpper(name); name {
+	case "BINARY", "VARBINARY", "BYTEA":
+		return true
+	default:
+		return strings.HasSuffix(name, "BLOB")
+	}
+}
@@ -0,0 +1,216 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// Import reads an archive from r and inserts its rows into db. Rows which already exist are left as they are,
+// so importing an archive multiple times has no further effect. Import returns the archive's header and the number
+// of rows imported per table, including the already existing ones.
+func Import(ctx context.Context, db *database.DB, r io.Reader) (*Header, map[string]int, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "can't decompress archive")
+	}
+
+	dec := json.NewDecoder(zr)
+	dec.UseNumber()
+
+	header := &Header{}
+	if err := dec.Decode(header); err != nil {
+		return nil, nil, errors.Wrap(err, "can't read archive header")
+	}
+
+	if err := header.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	counts := map[string]int{}
+	ins := newInserter(ctx, db)
+
+	var current string
+	for line := 2; ; line++ {
+		var record Record
+		if err := dec.Decode(&record); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			_ = ins.flush()
+			return nil, nil, errors.Wrapf(err, "can't read record in line %d", line)
+		}
+
+		// Tables referenced by the current one have been completely written before.
+		if record.Table != current {
+			if err := ins.flush(); err != nil {
+				return nil, nil, errors.Wrapf(err, "can't import table %q", current)
+			}
+
+			current = record.Table
+		}
+
+		entity, err := recordToEntity(db.Mapper, &record)
+		if err == nil {
+			err = ins.insert(entity)
+		}
+		if err != nil {
+			_ = ins.flush()
+			return nil, nil, errors.Wrapf(err, "can't import record in line %d", line)
+		}
+
+		counts[record.Table]++
+	}
+
+	if err := ins.flush(); err != nil {
+		return nil, nil, errors.Wrapf(err, "can't import table %q", current)
+	}
+
+	return header, counts, nil
+}
+
+// recordToEntity returns the entity for record, with its fields set from the record's columns.
+func recordToEntity(mapper *reflectx.Mapper, record *Record) (database.Entity, error) {
+	t, ok := tableByName(record.Table)
+	if !ok {
+		return nil, errors.Errorf("unknown table %q", record.Table)
+	}
+
+	entity, err := t.newEntity(record.Row)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := mapper.FieldMap(reflect.ValueOf(entity))
+	for column, value := range record.Row {
+		field, ok := fields[column]
+		if !ok {
+			return nil, errors.Errorf("unknown column %q of table %q", column, record.Table)
+		}
+
+		if err := setField(field, value); err != nil {
+			return nil, errors.Wrapf(err, "can't set column %q of table %q", column, record.Table)
+		}
+	}
+
+	if t.fixup != nil {
+		if err := t.fixup(entity, record.Row); err != nil {
+			return nil, err
+		}
+	}
+
+	return entity, nil
+}
+
+// setField sets field to the column value v as decoded from an archive.
+// Fields which don't hold column values, but compute them, are left as they are.
+func setField(field reflect.Value, v any) error {
+	if v == nil {
+		return nil
+	}
+
+	text := fmt.Sprint(v)
+
+	switch ptr := field.Addr().Interface().(type) {
+	case *types.Binary:
+		return ptr.UnmarshalText([]byte(text))
+	case sql.Scanner:
+		// Scan as if the database returned the value, e.g. "y" for a types.Bool.
+		return ptr.Scan([]byte(text))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(i)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(f)
+	}
+
+	return nil
+}
+
+// inserter inserts entities of different types concurrently, one stream per type, ignoring already existing rows.
+type inserter struct {
+	ctx     context.Context
+	db      *database.DB
+	g       *errgroup.Group
+	gCtx    context.Context
+	streams map[reflect.Type]chan database.Entity
+}
+
+func newInserter(ctx context.Context, db *database.DB) *inserter {
+	ins := &inserter{ctx: ctx, db: db}
+	ins.reset()
+
+	return ins
+}
+
+// reset prepares ins for the next table.
+func (ins *inserter) reset() {
+	ins.g, ins.gCtx = errgroup.WithContext(ins.ctx)
+	ins.streams = map[reflect.Type]chan database.Entity{}
+}
+
+// insert queues entity for inserting.
+func (ins *inserter) insert(entity database.Entity) error {
+	typ := reflect.TypeOf(entity)
+
+	stream, ok := ins.streams[typ]
+	if !ok {
+		stream = make(chan database.Entity, 1<<10)
+		ins.streams[typ] = stream
+
+		ins.g.Go(func() error {
+			return ins.db.CreateIgnoreStreamed(ins.gCtx, stream)
+		})
+	}
+
+	select {
+	case stream <- entity:
+		return nil
+	case <-ins.gCtx.Done():
+		return ins.g.Wait()
+	}
+}
+
+// flush waits for all queued entities to be inserted.
+func (ins *inserter) flush() error {
+	for _, stream := range ins.streams {
+		close(stream)
+	}
+
+	err := ins.g.Wait()
+	ins.reset()
+
+	return err
+}
@@ -8,13 +8,14 @@ import (
 	"github.com/icinga/icinga-go-library/database"
 	"github.com/icinga/icinga-go-library/retry"
 	"github.com/icinga/icinga-go-library/types"
+	"strings"
 	"time"
 )
 
 // CleanupStmt defines information needed to compose cleanup statements.
 type CleanupStmt struct {
 	Table  string
-	PK     string
+	PK     string // Comma-separated columns of a composite primary key.
 	Column string
 }
 
@@ -24,6 +25,26 @@ type CleanupStmt struct {
 func (stmt *CleanupStmt) CleanupOlderThan(
 	ctx context.Context, db *database.DB, envId types.Binary,
 	count uint64, olderThan time.Time, onSuccess ...database.OnSuccess[struct{}],
+) (uint64, error) {
+	return stmt.cleanup(ctx, db, cleanupWhere{
+		EnvironmentId: envId,
+		Time:          types.UnixMilli(olderThan),
+	}, count, onSuccess...)
+}
+
+// CleanupAll deletes all rows of the given environment from stmt.Table regardless of stmt.Column.
+// Like CleanupOlderThan, it deletes a maximum of count rows per round and returns the total number of rows deleted.
+func (stmt *CleanupStmt) CleanupAll(
+	ctx context.Context, db *database.DB, envId types.Binary, count uint64, onSuccess ...database.OnSuccess[struct{}],
+) (uint64, error) {
+	all := CleanupStmt{Table: stmt.Table, PK: stmt.PK}
+
+	return all.cleanup(ctx, db, cleanupWhere{EnvironmentId: envId}, count, onSuccess...)
+}
+
+// cleanup deletes the rows matching where in rounds of count rows.
+func (stmt *CleanupStmt) cleanup(
+	ctx context.Context, db *database.DB, where cleanupWhere, count uint64, onSuccess ...database.OnSuccess[struct{}],
 ) (uint64, error) {
 	var counter com.Counter
 
@@ -37,10 +58,7 @@ func (stmt *CleanupStmt) CleanupOlderThan(
 		err := retry.WithBackoff(
 			ctx,
 			func(ctx context.Context) error {
-				rs, err := db.NamedExecContext(ctx, q, cleanupWhere{
-					EnvironmentId: envId,
-					Time:          types.UnixMilli(olderThan),
-				})
+				rs, err := db.NamedExecContext(ctx, q, where)
 				if err != nil {
 					return database.CantPerformQuery(err, q)
 				}
@@ -77,16 +95,26 @@ func (stmt *CleanupStmt) CleanupOlderThan(
 }
 
 // build assembles the cleanup statement for the specified database driver with the given limit.
+// Without stmt.Column, the statement deletes all rows of the environment.
 func (stmt *CleanupStmt) build(driverName string, limit uint64) string {
+	where := "environment_id = :environment_id"
+	if stmt.Column != "" {
+		where += fmt.Sprintf(" AND %s < :time", stmt.Column)
+	}
+
 	switch driverName {
 	case database.MySQL:
-		return fmt.Sprintf(`DELETE FROM %[1]s WHERE environment_id = :environment_id AND %[2]s < :time LIMIT %[3]d`,
-			stmt.Table, stmt.Column, limit)
+		return fmt.Sprintf(`DELETE FROM "%[1]s" WHERE %[2]s LIMIT %[3]d`, stmt.Table, where, limit)
 	case database.PostgreSQL:
+		key := stmt.PK
+		if strings.Contains(key, ",") {
+			key = "(" + key + ")"
+		}
+
 		return fmt.Sprintf(`WITH rows AS (
-SELECT %[1]s FROM %[2]s WHERE environment_id = :environment_id AND %[3]s < :time LIMIT %[4]d
+SELECT %[1]s FROM "%[2]s" WHERE %[3]s LIMIT %[4]d
 )
-DELETE FROM %[2]s WHERE %[1]s IN (SELECT %[1]s FROM rows)`, stmt.PK, stmt.Table, stmt.Column, limit)
+DELETE FROM "%[2]s" WHERE %[5]s IN (SELECT %[1]s FROM rows)`, stmt.PK, stmt.Table, where, limit, key)
 	default:
 		panic(fmt.Sprintf("invalid database type %s", driverName))
 	}
@@ -0,0 +1,62 @@
+package icingadb
+
+import (
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCleanupStmt_build(t *testing.T) {
+	subtests := []struct {
+		name   string
+		stmt   CleanupStmt
+		driver string
+		query  string
+	}{
+		{
+			name:   "mysql-older-than",
+			stmt:   CleanupStmt{Table: "state_history", PK: "id", Column: "event_time"},
+			driver: database.MySQL,
+			query:  `DELETE FROM "state_history" WHERE environment_id = :environment_id AND event_time < :time LIMIT 100`,
+		},
+		{
+			name:   "mysql-all",
+			stmt:   CleanupStmt{Table: "user", PK: "id"},
+			driver: database.MySQL,
+			query:  `DELETE FROM "user" WHERE environment_id = :environment_id LIMIT 100`,
+		},
+		{
+			name:   "pgsql-older-than",
+			stmt:   CleanupStmt{Table: "comment_history", PK: "comment_id", Column: "remove_time"},
+			driver: database.PostgreSQL,
+			query: `WITH rows AS (
+SELECT comment_id FROM "comment_history" WHERE environment_id = :environment_id AND remove_time < :time LIMIT 100
+)
+DELETE FROM "comment_history" WHERE comment_id IN (SELECT comment_id FROM rows)`,
+		},
+		{
+			name:   "pgsql-all",
+			stmt:   CleanupStmt{Table: "user", PK: "id"},
+			driver: database.PostgreSQL,
+			query: `WITH rows AS (
+SELECT id FROM "user" WHERE environment_id = :environment_id LIMIT 100
+)
+DELETE FROM "user" WHERE id IN (SELECT id FROM rows)`,
+		},
+		{
+			name:   "pgsql-composite-pk",
+			stmt:   CleanupStmt{Table: "custom_state", PK: "environment_id, id"},
+			driver: database.PostgreSQL,
+			query: `WITH rows AS (
+SELECT environment_id, id FROM "custom_state" WHERE environment_id = :environment_id LIMIT 100
+)
+DELETE FROM "custom_state" WHERE (environment_id, id) IN (SELECT environment_id, id FROM rows)`,
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.query, st.stmt.build(st.driver, 100))
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package icingadb
+
+import (
+	"context"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icinga-go-library/periodic"
+	"github.com/icinga/icinga-go-library/redis"
+	"github.com/icinga/icinga-go-library/utils"
+	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clockSkewInterval defines how often ClockSkew measures the clocks of Redis and the database.
+const clockSkewInterval = 10 * time.Second
+
+// ClockSkew measures the clock skew of Redis, the database and Icinga 2 against the local clock.
+//
+// A positive skew means that the other clock is ahead of the local one.
+type ClockSkew struct {
+	db        *database.DB
+	redis     *redis.Client
+	logger    *logging.Logger
+	threshold time.Duration
+
+	redisMilli    atomic.Int64
+	databaseMilli atomic.Int64
+	icinga2Milli  atomic.Int64
+
+	errMu      sync.Mutex
+	err        error
+	errSinceMs int64
+}
+
+// NewClockSkew returns a new ClockSkew and starts measuring the clocks of Redis and the database periodically.
+// If threshold is positive, Err reports any clock skew exceeding it.
+func NewClockSkew(
+	ctx context.Context, db *database.DB, rc *redis.Client, threshold time.Duration, logger *logging.Logger,
+) *ClockSkew {
+	c := &ClockSkew{
+		db:        db,
+		redis:     rc,
+		logger:    logger,
+		threshold: threshold,
+	}
+
+	periodic.Start(ctx, clockSkewInterval, func(periodic.Tick) {
+		ctx, cancel := context.WithTimeout(ctx, clockSkewInterval)
+		defer cancel()
+
+		c.measure(ctx)
+	}, periodic.Immediate())
+
+	return c
+}
+
+// ObserveIcinga2 records the clock skew of Icinga 2 from a heartbeat sent at sent and received at received.
+func (c *ClockSkew) ObserveIcinga2(sent, received time.Time) {
+	c.icinga2Milli.Store(sent.Sub(received).Milliseconds())
+	c.check()
+}
+
+// Skews returns the last measured clock skews of Redis, the database and Icinga 2.
+func (c *ClockSkew) Skews() (redisSkew, databaseSkew, icinga2Skew time.Duration) {
+	return time.Duration(c.redisMilli.Load()) * time.Millisecond,
+		time.Duration(c.databaseMilli.Load()) * time.Millisecond,
+		time.Duration(c.icinga2Milli.Load()) * time.Millisecond
+}
+
+// Err returns an error if a clock skew exceeds the threshold.
+func (c *ClockSkew) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	return c.err
+}
+
+// ErrSinceMilli returns the time in milliseconds of the last change of Err from OK to error or from error to OK.
+func (c *ClockSkew) ErrSinceMilli() int64 {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	return c.errSinceMs
+}
+
+// measure measures the clock skews of Redis and the database.
+func (c *ClockSkew) measure(ctx context.Context) {
+	before := time.Now()
+	cmd := c.redis.Time(ctx)
+	redisTime, err := cmd.Result()
+	after := time.Now()
+	if err != nil {
+		if !utils.IsContextCanceled(err) {
+			c.logger.Warnw("Can't measure Redis clock skew", zap.Error(redis.WrapCmdErr(cmd)))
+		}
+	} else {
+		c.redisMilli.Store(skew(before, redisTime, after).Milliseconds())
+	}
+
+	var query string
+	switch c.db.DriverName() {
+	case database.MySQL:
+		query = "SELECT CAST(UNIX_TIMESTAMP(NOW(3)) * 1000 AS SIGNED)"
+	case database.PostgreSQL:
+		query = "SELECT CAST(EXTRACT(EPOCH FROM CLOCK_TIMESTAMP()) * 1000 AS BIGINT)"
+	}
+
+	var dbMilli int64
+	before = time.Now()
+	err = c.db.GetContext(ctx, &dbMilli, query)
+	after = time.Now()
+	if err != nil {
+		if !utils.IsContextCanceled(err) {
+			c.logger.Warnw("Can't measure database clock skew", zap.Error(database.CantPerformQuery(err, query)))
+		}
+	} else {
+		c.databaseMilli.Store(skew(before, time.UnixMilli(dbMilli), after).Milliseconds())
+	}
+
+	c.check()
+}
+
+// check updates Err according to the current clock skews and logs changes.
+func (c *ClockSkew) check() {
+	redisSkew, databaseSkew, icinga2Skew := c.Skews()
+	err := exceedingClockSkew(c.threshold, []clockSkew{
+		{"Redis", redisSkew},
+		{"database", databaseSkew},
+		{"Icinga 2", icinga2Skew},
+	})
+
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	switch {
+	case err != nil && c.err == nil:
+		c.logger.Errorw("Clock skew exceeds threshold, refusing to take over HA", zap.Error(err))
+		c.errSinceMs = time.Now().UnixMilli()
+	case err == nil && c.err != nil:
+		c.logger.Info("Clock skew is back within threshold")
+		c.errSinceMs = time.Now().UnixMilli()
+	}
+
+	c.err = err
+}
+
+// skew returns the skew of the remote time measured between the local times before and after.
+// The round trip time is accounted by comparing the remote time with the middle of before and after.
+func skew(before, remote, after time.Time) time.Duration {
+	return remote.Sub(before.Add(after.Sub(before) / 2))
+}
+
+// clockSkew is the skew of a named clock.
+type clockSkew struct {
+	clock string
+	skew  time.Duration
+}
+
+// exceedingClockSkew returns an error naming the largest of the given clock skews if it exceeds threshold.
+// A threshold of zero or less disables the check.
+func exceedingClockSkew(threshold time.Duration, skews []clockSkew) error {
+	if threshold <= 0 {
+		return nil
+	}
+
+	var largest clockSkew
+	for _, cs := range skews {
+		if cs.skew.Abs() > largest.skew.Abs() {
+			largest = cs
+		}
+	}
+
+	if largest.skew.Abs() <= threshold {
+		return nil
+	}
+
+	// Like the Redis schema version errors, this is caused by the environment and doesn't need a stack trace.
+	return fmt.Errorf("clock skew of %s is %s, exceeding %s", largest.clock, largest.skew, threshold)
+}
@@ -0,0 +1,53 @@
+package icingadb
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSkew(t *testing.T) {
+	before := time.Unix(1700000000, 0)
+	after := before.Add(200 * time.Millisecond)
+
+	require.Equal(t, time.Duration(0), skew(before, before.Add(100*time.Millisecond), after))
+	require.Equal(t, 5*time.Second, skew(before, before.Add(5100*time.Millisecond), after))
+	require.Equal(t, -5*time.Second, skew(before, before.Add(-4900*time.Millisecond), after))
+}
+
+func TestExceedingClockSkew(t *testing.T) {
+	subtests := []struct {
+		name      string
+		threshold time.Duration
+		skews     []clockSkew
+		error     string
+	}{
+		{
+			name:      "within-threshold",
+			threshold: 10 * time.Second,
+			skews:     []clockSkew{{"Redis", time.Second}, {"database", -10 * time.Second}},
+		},
+		{
+			name:      "disabled",
+			threshold: 0,
+			skews:     []clockSkew{{"Redis", time.Hour}},
+		},
+		{
+			name:      "largest-exceeding",
+			threshold: 10 * time.Second,
+			skews:     []clockSkew{{"Redis", 11 * time.Second}, {"database", -time.Minute}, {"Icinga 2", 0}},
+			error:     "clock skew of database is -1m0s, exceeding 10s",
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			err := exceedingClockSkew(st.threshold, st.skews)
+			if st.error == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, st.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package icingadb
+
+import (
+	"context"
+	"encoding/hex"
+	stderrors "errors"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/pkg/errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EnvironmentTables returns cleanup statements for all tables of db scoped by a binary environment_id column.
+// Tables with foreign keys come first, so that deleting rows in this order doesn't cascade.
+func EnvironmentTables(ctx context.Context, db *database.DB) ([]CleanupStmt, error) {
+	var schema, envType string
+	switch db.DriverName() {
+	case database.MySQL:
+		schema, envType = "DATABASE()", "c.column_type"
+	case database.PostgreSQL:
+		schema, envType = "CURRENT_SCHEMA()", "c.data_type"
+	default:
+		return nil, errors.Errorf("unsupported database driver %q", db.DriverName())
+	}
+
+	var columns []environmentTableColumn
+
+	query := "SELECT c.table_name AS name, " + envType + " AS env_type, kcu.column_name AS pk, EXISTS (" +
+		"SELECT 1 FROM information_schema.table_constraints fk WHERE fk.table_schema=c.table_schema" +
+		" AND fk.table_name=c.table_name AND fk.constraint_type='FOREIGN KEY') AS referencing" +
+		" FROM information_schema.columns c" +
+		" INNER JOIN information_schema.table_constraints tc ON tc.table_schema=c.table_schema" +
+		" AND tc.table_name=c.table_name AND tc.constraint_type='PRIMARY KEY'" +
+		" INNER JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema=tc.constraint_schema" +
+		" AND kcu.constraint_name=tc.constraint_name AND kcu.table_name=tc.table_name" +
+		" WHERE c.table_schema=" + schema + " AND c.column_name='environment_id'" +
+		" ORDER BY c.table_name, kcu.ordinal_position"
+	if err := db.SelectContext(ctx, &columns, query); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	return environmentTables(columns), nil
+}
+
+// environmentTableColumn is a primary key column of a table with an environment_id column.
+type environmentTableColumn struct {
+	Name        string
+	EnvType     string `db:"env_type"`
+	Pk          string
+	Referencing bool
+}
+
+// environmentTables builds the cleanup statements of EnvironmentTables from the primary key columns of all tables
+// with an environment_id column, ordered by table and key position. Composite primary keys are joined into a single
+// comma-separated CleanupStmt.PK. Tables whose environment_id isn't binary, such as ido_migration_progress, storing
+// the ID hex encoded, aren't scoped by an Icinga DB environment ID and are skipped.
+func environmentTables(columns []environmentTableColumn) []CleanupStmt {
+	var tables []environmentTableColumn
+	for _, c := range columns {
+		if envType := strings.ToLower(c.EnvType); envType != "binary(20)" && envType != "bytea" {
+			continue
+		}
+
+		if n := len(tables); n > 0 && tables[n-1].Name == c.Name {
+			tables[n-1].Pk += ", " + c.Pk
+			continue
+		}
+
+		tables = append(tables, c)
+	}
+
+	stmts := make([]CleanupStmt, 0, len(tables))
+	for _, referencing := range []bool{true, false} {
+		for _, t := range tables {
+			if t.Referencing == referencing {
+				stmts = append(stmts, CleanupStmt{Table: t.Name, PK: t.Pk})
+			}
+		}
+	}
+
+	return stmts
+}
+
+// Environments returns all environments of db ordered by name.
+func Environments(ctx context.Context, db *database.DB) ([]*v1.Environment, error) {
+	var envs []*v1.Environment
+
+	query := db.BuildSelectStmt(&v1.Environment{}, &v1.Environment{}) + ` ORDER BY "name"`
+	if err := db.SelectContext(ctx, &envs, query); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	return envs, nil
+}
+
+// FindEnvironment returns the environment of the given name or hex encoded ID.
+// An ID not known as environment is returned as such, without a name,
+// so that rows of environments which don't exist anymore can be addressed.
+func FindEnvironment(ctx context.Context, db *database.DB, nameOrId string) (*v1.Environment, error) {
+	envs, err := Environments(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, env := range envs {
+		if env.Name.Valid && env.Name.String == nameOrId {
+			return env, nil
+		}
+	}
+
+	id, err := hex.DecodeString(nameOrId)
+	if err != nil || len(id) != 20 {
+		return nil, errors.Errorf("environment %q not found", nameOrId)
+	}
+
+	for _, env := range envs {
+		if slices.Equal(env.Id, id) {
+			return env, nil
+		}
+	}
+
+	env := &v1.Environment{}
+	env.Id = id
+
+	return env, nil
+}
+
+// CountEnvironmentRows returns the number of rows of all given tables by hex encoded environment ID and table name.
+func CountEnvironmentRows(ctx context.Context, db *database.DB, tables []CleanupStmt) (map[string]map[string]uint64, error) {
+	counts := map[string]map[string]uint64{}
+
+	for _, t := range tables {
+		var rows []struct {
+			EnvironmentId types.Binary
+			Count         uint64
+		}
+
+		query := `SELECT environment_id, COUNT(*) AS count FROM "` + t.Table + `" GROUP BY environment_id`
+		if err := db.SelectContext(ctx, &rows, query); err != nil {
+			return nil, database.CantPerformQuery(err, query)
+		}
+
+		for _, row := range rows {
+			env := row.EnvironmentId.String()
+			if counts[env] == nil {
+				counts[env] = map[string]uint64{}
+			}
+
+			counts[env][t.Table] = row.Count
+		}
+	}
+
+	return counts, nil
+}
+
+// RenameEnvironment sets the name of the environment id. Icinga DB keeps the name of existing environments.
+func RenameEnvironment(ctx context.Context, db *database.DB, id types.Binary, name string) error {
+	query := db.Rebind(`UPDATE "environment" SET "name" = ? WHERE "id" = ?`)
+
+	rs, err := db.ExecContext(ctx, query, name, id)
+	if err != nil {
+		return database.CantPerformQuery(err, query)
+	}
+
+	if affected, err := rs.RowsAffected(); err == nil && affected == 0 {
+		return errors.Errorf("environment %s not found", id)
+	}
+
+	return nil
+}
+
+// ErrEnvironmentInUse implies that an Icinga DB instance is still writing to the environment to be purged.
+var ErrEnvironmentInUse = stderrors.New("environment is in use by a running Icinga DB instance")
+
+// PurgeEnvironment deletes all rows of the environment id from tables, as returned by EnvironmentTables,
+// and finally the environment itself. Rows are deleted in rounds of count rows.
+// After each table, onTable is called with the table name and the number of rows deleted.
+// PurgeEnvironment refuses with ErrEnvironmentInUse if an Icinga DB instance of the environment is still alive.
+func PurgeEnvironment(
+	ctx context.Context, db *database.DB, id types.Binary, tables []CleanupStmt,
+	count uint64, onTable func(table string, deleted uint64),
+) error {
+	var alive int
+	query := db.Rebind(`SELECT COUNT(*) FROM "icingadb_instance" WHERE "environment_id" = ? AND "heartbeat" > ?`)
+	if err := db.GetContext(ctx, &alive, query, id, types.UnixMilli(time.Now().Add(-1*peerTimeout))); err != nil {
+		return database.CantPerformQuery(err, query)
+	}
+
+	if alive > 0 {
+		return ErrEnvironmentInUse
+	}
+
+	for _, t := range tables {
+		deleted, err := t.CleanupAll(ctx, db, id, count)
+		if err != nil {
+			return errors.Wrapf(err, "can't purge table %q", t.Table)
+		}
+
+		onTable(t.Table, deleted)
+	}
+
+	query = db.Rebind(`DELETE FROM "environment" WHERE "id" = ?`)
+	if _, err := db.ExecContext(ctx, query, id); err != nil {
+		return database.CantPerformQuery(err, query)
+	}
+
+	return nil
+}
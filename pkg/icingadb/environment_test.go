@@ -0,0 +1,61 @@
+package icingadb
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEnvironmentTables(t *testing.T) {
+	subtests := []struct {
+		name    string
+		columns []environmentTableColumn
+		stmts   []CleanupStmt
+	}{
+		{
+			name:  "empty",
+			stmts: []CleanupStmt{},
+		},
+		{
+			name: "referencing-first",
+			columns: []environmentTableColumn{
+				{Name: "host", EnvType: "binary(20)", Pk: "id"},
+				{Name: "host_customvar", EnvType: "binary(20)", Pk: "id", Referencing: true},
+				{Name: "service", EnvType: "binary(20)", Pk: "id"},
+			},
+			stmts: []CleanupStmt{
+				{Table: "host_customvar", PK: "id"},
+				{Table: "host", PK: "id"},
+				{Table: "service", PK: "id"},
+			},
+		},
+		{
+			name: "composite-pk",
+			columns: []environmentTableColumn{
+				{Name: "custom_state", EnvType: "bytea", Pk: "environment_id"},
+				{Name: "custom_state", EnvType: "bytea", Pk: "id"},
+				{Name: "host", EnvType: "bytea", Pk: "id"},
+			},
+			stmts: []CleanupStmt{
+				{Table: "custom_state", PK: "environment_id, id"},
+				{Table: "host", PK: "id"},
+			},
+		},
+		{
+			name: "non-binary-environment-id",
+			columns: []environmentTableColumn{
+				{Name: "host", EnvType: "BINARY(20)", Pk: "id"},
+				{Name: "ido_migration_progress", EnvType: "varchar(64)", Pk: "environment_id"},
+				{Name: "ido_migration_progress", EnvType: "varchar(64)", Pk: "history_type"},
+				{Name: "ido_migration_progress", EnvType: "varchar(64)", Pk: "from_ts"},
+				{Name: "ido_migration_progress", EnvType: "varchar(64)", Pk: "to_ts"},
+			},
+			stmts: []CleanupStmt{{Table: "host", PK: "id"}},
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.stmts, environmentTables(st.columns))
+		})
+	}
+}
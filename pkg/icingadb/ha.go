@@ -19,6 +19,7 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"golang.org/x/sys/unix"
+	"math"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -32,6 +33,64 @@ import (
 // Because this timeout relies on icingaredis.Timeout, it is icingaredis.Timeout plus a short grace period.
 const peerTimeout = icingaredis.Timeout + 5*time.Second
 
+// HAOptions defines the configurable behavior of HA.
+type HAOptions struct {
+	// Priority of this instance. A healthy instance takes over from a responsible one with a lower priority.
+	Priority uint16 `yaml:"priority" env:"PRIORITY"`
+
+	// PreemptDelay is how long an instance with a higher priority has to be healthy
+	// before the responsible instance hands over to it, so that HA doesn't flap.
+	PreemptDelay time.Duration `yaml:"preempt-delay" env:"PREEMPT_DELAY" default:"1m"`
+
+	// StandbyDuration is how long an instance stays passive after a manual handover, see HA.Standby.
+	// Zero means until HA.Release is called.
+	StandbyDuration time.Duration `yaml:"standby-duration" env:"STANDBY_DURATION" default:"1h"`
+
+	// MaxClockSkew is the maximum clock skew of Redis, the database and Icinga 2 against the local clock
+	// up to which this instance takes over, see ClockSkew. Zero disables the check.
+	MaxClockSkew time.Duration `yaml:"max-clock-skew" env:"MAX_CLOCK_SKEW" default:"10s"`
+}
+
+// Validate checks constraints in the supplied HA options and returns an error if they are violated.
+func (o *HAOptions) Validate() error {
+	if o.PreemptDelay < 0 {
+		return errors.New("preempt-delay must not be negative")
+	}
+
+	if o.StandbyDuration < 0 {
+		return errors.New("standby-duration must not be negative")
+	}
+
+	if o.MaxClockSkew < 0 {
+		return errors.New("max-clock-skew must not be negative")
+	}
+
+	return nil
+}
+
+// preemption tracks the healthy instance with the highest priority above our own,
+// in order to hand over to it once it has been healthy for HAOptions.PreemptDelay.
+type preemption struct {
+	instanceId types.Binary
+	since      time.Time
+}
+
+// observe records the preferred instance at now, nil if there is none,
+// and returns whether it has been healthy for at least delay.
+func (p *preemption) observe(instanceId types.Binary, now time.Time, delay time.Duration) bool {
+	if instanceId == nil {
+		*p = preemption{}
+
+		return false
+	}
+
+	if !bytes.Equal(p.instanceId, instanceId) {
+		*p = preemption{instanceId: instanceId, since: now}
+	}
+
+	return !now.Before(p.since.Add(delay))
+}
+
 type haState struct {
 	responsibleTsMilli int64
 	responsible        bool
@@ -57,12 +116,48 @@ func (ns *NotificationsState) IsValidUnhealthy() bool {
 	return ns.healthy.Valid && !ns.healthy.Bool
 }
 
+// StandbyState is the manual standby of the HA of an environment, see HA.Standby.
+// It's kept outside the HA, so that the standby persists if the HA is recreated while the environment restarts.
+type StandbyState struct {
+	until atomic.Int64 // Unix milliseconds
+}
+
+// Start starts a standby for duration, or until Release is called if duration is zero.
+func (s *StandbyState) Start(duration time.Duration) {
+	until := int64(math.MaxInt64)
+	if duration > 0 {
+		until = time.Now().Add(duration).UnixMilli()
+	}
+
+	s.until.Store(until)
+}
+
+// Release ends the standby started by Start.
+func (s *StandbyState) Release() {
+	s.until.Store(0)
+}
+
+// active returns whether s is in standby at now.
+func (s *StandbyState) active(now time.Time) bool {
+	return now.UnixMilli() < s.until.Load()
+}
+
 // HA provides high availability and indicates whether a Takeover or Handover must be made.
 type HA struct {
 	state         atomic.Pointer[haState]
 	ctx           context.Context
 	cancelCtx     context.CancelFunc
 	instanceId    types.Binary
+	options       HAOptions
+	preemption    preemption
+	preferredWait preemption   // Tracks how long h waits for the preferred instance to take over.
+	unresponsive  types.Binary // The preferred instance which didn't take over, see HA.decide.
+	standbyState  *StandbyState
+	standby       bool
+	degraded      atomic.Bool // See HA.SetDegraded.
+	endpointId    types.Binary
+	lastTakeover  atomic.Pointer[v1.IcingadbHaHistory]
+	clock         *ClockSkew
 	db            *database.DB
 	environmentMu sync.Mutex
 	environment   *v1.Environment
@@ -85,21 +180,28 @@ type HA struct {
 }
 
 // NewHA returns a new HA and starts the controller loop.
-func NewHA(ctx context.Context, db *database.DB, heartbeat *icingaredis.Heartbeat, logger *logging.Logger) *HA {
+// The manual standby is tracked in standby, which may outlive the HA.
+func NewHA(
+	ctx context.Context, db *database.DB, heartbeat *icingaredis.Heartbeat, clock *ClockSkew, standby *StandbyState,
+	options HAOptions, logger *logging.Logger,
+) *HA {
 	ctx, cancelCtx := context.WithCancel(ctx)
 
 	instanceId := uuid.New()
 
 	ha := &HA{
-		ctx:        ctx,
-		cancelCtx:  cancelCtx,
-		instanceId: instanceId[:],
-		db:         db,
-		heartbeat:  heartbeat,
-		logger:     logger,
-		handover:   make(chan string),
-		takeover:   make(chan string),
-		done:       make(chan struct{}),
+		ctx:          ctx,
+		cancelCtx:    cancelCtx,
+		instanceId:   instanceId[:],
+		options:      options,
+		standbyState: standby,
+		clock:        clock,
+		db:           db,
+		heartbeat:    heartbeat,
+		logger:       logger,
+		handover:     make(chan string),
+		takeover:     make(chan string),
+		done:         make(chan struct{}),
 
 		notificationsHeartbeatCh: make(chan bool),
 	}
@@ -144,6 +246,43 @@ func (h *HA) Err() error {
 	return h.err
 }
 
+// Standby makes h hand over if it is responsible and stay passive for HAOptions.StandbyDuration,
+// or until Release is called if that is zero, so that another instance takes over immediately.
+func (h *HA) Standby() {
+	h.standbyState.Start(h.options.StandbyDuration)
+}
+
+// Release ends the standby started by Standby, so that h may take over again.
+func (h *HA) Release() {
+	h.standbyState.Release()
+}
+
+// SetDegraded sets whether h runs in degraded mode, in which Icinga DB only syncs the history,
+// e.g. because of a newer Redis schema version during a rolling upgrade. Like in standby,
+// h hands over if it is responsible and doesn't take over in degraded mode.
+func (h *HA) SetDegraded(degraded bool) {
+	if h.degraded.Swap(degraded) == degraded {
+		return
+	}
+
+	if degraded {
+		h.logger.Warn("Entering degraded mode, only syncing the history")
+	} else {
+		h.logger.Info("Leaving degraded mode")
+	}
+}
+
+// inStandby returns whether h is in standby at now and logs when the standby ends.
+func (h *HA) inStandby(now time.Time) bool {
+	standby := h.standbyState.active(now)
+	if h.standby && !standby {
+		h.logger.Info("Leaving standby")
+	}
+	h.standby = standby
+
+	return standby
+}
+
 // Handover returns a channel with which handovers and their reasons are signaled.
 func (h *HA) Handover() chan string {
 	return h.handover
@@ -200,6 +339,7 @@ func (h *HA) controller() {
 					h.abort(err)
 				}
 				tt := t.Time()
+				h.clock.ObserveIcinga2(tt, now)
 				if tt.After(now.Add(1 * time.Second)) {
 					h.logger.Warnw("Received heartbeat from the future", zap.Time("time", tt))
 				}
@@ -318,6 +458,7 @@ func (h *HA) realize(
 ) error {
 	var (
 		takeover         string
+		handover         string
 		otherResponsible bool
 	)
 
@@ -325,16 +466,16 @@ func (h *HA) realize(
 		panic("can't use context w/o deadline in realize()")
 	}
 
-	routineEventsLogLevel := zap.DebugLevel
-	if infoLogRoutineEvents {
-		routineEventsLogLevel = zap.InfoLevel
-	}
+	h.endpointId = s.EndpointId
 
 	err := retry.WithBackoff(
 		ctx,
 		func(ctx context.Context) error {
 			takeover = ""
+			handover = ""
 			otherResponsible = false
+			standby := h.inStandby(time.Now())
+			degraded := h.degraded.Load()
 			isoLvl := sql.LevelSerializable
 
 			if h.db.DriverName() == database.MySQL {
@@ -363,41 +504,44 @@ func (h *HA) realize(
 			instance := &v1.IcingadbInstance{}
 			errQuery := tx.QueryRowxContext(ctx, query, envId, "y", h.instanceId).StructScan(instance)
 
-			// For future changes, please make sure that every branch and sub-branch within this switch creates at least
-			// one debug log event or returns with an error. This makes it easier to read the logs, since each time this
-			// function is called, it leaves a trace.
+			// A healthy instance with a higher priority than ours is preferred to be responsible.
+			// Instances which refuse to take over mark themselves as in standby, see haObservation.passive.
+			preferredQuery := h.db.Rebind("SELECT id, heartbeat, priority FROM icingadb_instance " +
+				"WHERE environment_id = ? AND id <> ? AND priority > ? AND heartbeat > ? AND standby = ? " +
+				"ORDER BY priority DESC, heartbeat DESC LIMIT 1")
+
+			preferred := &v1.IcingadbInstance{}
+			errPreferred := tx.QueryRowxContext(
+				ctx, preferredQuery, envId, h.instanceId, h.options.Priority,
+				types.UnixMilli(time.Now().Add(-1*peerTimeout)), "n",
+			).StructScan(preferred)
 			switch {
-			case errQuery == nil:
-				fields := []any{
-					zap.String("instance_id", instance.Id.String()),
-					zap.String("environment", envId.String()),
-					zap.Time("heartbeat", instance.Heartbeat.Time()),
-					zap.Duration("heartbeat_age", time.Since(instance.Heartbeat.Time())),
-				}
-
-				if instance.Heartbeat.Time().Before(time.Now().Add(-1 * peerTimeout)) {
-					takeover = "other instance's heartbeat has expired"
-					h.logger.Debugw("Preparing to take over HA as other instance's heartbeat has expired", fields...)
-				} else {
-					otherResponsible = true
-					h.logger.Logw(routineEventsLogLevel, "Another instance is active", fields...)
-				}
+			case errors.Is(errPreferred, sql.ErrNoRows):
+				preferred = nil
+			case errPreferred != nil:
+				return database.CantPerformQuery(errPreferred, preferredQuery)
+			}
 
+			switch {
 			case errors.Is(errQuery, sql.ErrNoRows):
-				fields := []any{
-					zap.String("instance_id", h.instanceId.String()),
-					zap.String("environment", envId.String())}
-				if !h.responsible {
-					takeover = "no other instance is active"
-					h.logger.Debugw("Preparing to take over HA as no instance is active", fields...)
-				} else {
-					h.logger.Logw(routineEventsLogLevel, "Continuing being the active instance", fields...)
-				}
-
-			default:
+				instance = nil
+			case errQuery != nil:
 				return database.CantPerformQuery(errQuery, query)
 			}
 
+			observation := haObservation{
+				now:         time.Now(),
+				environment: envId,
+				responsible: instance,
+				preferred:   preferred,
+				standby:     standby,
+				degraded:    degraded,
+				clockErr:    h.clock.Err(),
+			}
+			takeover, handover, otherResponsible = h.decide(observation, infoLogRoutineEvents)
+
+			redisSkew, databaseSkew, icinga2Skew := h.clock.Skews()
+
 			i := v1.IcingadbInstance{
 				EntityWithoutChecksum: v1.EntityWithoutChecksum{
 					IdMeta: v1.IdMeta{
@@ -408,7 +552,12 @@ func (h *HA) realize(
 					EnvironmentId: envId,
 				},
 				Heartbeat:                         types.UnixMilli(time.UnixMilli(h.heartbeat.LastMessageTime())),
-				Responsible:                       types.Bool{Bool: takeover != "" || h.responsible, Valid: true},
+				Responsible:                       types.Bool{Bool: (takeover != "" || h.responsible) && handover == "", Valid: true},
+				Priority:                          h.options.Priority,
+				Standby:                           types.Bool{Bool: observation.passive(), Valid: true},
+				ClockSkewRedis:                    redisSkew.Milliseconds(),
+				ClockSkewDatabase:                 databaseSkew.Milliseconds(),
+				ClockSkewIcinga2:                  icinga2Skew.Milliseconds(),
 				EndpointId:                        s.EndpointId,
 				Icinga2Version:                    s.Version,
 				Icinga2StartTime:                  s.ProgramStart,
@@ -519,6 +668,8 @@ func (h *HA) realize(
 
 	if takeover != "" {
 		h.signalTakeover(takeover)
+	} else if handover != "" {
+		h.signalHandover(handover)
 	} else if otherResponsible {
 		if state := h.state.Load(); state.responsible {
 			h.logger.Error("Other instance is responsible while this node itself is responsible, dropping responsibility")
@@ -548,6 +699,139 @@ func (h *HA) instanceCount() int64 {
 	return count
 }
 
+// haObservation is what realize observed in the icingadb_instance table and locally in one HA cycle.
+type haObservation struct {
+	now         time.Time
+	environment types.Binary
+	responsible *v1.IcingadbInstance // The other responsible instance, if any.
+	preferred   *v1.IcingadbInstance // The healthy instance with the highest priority above our own, if any.
+	standby     bool
+	degraded    bool
+	clockErr    error
+}
+
+// passive returns whether the instance refuses to take over, so that it must not be preferred by other instances.
+func (o haObservation) passive() bool {
+	return o.standby || o.degraded || o.clockErr != nil
+}
+
+// decide returns whether to take over or hand over with their reasons and whether another instance is responsible.
+//
+// infoLogRoutineEvents indicates if recurring events should be logged at "info" or "debug" level.
+func (h *HA) decide(o haObservation, infoLogRoutineEvents bool) (takeover, handover string, otherResponsible bool) {
+	routineEventsLogLevel := zap.DebugLevel
+	if infoLogRoutineEvents {
+		routineEventsLogLevel = zap.InfoLevel
+	}
+
+	var preferredId types.Binary
+	if o.preferred != nil {
+		preferredId = o.preferred.Id
+	}
+	preempt := h.preemption.observe(preferredId, o.now, h.options.PreemptDelay)
+
+	// The preferred instance may not take over for reasons not reflected in the icingadb_instance table.
+	// Therefore, we only wait for it for a limited time and don't hand over to it again after taking over,
+	// until it is no longer preferred.
+	var waitingForId types.Binary
+	if !h.responsible && o.responsible == nil {
+		waitingForId = preferredId
+	}
+	waitExpired := h.preferredWait.observe(waitingForId, o.now, peerTimeout)
+	if preferredId == nil {
+		h.unresponsive = nil
+	}
+	unresponsive := preferredId != nil && bytes.Equal(preferredId, h.unresponsive)
+
+	// For future changes, please make sure that every branch and sub-branch within this switch creates at least
+	// one debug log event. This makes it easier to read the logs, since each time this
+	// function is called, it leaves a trace.
+	if instance := o.responsible; instance != nil {
+		fields := []any{
+			zap.String("instance_id", instance.Id.String()),
+			zap.String("environment", o.environment.String()),
+			zap.Time("heartbeat", instance.Heartbeat.Time()),
+			zap.Duration("heartbeat_age", o.now.Sub(instance.Heartbeat.Time())),
+		}
+
+		if instance.Heartbeat.Time().Before(o.now.Add(-1 * peerTimeout)) {
+			if o.standby || o.degraded {
+				h.logger.Logw(routineEventsLogLevel,
+					"Not taking over HA from the instance with an expired heartbeat in standby or degraded mode",
+					append(fields, zap.Bool("standby", o.standby), zap.Bool("degraded", o.degraded))...)
+			} else {
+				takeover = "other instance's heartbeat has expired"
+				h.logger.Debugw("Preparing to take over HA as other instance's heartbeat has expired", fields...)
+			}
+		} else {
+			otherResponsible = true
+			h.logger.Logw(routineEventsLogLevel, "Another instance is active", fields...)
+		}
+	} else {
+		fields := []any{
+			zap.String("instance_id", h.instanceId.String()),
+			zap.String("environment", o.environment.String())}
+		if o.preferred != nil {
+			fields = append(fields,
+				zap.String("preferred_instance_id", o.preferred.Id.String()),
+				zap.Uint16("preferred_priority", o.preferred.Priority),
+				zap.Uint16("priority", h.options.Priority))
+		}
+
+		switch {
+		case o.degraded && h.responsible:
+			handover = "degraded mode"
+			h.logger.Infow("Preparing to hand over HA due to degraded mode", fields...)
+		case o.degraded:
+			h.logger.Logw(routineEventsLogLevel, "Staying passive in degraded mode", fields...)
+		case o.standby && h.responsible:
+			handover = "manual handover"
+			h.logger.Infow("Preparing to hand over HA due to standby", fields...)
+		case o.standby:
+			h.logger.Logw(routineEventsLogLevel, "Staying passive in standby", fields...)
+		case !h.responsible && o.preferred != nil && !waitExpired:
+			h.logger.Logw(routineEventsLogLevel,
+				"Waiting for the instance with a higher priority to take over", fields...)
+		case !h.responsible && o.preferred != nil:
+			takeover = "instance with a higher priority didn't take over"
+			h.unresponsive = o.preferred.Id
+			h.logger.Infow("Preparing to take over HA as the instance with a higher priority didn't take over",
+				append(fields, zap.Duration("waited", peerTimeout))...)
+		case !h.responsible:
+			takeover = "no other instance is active"
+			h.logger.Debugw("Preparing to take over HA as no instance is active", fields...)
+		case unresponsive:
+			h.logger.Logw(routineEventsLogLevel,
+				"Continuing being the active instance as the instance with a higher priority didn't take over",
+				fields...)
+		case preempt:
+			handover = "instance with a higher priority is available"
+			h.logger.Infow("Preparing to hand over HA to the instance with a higher priority", fields...)
+		case o.preferred != nil:
+			h.logger.Logw(routineEventsLogLevel,
+				"Continuing being the active instance until the instance with a higher priority is stable",
+				append(fields, zap.Time("preferred_since", h.preemption.since))...)
+		default:
+			h.logger.Logw(routineEventsLogLevel, "Continuing being the active instance", fields...)
+		}
+	}
+
+	if takeover != "" && o.clockErr != nil {
+		// As this repeats with every heartbeat, it is only logged as a warning along with other routine events.
+		level := zap.DebugLevel
+		if infoLogRoutineEvents {
+			level = zap.WarnLevel
+		}
+
+		h.logger.Logw(level, "Refusing to take over HA due to clock skew",
+			zap.String("takeover_reason", takeover), zap.Error(o.clockErr))
+
+		takeover = ""
+	}
+
+	return takeover, handover, otherResponsible
+}
+
 // realizeLostHeartbeat updates "responsible = n" for this HA into the database.
 func (h *HA) realizeLostHeartbeat() {
 	stmt := h.db.Rebind("UPDATE icingadb_instance SET responsible = ?, notifications_healthy = ? WHERE id = ?")
@@ -592,9 +876,63 @@ func (h *HA) removeOldInstances(s *icingaredisv1.IcingaStatus, envId types.Binar
 	}
 }
 
+// RecordSyncDuration records in the icingadb_ha_history table how long the config sync after the last takeover took.
+// Only the first call after each takeover is recorded.
+func (h *HA) RecordSyncDuration(ctx context.Context, d time.Duration) {
+	event := h.lastTakeover.Load()
+	if event == nil {
+		return
+	}
+
+	stmt := h.db.Rebind("UPDATE icingadb_ha_history SET sync_duration = ? WHERE id = ? AND sync_duration IS NULL")
+	if _, err := h.db.ExecContext(ctx, stmt, d.Milliseconds(), event.Id); err != nil && !utils.IsContextCanceled(err) {
+		h.logger.Warnw("Can't record sync duration", zap.Error(database.CantPerformQuery(err, stmt)))
+	}
+}
+
+// recordEvent inserts a takeover or handover with its reason into the icingadb_ha_history table in the background.
+func (h *HA) recordEvent(eventType, reason string) {
+	h.environmentMu.Lock()
+	environment := h.environment
+	h.environmentMu.Unlock()
+
+	if environment == nil {
+		return
+	}
+
+	id := uuid.New()
+	event := &v1.IcingadbHaHistory{
+		EntityWithoutChecksum: v1.EntityWithoutChecksum{IdMeta: v1.IdMeta{Id: id[:]}},
+		EnvironmentMeta:       v1.EnvironmentMeta{EnvironmentId: environment.Id},
+		InstanceId:            h.instanceId,
+		EndpointId:            h.endpointId,
+		EventType:             eventType,
+		Reason:                reason,
+		EventTime:             types.UnixMilli(time.Now()),
+	}
+
+	if eventType == "takeover" {
+		h.lastTakeover.Store(event)
+	}
+
+	go func() {
+		// Don't block the controller loop, but give up in time if the database is unavailable.
+		ctx, cancel := context.WithTimeout(h.ctx, peerTimeout)
+		defer cancel()
+
+		stmt, _ := h.db.BuildInsertStmt(event)
+		if _, err := h.db.NamedExecContext(ctx, stmt, event); err != nil && !utils.IsContextCanceled(err) {
+			h.logger.Warnw("Can't record HA event", zap.String("event_type", eventType),
+				zap.Error(database.CantPerformQuery(err, stmt)))
+		}
+	}()
+}
+
 // signalHandover gives up HA.responsible and notifies the HA.Handover chan.
 func (h *HA) signalHandover(reason string) {
 	if h.responsible {
+		h.recordEvent("handover", reason)
+
 		h.state.Store(&haState{
 			responsibleTsMilli: time.Now().UnixMilli(),
 			responsible:        false,
@@ -613,6 +951,8 @@ func (h *HA) signalHandover(reason string) {
 // signalTakeover claims HA.responsible and notifies the HA.Takeover chan.
 func (h *HA) signalTakeover(reason string) {
 	if !h.responsible {
+		h.recordEvent("takeover", reason)
+
 		h.state.Store(&haState{
 			responsibleTsMilli: time.Now().UnixMilli(),
 			responsible:        true,
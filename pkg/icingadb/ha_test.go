@@ -0,0 +1,222 @@
+package icingadb
+
+import (
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestPreemption_observe(t *testing.T) {
+	a := types.Binary{0xa}
+	b := types.Binary{0xb}
+	start := time.Unix(1700000000, 0)
+	delay := time.Minute
+
+	steps := []struct {
+		name       string
+		instanceId types.Binary
+		at         time.Duration
+		preempt    bool
+	}{
+		{"no-preferred-instance", nil, 0, false},
+		{"preferred-instance-appears", a, time.Second, false},
+		{"preferred-instance-within-delay", a, 30 * time.Second, false},
+		{"preferred-instance-after-delay", a, time.Minute + time.Second, true},
+		{"other-preferred-instance", b, 2 * time.Minute, false},
+		{"preferred-instance-gone", nil, 3 * time.Minute, false},
+		{"preferred-instance-back", b, 3*time.Minute + time.Second, false},
+		{"preferred-instance-back-after-delay", b, 4*time.Minute + time.Second, true},
+	}
+
+	var p preemption
+	for _, st := range steps {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.preempt, p.observe(st.instanceId, start.Add(st.at), delay))
+		})
+	}
+}
+
+func TestHA_Standby(t *testing.T) {
+	subtests := []struct {
+		name     string
+		duration time.Duration
+		after    time.Duration
+		standby  bool
+	}{
+		{"within-duration", time.Hour, 59 * time.Minute, true},
+		{"after-duration", time.Hour, 61 * time.Minute, false},
+		{"until-released", 0, 24 * time.Hour, true},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			h := &HA{
+				options:      HAOptions{StandbyDuration: st.duration},
+				standbyState: &StandbyState{},
+				logger:       logging.NewLogger(zap.NewNop().Sugar(), time.Second),
+			}
+
+			require.False(t, h.inStandby(time.Now()))
+
+			h.Standby()
+			require.Equal(t, st.standby, h.inStandby(time.Now().Add(st.after)))
+
+			h.Release()
+			require.False(t, h.inStandby(time.Now()))
+		})
+	}
+}
+
+func TestHA_Standby_Restart(t *testing.T) {
+	newHA := func(standby *StandbyState) *HA {
+		return &HA{
+			options:      HAOptions{StandbyDuration: time.Hour},
+			standbyState: standby,
+			logger:       logging.NewLogger(zap.NewNop().Sugar(), time.Second),
+		}
+	}
+
+	standby := &StandbyState{}
+	newHA(standby).Standby()
+
+	// The HA of a restarted environment is recreated with the standby of the previous one.
+	h := newHA(standby)
+	require.True(t, h.inStandby(time.Now()), "standby should persist across environment restarts")
+
+	// Signals are handled outside the environments and release the standby even while no HA exists.
+	standby.Release()
+	require.False(t, h.inStandby(time.Now()))
+
+	standby.Start(time.Hour)
+	require.True(t, newHA(standby).inStandby(time.Now()))
+}
+
+// haTestInstance simulates the icingadb_instance row of an HA instance with a fresh heartbeat.
+type haTestInstance struct {
+	ha          *HA
+	responsible bool
+	passive     bool
+	clockErr    error
+}
+
+// realizeHaTestInstances simulates an HA cycle of each instance at now like HA.realize does.
+func realizeHaTestInstances(now time.Time, instances []*haTestInstance) {
+	for _, self := range instances {
+		var responsible, preferred *v1.IcingadbInstance
+		for _, other := range instances {
+			if other == self {
+				continue
+			}
+
+			row := &v1.IcingadbInstance{
+				EntityWithoutChecksum: v1.EntityWithoutChecksum{IdMeta: v1.IdMeta{Id: other.ha.instanceId}},
+				Heartbeat:             types.UnixMilli(now),
+				Priority:              other.ha.options.Priority,
+			}
+
+			if other.responsible {
+				responsible = row
+			}
+
+			if row.Priority > self.ha.options.Priority && !other.passive &&
+				(preferred == nil || row.Priority > preferred.Priority) {
+				preferred = row
+			}
+		}
+
+		o := haObservation{now: now, responsible: responsible, preferred: preferred, clockErr: self.clockErr}
+		takeover, handover, _ := self.ha.decide(o, false)
+
+		self.responsible = (takeover != "" || self.ha.responsible) && handover == ""
+		self.passive = o.passive()
+		self.ha.responsible = self.responsible
+
+		if takeover != "" {
+			for _, other := range instances {
+				if other != self {
+					other.responsible = false
+					other.ha.responsible = false
+				}
+			}
+		}
+	}
+}
+
+func TestHA_decide_ClockSkew(t *testing.T) {
+	newHA := func(id byte, priority uint16) *HA {
+		return &HA{
+			instanceId: types.Binary{id},
+			options:    HAOptions{Priority: priority, PreemptDelay: time.Minute},
+			logger:     logging.NewLogger(zap.NewNop().Sugar(), time.Second),
+		}
+	}
+
+	skewed := &haTestInstance{ha: newHA(0xa, 10), clockErr: errors.New("clock skew")}
+	healthy := &haTestInstance{ha: newHA(0xb, 0)}
+	instances := []*haTestInstance{skewed, healthy}
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		realizeHaTestInstances(start.Add(time.Duration(i)*time.Second), instances)
+	}
+
+	require.True(t, skewed.passive, "skewed instance should mark itself as passive")
+	require.False(t, skewed.responsible, "skewed instance should not take over")
+	require.True(t, healthy.responsible, "healthy instance should not wait for the skewed one")
+
+	skewed.clockErr = nil
+	for i := 0; i < 2; i++ {
+		realizeHaTestInstances(start.Add(time.Minute+time.Duration(i)*time.Second), instances)
+	}
+	require.False(t, skewed.responsible, "instance should not take over before the preempt delay")
+
+	realizeHaTestInstances(start.Add(3*time.Minute), instances)
+	realizeHaTestInstances(start.Add(3*time.Minute+time.Second), instances)
+	require.True(t, skewed.responsible, "instance should take over after the clocks are synchronized again")
+	require.False(t, healthy.responsible)
+}
+
+func TestHA_decide_PreferredWait(t *testing.T) {
+	h := &HA{
+		instanceId: types.Binary{0xb},
+		options:    HAOptions{PreemptDelay: time.Minute},
+		logger:     logging.NewLogger(zap.NewNop().Sugar(), time.Second),
+	}
+
+	start := time.Unix(1700000000, 0)
+	preferred := &v1.IcingadbInstance{
+		EntityWithoutChecksum: v1.EntityWithoutChecksum{IdMeta: v1.IdMeta{Id: types.Binary{0xa}}},
+		Priority:              10,
+	}
+
+	steps := []struct {
+		name      string
+		at        time.Duration
+		preferred *v1.IcingadbInstance
+		takeover  bool
+		handover  bool
+	}{
+		{"waiting", 0, preferred, false, false},
+		{"still-waiting", peerTimeout - time.Second, preferred, false, false},
+		{"wait-expired", peerTimeout, preferred, true, false},
+		{"no-preemption-to-unresponsive", peerTimeout + 2*time.Minute, preferred, false, false},
+		{"preferred-gone", peerTimeout + 3*time.Minute, nil, false, false},
+		{"preferred-back", peerTimeout + 4*time.Minute, preferred, false, false},
+		{"preemption-after-delay", peerTimeout + 5*time.Minute, preferred, false, true},
+	}
+
+	for _, st := range steps {
+		t.Run(st.name, func(t *testing.T) {
+			takeover, handover, _ := h.decide(haObservation{now: start.Add(st.at), preferred: st.preferred}, false)
+			require.Equal(t, st.takeover, takeover != "")
+			require.Equal(t, st.handover, handover != "")
+
+			h.responsible = (takeover != "" || h.responsible) && handover == ""
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package history
+
+import (
+	"github.com/icinga/icingadb/pkg/common"
+)
+
+// streamAdapter converts the values of a history stream entry with fields changed between Redis schema versions
+// into the format the sync pipelines expect. Entries written before an Icinga 2 upgrade may still be pending in Redis
+// after it, so adapters can't rely on the current schema version, but must detect the format from the values.
+type streamAdapter func(values map[string]any)
+
+// streamAdapters maps sync pipeline keys to the adapters applied to each entry of that history stream
+// before it is passed to the pipeline stages.
+var streamAdapters = map[string][]streamAdapter{
+	SyncPipelineState: {adaptStateType},
+}
+
+// adaptStream applies the adapters of the sync pipeline key to values.
+func adaptStream(key string, values map[string]any) {
+	for _, adapt := range streamAdapters[key] {
+		adapt(values)
+	}
+}
+
+// adaptStateType converts the state_type written as integer (0, 1) by Icinga 2 before v2.15
+// into its string representation (soft, hard).
+func adaptStateType(values map[string]any) {
+	switch values["state_type"] {
+	case "0":
+		values["state_type"] = common.SoftState
+	case "1":
+		values["state_type"] = common.HardState
+	}
+}
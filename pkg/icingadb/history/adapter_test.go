@@ -0,0 +1,28 @@
+package history
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAdaptStream(t *testing.T) {
+	subtests := []struct {
+		name   string
+		key    string
+		input  map[string]any
+		output map[string]any
+	}{
+		{"soft-int", SyncPipelineState, map[string]any{"state_type": "0"}, map[string]any{"state_type": "soft"}},
+		{"hard-int", SyncPipelineState, map[string]any{"state_type": "1"}, map[string]any{"state_type": "hard"}},
+		{"soft", SyncPipelineState, map[string]any{"state_type": "soft"}, map[string]any{"state_type": "soft"}},
+		{"missing", SyncPipelineState, map[string]any{}, map[string]any{}},
+		{"other-pipeline", SyncPipelineFlapping, map[string]any{"state_type": "0"}, map[string]any{"state_type": "0"}},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			adaptStream(st.key, st.input)
+			require.Equal(t, st.output, st.input)
+		})
+	}
+}
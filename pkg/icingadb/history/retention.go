@@ -21,6 +21,7 @@ type RetentionType int
 const (
 	RetentionHistory RetentionType = iota
 	RetentionSla
+	RetentionPerfdata
 )
 
 type retentionStatement struct {
@@ -78,6 +79,14 @@ var RetentionStatements = []retentionStatement{{
 		PK:     "id",
 		Column: "event_time",
 	},
+}, {
+	RetentionType: RetentionHistory,
+	Category:      "ha",
+	CleanupStmt: icingadb.CleanupStmt{
+		Table:  "icingadb_ha_history",
+		PK:     "id",
+		Column: "event_time",
+	},
 }, {
 	RetentionType: RetentionSla,
 	Category:      "sla_downtime",
@@ -94,8 +103,39 @@ var RetentionStatements = []retentionStatement{{
 		PK:     "id",
 		Column: "event_time",
 	},
+}, {
+	RetentionType: RetentionPerfdata,
+	Category:      "perfdata",
+	CleanupStmt: icingadb.CleanupStmt{
+		Table:  "perfdata_sample",
+		PK:     "id",
+		Column: "sample_time",
+	},
+}, {
+	RetentionType: RetentionPerfdata,
+	Category:      "perfdata_hourly",
+	CleanupStmt: icingadb.CleanupStmt{
+		Table:  "perfdata_hourly",
+		PK:     "id",
+		Column: "bucket_start",
+	},
+}, {
+	RetentionType: RetentionPerfdata,
+	Category:      "perfdata_daily",
+	CleanupStmt: icingadb.CleanupStmt{
+		Table:  "perfdata_daily",
+		PK:     "id",
+		Column: "bucket_start",
+	},
 }}
 
+// perfdataRollupDays are the minimum default retention periods in days of the performance data rollups.
+// As the rollups are meant for long-term graphs, they are retained for longer than the samples by default.
+var perfdataRollupDays = map[string]uint16{
+	"perfdata_hourly": 90,
+	"perfdata_daily":  730,
+}
+
 // RetentionOptions defines the non-default mapping of history categories with their retention period in days.
 type RetentionOptions map[string]uint16
 
@@ -144,7 +184,7 @@ func (o *RetentionOptions) UnmarshalYAML(unmarshal func(any) error) error {
 func (o *RetentionOptions) Validate() error {
 	allowedCategories := make(map[string]struct{})
 	for _, stmt := range RetentionStatements {
-		if stmt.RetentionType == RetentionHistory {
+		if stmt.RetentionType == RetentionHistory || stmt.RetentionType == RetentionPerfdata {
 			allowedCategories[stmt.Category] = struct{}{}
 		}
 	}
@@ -160,28 +200,52 @@ func (o *RetentionOptions) Validate() error {
 
 // Retention deletes rows from history tables that exceed their configured retention period.
 type Retention struct {
-	db          *database.DB
-	logger      *logging.Logger
-	historyDays uint16
-	slaDays     uint16
-	interval    time.Duration
-	count       uint64
-	options     RetentionOptions
+	db           *database.DB
+	logger       *logging.Logger
+	historyDays  uint16
+	slaDays      uint16
+	perfdataDays uint16
+	interval     time.Duration
+	count        uint64
+	options      RetentionOptions
 }
 
 // NewRetention returns a new Retention.
 func NewRetention(
-	db *database.DB, historyDays, slaDays uint16, interval time.Duration,
+	db *database.DB, historyDays, slaDays, perfdataDays uint16, interval time.Duration,
 	count uint64, options RetentionOptions, logger *logging.Logger,
 ) *Retention {
 	return &Retention{
-		db:          db,
-		logger:      logger,
-		historyDays: historyDays,
-		slaDays:     slaDays,
-		interval:    interval,
-		count:       count,
-		options:     options,
+		db:           db,
+		logger:       logger,
+		historyDays:  historyDays,
+		slaDays:      slaDays,
+		perfdataDays: perfdataDays,
+		interval:     interval,
+		count:        count,
+		options:      options,
+	}
+}
+
+// days returns the retention period in days of the category of stmt, 0 meaning forever.
+func (r *Retention) days(stmt retentionStatement) uint16 {
+	if d, ok := r.options[stmt.Category]; ok && stmt.RetentionType != RetentionSla {
+		return d
+	}
+
+	switch stmt.RetentionType {
+	case RetentionHistory:
+		return r.historyDays
+	case RetentionSla:
+		return r.slaDays
+	case RetentionPerfdata:
+		if days := perfdataRollupDays[stmt.Category]; r.perfdataDays > 0 && r.perfdataDays < days {
+			return days
+		}
+
+		return r.perfdataDays
+	default:
+		return 0
 	}
 }
 
@@ -198,18 +262,7 @@ func (r *Retention) Start(ctx context.Context) error {
 	errs := make(chan error, 1)
 
 	for _, stmt := range RetentionStatements {
-		var days uint16
-		switch stmt.RetentionType {
-		case RetentionHistory:
-			if d, ok := r.options[stmt.Category]; ok {
-				days = d
-			} else {
-				days = r.historyDays
-			}
-		case RetentionSla:
-			days = r.slaDays
-		}
-
+		days := r.days(stmt)
 		if days < 1 {
 			r.logger.Debugf("Skipping history retention for category %s", stmt.Category)
 			continue
@@ -230,7 +283,7 @@ func (r *Retention) Start(ctx context.Context) error {
 
 			deleted, err := stmt.CleanupOlderThan(
 				ctx, r.db, e.Id, r.count, olderThan,
-				database.OnSuccessIncrement[struct{}](&telemetry.Stats.HistoryCleanup),
+				database.OnSuccessIncrement[struct{}](&telemetry.StatsFromContext(ctx).HistoryCleanup),
 			)
 			if err != nil {
 				select {
@@ -0,0 +1,37 @@
+package history
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRetention_days(t *testing.T) {
+	statements := make(map[string]retentionStatement)
+	for _, stmt := range RetentionStatements {
+		statements[stmt.Category] = stmt
+	}
+
+	subtests := []struct {
+		name         string
+		perfdataDays uint16
+		options      RetentionOptions
+		category     string
+		days         uint16
+	}{
+		{"samples", 7, nil, "perfdata", 7},
+		{"hourly-default", 7, nil, "perfdata_hourly", 90},
+		{"daily-default", 7, nil, "perfdata_daily", 730},
+		{"hourly-longer", 365, nil, "perfdata_hourly", 365},
+		{"hourly-forever", 0, nil, "perfdata_hourly", 0},
+		{"hourly-option", 7, RetentionOptions{"perfdata_hourly": 30}, "perfdata_hourly", 30},
+		{"history", 7, RetentionOptions{"state": 14}, "state", 14},
+		{"sla", 7, RetentionOptions{"sla_state": 14}, "sla_state", 365},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			r := NewRetention(nil, 30, 365, st.perfdataDays, 0, 0, st.options, nil)
+			require.Equal(t, st.days, r.days(statements[st.category]))
+		})
+	}
+}
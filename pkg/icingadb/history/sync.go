@@ -39,9 +39,10 @@ func NewSync(db *database.DB, redis *redis.Client, logger *logging.Logger) *Sync
 
 // Sync synchronizes Redis history streams from s.redis to s.db and deletes the original data on success.
 //
-// The optional extraStages parameter allows specifying an additional extra stage for each pipeline, identified by their
-// key. This stage is executed after every other stage, but before the entry gets deleted from Redis.
-func (s Sync) Sync(ctx context.Context, extraStages map[string]StageFunc) error {
+// The optional extraStages parameter allows specifying additional extra stages for each pipeline, identified by their
+// key. These stages are executed in the given order after every other stage, but before the entry gets deleted from
+// Redis.
+func (s Sync) Sync(ctx context.Context, extraStages map[string][]StageFunc) error {
 	g, ctx := errgroup.WithContext(ctx)
 
 	for key, pipeline := range syncPipelines {
@@ -67,16 +68,13 @@ func (s Sync) Sync(ctx context.Context, extraStages map[string]StageFunc) error
 		// forward the entry after it has completed its own sync so that later stages can rely on previous stages being
 		// executed successfully.
 		//
-		// If an extra stage exists for this key, it will be appended to the pipeline. Thus, it is executed after every
-		// other pipeline action, but before deleteFromRedis.
+		// If extra stages exist for this key, they will be appended to the pipeline. Thus, they are executed after
+		// every other pipeline action, but before deleteFromRedis.
 
 		// Shadowed variable to allow appending custom callbacks.
 		pipeline := pipeline
 		if extraStages != nil {
-			extraStage, ok := extraStages[key]
-			if ok {
-				pipeline = append(slices.Clip(pipeline), extraStage)
-			}
+			pipeline = append(slices.Clip(pipeline), extraStages[key]...)
 		}
 
 		ch := make([]chan redis.XMessage, len(pipeline)+1)
@@ -127,6 +125,7 @@ func (s Sync) readFromRedis(ctx context.Context, key string, output chan<- redis
 		for _, stream := range streams {
 			for _, message := range stream.Messages {
 				xra.Streams[1] = message.ID
+				adaptStream(key, message.Values)
 
 				select {
 				case output <- message:
@@ -390,7 +389,7 @@ func countElementStage(ctx context.Context, _ Sync, _ string, in <-chan redis.XM
 				return nil
 			}
 
-			telemetry.Stats.History.Add(1)
+			telemetry.StatsFromContext(ctx).History.Add(1)
 			out <- msg
 
 		case <-ctx.Done():
@@ -234,7 +234,7 @@ func (s Sync) updateOverdue(
 	}
 
 	counter.Add(uint64(len(ids)))
-	telemetry.Stats.Overdue.Add(uint64(len(ids)))
+	telemetry.StatsFromContext(ctx).Overdue.Add(uint64(len(ids)))
 
 	var op func(ctx context.Context, key string, members ...any) *redis.IntCmd
 	if overdue {
@@ -0,0 +1,133 @@
+package perfdata
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// bufferFileSuffix is the suffix of the files of a diskBuffer, each containing one batch.
+const bufferFileSuffix = ".batch"
+
+// diskBuffer stores batches which could not be written to the export target as files in a directory,
+// so that they can be written again later, even after a restart. Each batch is written to a separate file
+// named after a sequence number, which preserves the order of the batches.
+//
+// diskBuffer is not safe for concurrent use.
+type diskBuffer struct {
+	dir     string
+	maxSize int64
+
+	// files are the names of the buffered files from the oldest to the newest one.
+	files []string
+	sizes map[string]int64
+	size  int64
+	seq   int64
+}
+
+// newDiskBuffer returns a new diskBuffer for the given directory, which is created if necessary,
+// and picks up the batches buffered by a previous run.
+func newDiskBuffer(dir string, maxSize int64) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, errors.Wrap(err, "can't create buffer directory")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read buffer directory")
+	}
+
+	b := &diskBuffer{dir: dir, maxSize: maxSize, sizes: make(map[string]int64)}
+
+	// os.ReadDir returns the entries sorted by name, which are zero-padded sequence numbers.
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), bufferFileSuffix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, errors.Wrap(err, "can't stat buffer file")
+		}
+
+		b.files = append(b.files, entry.Name())
+		b.sizes[entry.Name()] = info.Size()
+		b.size += info.Size()
+	}
+
+	if n := len(b.files); n > 0 {
+		// Continue after the newest batch in case the clock went backwards.
+		_, _ = fmt.Sscanf(b.files[n-1], "%d", &b.seq)
+	}
+
+	return b, nil
+}
+
+// len returns the number of buffered batches.
+func (b *diskBuffer) len() int {
+	return len(b.files)
+}
+
+// push buffers a batch. If the buffer exceeds its maximum size afterwards,
+// the oldest batches are dropped and their number is returned.
+func (b *diskBuffer) push(batch []byte) (dropped int, err error) {
+	// Use the current time as sequence number to keep the order across restarts.
+	b.seq = max(b.seq+1, time.Now().UnixNano())
+
+	name := fmt.Sprintf("%020d%s", b.seq, bufferFileSuffix)
+	path := filepath.Join(b.dir, name)
+
+	// Write to a temporary file first not to pick up incomplete batches after a crash.
+	if err := os.WriteFile(path+".tmp", batch, 0640); err != nil { // #nosec G306 -- same permissions as the file export
+		return 0, errors.Wrap(err, "can't write buffer file")
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return 0, errors.Wrap(err, "can't rename buffer file")
+	}
+
+	b.files = append(b.files, name)
+	b.sizes[name] = int64(len(batch))
+	b.size += int64(len(batch))
+
+	for b.maxSize > 0 && b.size > b.maxSize && len(b.files) > 1 {
+		if err := b.remove(b.files[0]); err != nil {
+			return dropped, err
+		}
+
+		dropped++
+	}
+
+	return dropped, nil
+}
+
+// peek returns the name and content of the oldest batch, or an empty name if the buffer is empty.
+func (b *diskBuffer) peek() (string, []byte, error) {
+	if len(b.files) == 0 {
+		return "", nil, nil
+	}
+
+	name := b.files[0]
+	batch, err := os.ReadFile(filepath.Join(b.dir, name)) // #nosec G304 -- the name is from the buffer directory
+	if err != nil {
+		return "", nil, errors.Wrap(err, "can't read buffer file")
+	}
+
+	return name, batch, nil
+}
+
+// remove removes a batch from the buffer.
+func (b *diskBuffer) remove(name string) error {
+	if err := os.Remove(filepath.Join(b.dir, name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "can't remove buffer file")
+	}
+
+	b.files = slices.DeleteFunc(b.files, func(f string) bool { return f == name })
+	b.size -= b.sizes[name]
+	delete(b.sizes, name)
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package perfdata
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiskBuffer(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := newDiskBuffer(dir, 12)
+	require.NoError(t, err)
+	require.Equal(t, 0, b.len())
+
+	for _, batch := range []string{"first", "second"} {
+		dropped, err := b.push([]byte(batch))
+		require.NoError(t, err)
+		require.Equal(t, 0, dropped)
+	}
+
+	// Exceeds the maximum size of 12 bytes, so the oldest batch is dropped.
+	dropped, err := b.push([]byte("third"))
+	require.NoError(t, err)
+	require.Equal(t, 1, dropped)
+
+	// A new buffer picks up the batches of the previous one in order.
+	b, err = newDiskBuffer(dir, 12)
+	require.NoError(t, err)
+	require.Equal(t, 2, b.len())
+
+	for _, expected := range []string{"second", "third"} {
+		name, batch, err := b.peek()
+		require.NoError(t, err)
+		require.Equal(t, expected, string(batch))
+		require.NoError(t, b.remove(name))
+	}
+
+	name, batch, err := b.peek()
+	require.NoError(t, err)
+	require.Empty(t, name)
+	require.Nil(t, batch)
+}
@@ -0,0 +1,218 @@
+package perfdata
+
+import (
+	"bytes"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metric is a single performance data value of a host or service to be exported.
+type metric struct {
+	Value
+
+	Host         string
+	Service      string
+	CheckCommand string
+
+	// Vars are the selected custom variables of the host or service.
+	Vars map[string]string
+
+	Time time.Time
+}
+
+// tags returns the tags of the metric sorted by name, omitting empty values.
+// Custom variables never override the built-in tags.
+// The built-in tags are only included if their name is given.
+func (m metric) tags(host, service, checkCommand, label, unit string) [][2]string {
+	tags := make([][2]string, 0, 5+len(m.Vars))
+	builtin := make(map[string]struct{}, 5)
+
+	for _, tag := range [][2]string{
+		{host, m.Host}, {service, m.Service}, {checkCommand, m.CheckCommand}, {label, m.Label}, {unit, m.Unit},
+	} {
+		if tag[0] != "" {
+			builtin[tag[0]] = struct{}{}
+
+			if tag[1] != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	for name, value := range m.Vars {
+		if _, ok := builtin[name]; !ok && value != "" {
+			tags = append(tags, [2]string{name, value})
+		}
+	}
+
+	slices.SortFunc(tags, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+
+	return tags
+}
+
+// fields returns the numeric fields of the metric, i.e. its value and, if set, its min, max, warn and crit values.
+// Thresholds are only included if they are plain numbers and not ranges.
+func (m metric) fields() [][2]string {
+	fields := [][2]string{{"value", formatFloat(m.Value.Value)}}
+
+	for _, limit := range []struct {
+		name  string
+		value *float64
+	}{{"min", m.Min}, {"max", m.Max}} {
+		if limit.value != nil {
+			fields = append(fields, [2]string{limit.name, formatFloat(*limit.value)})
+		}
+	}
+
+	for _, threshold := range [][2]string{{"warn", m.Warn}, {"crit", m.Crit}} {
+		if f, err := strconv.ParseFloat(threshold[1], 64); err == nil {
+			fields = append(fields, [2]string{threshold[0], formatFloat(f)})
+		}
+	}
+
+	return fields
+}
+
+// encoder appends the wire representation of a metric to a buffer.
+type encoder func(buf *bytes.Buffer, prefix string, m metric)
+
+// encodeInfluxdb encodes a metric in the InfluxDB line protocol.
+// The measurement is the check command, all values are fields of a single point.
+func encodeInfluxdb(buf *bytes.Buffer, _ string, m metric) {
+	buf.WriteString(influxdbEscaper.measurement.Replace(m.CheckCommand))
+
+	for _, tag := range m.tags("hostname", "service", "", "metric", "") {
+		buf.WriteByte(',')
+		buf.WriteString(influxdbEscaper.key.Replace(tag[0]))
+		buf.WriteByte('=')
+		buf.WriteString(influxdbEscaper.key.Replace(tag[1]))
+	}
+
+	for i, field := range m.fields() {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(field[0])
+		buf.WriteByte('=')
+		buf.WriteString(field[1])
+	}
+
+	if m.Unit != "" {
+		buf.WriteString(`,unit="`)
+		buf.WriteString(influxdbEscaper.stringField.Replace(m.Unit))
+		buf.WriteByte('"')
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(m.Time.UnixNano(), 10))
+	buf.WriteByte('\n')
+}
+
+// influxdbEscaper escapes the special characters of the different InfluxDB line protocol elements.
+var influxdbEscaper = struct {
+	measurement, key, stringField *strings.Replacer
+}{
+	measurement: strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`),
+	key:         strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`),
+	stringField: strings.NewReplacer(`"`, `\"`, `\`, `\\`),
+}
+
+// encodeGraphite encodes a metric in the Graphite plaintext protocol with tags.
+// Each value is written as a separate series named <prefix>.perfdata.<label>.<field>.
+func encodeGraphite(buf *bytes.Buffer, prefix string, m metric) {
+	tags := m.tags("host", "service", "check_command", "", "unit")
+
+	for _, field := range m.fields() {
+		buf.WriteString(graphiteName(prefix, "perfdata", m.Label, field[0]))
+
+		for _, tag := range tags {
+			buf.WriteByte(';')
+			buf.WriteString(graphiteTagEscaper.Replace(tag[0]))
+			buf.WriteByte('=')
+			buf.WriteString(graphiteTagEscaper.Replace(tag[1]))
+		}
+
+		buf.WriteByte(' ')
+		buf.WriteString(field[1])
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(m.Time.Unix(), 10))
+		buf.WriteByte('\n')
+	}
+}
+
+// graphiteName joins the non-empty path components with dots after replacing dots and other
+// characters with a special meaning in Graphite paths by underscores.
+func graphiteName(components ...string) string {
+	var name []string
+	for _, c := range components {
+		if c != "" {
+			name = append(name, graphitePathEscaper.Replace(c))
+		}
+	}
+
+	return strings.Join(name, ".")
+}
+
+var (
+	graphitePathEscaper = strings.NewReplacer(".", "_", " ", "_", ";", "_", "\t", "_", "\n", "_", "/", "_", `\`, "_")
+	graphiteTagEscaper  = strings.NewReplacer(" ", "_", ";", "_", "~", "_", "!", "_", "^", "_", "=", "_", "\t", "_", "\n", "_")
+)
+
+// encodeOpentsdb encodes a metric as OpenTSDB telnet style put commands.
+// Each value is written as a separate metric named <prefix>.perfdata.<label>.<field>.
+func encodeOpentsdb(buf *bytes.Buffer, prefix string, m metric) {
+	tags := m.tags("host", "service", "check_command", "", "unit")
+
+	for _, field := range m.fields() {
+		buf.WriteString("put ")
+		buf.WriteString(opentsdbName(prefix, "perfdata", m.Label, field[0]))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(m.Time.UnixMilli(), 10))
+		buf.WriteByte(' ')
+		buf.WriteString(field[1])
+
+		for _, tag := range tags {
+			buf.WriteByte(' ')
+			buf.WriteString(opentsdbEscape(tag[0]))
+			buf.WriteByte('=')
+			buf.WriteString(opentsdbEscape(tag[1]))
+		}
+
+		buf.WriteByte('\n')
+	}
+}
+
+// opentsdbName joins the non-empty metric name components with dots after escaping them.
+// Dots within the components are kept as they are allowed in OpenTSDB metric names.
+func opentsdbName(components ...string) string {
+	var name []string
+	for _, c := range components {
+		if c != "" {
+			name = append(name, opentsdbEscape(c))
+		}
+	}
+
+	return strings.Join(name, ".")
+}
+
+// opentsdbEscape replaces all characters not allowed in OpenTSDB metric names and tags by underscores.
+func opentsdbEscape(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == '/':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// formatFloat formats a float as the shortest decimal representation which is understood by all export formats.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
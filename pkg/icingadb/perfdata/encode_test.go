@@ -0,0 +1,81 @@
+package perfdata
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	ptr := func(f float64) *float64 { return &f }
+	ts := time.UnixMilli(1700000000123)
+
+	hostMetric := metric{
+		Value:        Value{Label: "rta", Value: 0.5, Unit: "ms", Warn: "100", Crit: "@200:300", Min: ptr(0)},
+		Host:         "web 1",
+		CheckCommand: "hostalive",
+		Vars:         map[string]string{"os": "Linux", "hostname": "ignored", "empty": ""},
+		Time:         ts,
+	}
+
+	serviceMetric := metric{
+		Value:        Value{Label: "disk /var", Value: 42},
+		Host:         "db.example.com",
+		Service:      "disk;var",
+		CheckCommand: "disk",
+		Time:         ts,
+	}
+
+	subtests := []struct {
+		name   string
+		encode encoder
+		prefix string
+		input  metric
+		output string
+	}{
+		{
+			name:   "influxdb-host",
+			encode: encodeInfluxdb,
+			input:  hostMetric,
+			output: `hostalive,hostname=web\ 1,metric=rta,os=Linux value=0.5,min=0,warn=100,unit="ms" 1700000000123000000` + "\n",
+		},
+		{
+			name:   "influxdb-service",
+			encode: encodeInfluxdb,
+			input:  serviceMetric,
+			output: `disk,hostname=db.example.com,metric=disk\ /var,service=disk;var value=42 1700000000123000000` + "\n",
+		},
+		{
+			name:   "graphite",
+			encode: encodeGraphite,
+			prefix: "icinga",
+			input:  hostMetric,
+			output: "icinga.perfdata.rta.value;check_command=hostalive;host=web_1;hostname=ignored;os=Linux;unit=ms 0.5 1700000000\n" +
+				"icinga.perfdata.rta.min;check_command=hostalive;host=web_1;hostname=ignored;os=Linux;unit=ms 0 1700000000\n" +
+				"icinga.perfdata.rta.warn;check_command=hostalive;host=web_1;hostname=ignored;os=Linux;unit=ms 100 1700000000\n",
+		},
+		{
+			name:   "graphite-service",
+			encode: encodeGraphite,
+			input:  serviceMetric,
+			output: "perfdata.disk__var.value;check_command=disk;host=db.example.com;service=disk_var 42 1700000000\n",
+		},
+		{
+			name:   "opentsdb",
+			encode: encodeOpentsdb,
+			prefix: "icinga",
+			input:  serviceMetric,
+			output: "put icinga.perfdata.disk_/var.value 1700000000123 42 check_command=disk host=db.example.com service=disk_var\n",
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			st.encode(&buf, st.prefix, st.input)
+
+			require.Equal(t, st.output, buf.String())
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package perfdata
+
+import (
+	"bytes"
+	"context"
+	"github.com/icinga/icinga-go-library/com"
+	"github.com/icinga/icinga-go-library/config"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icinga-go-library/periodic"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Export types supported by [ExportConfig.Type].
+const (
+	ExportInfluxdb     = "influxdb"
+	ExportInfluxdbFile = "influxdb-file"
+	ExportGraphite     = "graphite"
+	ExportOpentsdb     = "opentsdb"
+)
+
+// ExportConfig defines the configuration of an Exporter.
+type ExportConfig struct {
+	// Type is one of the Export* constants or empty if exporting is disabled.
+	Type string `yaml:"type" env:"TYPE"`
+
+	// Url is the InfluxDB write endpoint including its query parameters, e.g. the database or bucket.
+	Url      string `yaml:"url" env:"URL"`
+	Token    string `yaml:"token" env:"TOKEN,unset"`
+	Username string `yaml:"username" env:"USERNAME"`
+	Password string `yaml:"password" env:"PASSWORD,unset"` // #nosec G117 -- exported password field
+
+	// Path is the file to append the InfluxDB line protocol to.
+	Path string `yaml:"path" env:"PATH"`
+
+	// Address is the host:port of the Graphite or OpenTSDB server.
+	Address string `yaml:"address" env:"ADDRESS"`
+
+	TlsOptions config.TLS `yaml:",inline"`
+
+	// Prefix is prepended to the Graphite and OpenTSDB metric names.
+	Prefix string `yaml:"prefix" env:"PREFIX" default:"icinga"`
+
+	// CustomVars are the names of the host and service custom variables to include as tags.
+	CustomVars []string `yaml:"custom-vars" env:"CUSTOM_VARS"`
+
+	BatchSize     int           `yaml:"batch-size" env:"BATCH_SIZE" default:"5000"`
+	FlushInterval time.Duration `yaml:"flush-interval" env:"FLUSH_INTERVAL" default:"10s"`
+
+	// BufferDir is the directory to buffer batches in while the target is unavailable.
+	// If empty, such batches are dropped.
+	BufferDir string `yaml:"buffer-dir" env:"BUFFER_DIR"`
+
+	// BufferSize is the maximum size of the buffer in bytes. The oldest batches are dropped if it is exceeded.
+	BufferSize int64 `yaml:"buffer-size" env:"BUFFER_SIZE" default:"1073741824"`
+}
+
+// Validate checks constraints in the supplied export configuration and returns an error if they are violated.
+func (c *ExportConfig) Validate() error {
+	switch c.Type {
+	case "":
+		return nil
+	case ExportInfluxdb:
+		if c.Url == "" {
+			return errors.New("url missing")
+		}
+		if u, err := url.Parse(c.Url); err != nil {
+			return errors.Wrap(err, "invalid url")
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.Errorf("invalid url scheme %q, must be http or https", u.Scheme)
+		}
+	case ExportInfluxdbFile:
+		if c.Path == "" {
+			return errors.New("path missing")
+		}
+	case ExportGraphite, ExportOpentsdb:
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			return errors.Wrap(err, "invalid address")
+		}
+	default:
+		return errors.Errorf("unknown type %q", c.Type)
+	}
+
+	if c.BatchSize < 1 {
+		return errors.New("batch-size must be at least 1")
+	}
+	if c.FlushInterval <= 0 {
+		return errors.New("flush-interval must be positive")
+	}
+	if c.BufferSize < 0 {
+		return errors.New("buffer-size must not be negative")
+	}
+
+	return nil
+}
+
+// exportItem is a parsed check result queued by Exporter.Submit.
+type exportItem struct {
+	hostId    types.Binary
+	serviceId types.Binary
+	time      time.Time
+	values    []Value
+}
+
+// Exporter forwards the performance data of check results received as state runtime updates to an external
+// time series database. The metrics are written in batches, which are buffered on disk if the target is unavailable.
+type Exporter struct {
+	db     *database.DB
+	logger *logging.Logger
+	config ExportConfig
+
+	encode  encoder
+	queue   chan exportItem
+	dropped com.Counter // Check results dropped by Submit as the queue is full.
+}
+
+// NewExporter returns a new Exporter for the given configuration.
+func NewExporter(db *database.DB, logger *logging.Logger, config ExportConfig) (*Exporter, error) {
+	e := &Exporter{
+		db:     db,
+		logger: logger,
+		config: config,
+		queue:  make(chan exportItem, 1<<10),
+	}
+
+	switch config.Type {
+	case ExportInfluxdb, ExportInfluxdbFile:
+		e.encode = encodeInfluxdb
+	case ExportGraphite:
+		e.encode = encodeGraphite
+	case ExportOpentsdb:
+		e.encode = encodeOpentsdb
+	default:
+		return nil, errors.Errorf("unknown performance data export type %q", config.Type)
+	}
+
+	// Fail early on invalid TLS options.
+	if _, err := e.newSink(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Submit parses the performance data of host and service states and queues it to be exported by Run.
+// All other entities are ignored. If the queue is full, e.g. because the target is unavailable,
+// the performance data is dropped instead of blocking the runtime updates, which Run logs periodically.
+//
+// Submit implements the [github.com/icinga/icingadb/pkg/icingadb.RUUpsertFunc] type.
+func (e *Exporter) Submit(ctx context.Context, entity database.Entity) error {
+	state, hostId, serviceId, perfdata := checkResultOf(entity)
+	if perfdata == "" {
+		return nil
+	}
+
+	values, err := Parse(perfdata)
+	if err != nil {
+		e.logger.Debugw("Ignoring malformed performance data", zap.String("id", entity.ID().String()), zap.Error(err))
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	select {
+	case e.queue <- exportItem{hostId: hostId, serviceId: serviceId, time: state.LastUpdate.Time(), values: values}:
+	default:
+		e.dropped.Inc()
+	}
+
+	return nil
+}
+
+// Run exports the performance data queued by Submit until the context is canceled.
+func (e *Exporter) Run(ctx context.Context) error {
+	s, err := e.newSink()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+
+	var buffer *diskBuffer
+	if e.config.BufferDir != "" {
+		buffer, err = newDiskBuffer(e.config.BufferDir, e.config.BufferSize)
+		if err != nil {
+			return err
+		}
+
+		if n := buffer.len(); n > 0 {
+			e.logger.Infof("Exporting %d buffered batches of performance data", n)
+		}
+	}
+
+	tags := newTagCache(e.db, e.config.CustomVars)
+
+	var counter com.Counter
+	defer periodic.Start(ctx, e.logger.Interval(), func(_ periodic.Tick) {
+		if count := counter.Reset(); count > 0 {
+			e.logger.Infof("Exported %d performance data values", count)
+		}
+		if count := e.dropped.Reset(); count > 0 {
+			e.logger.Warnf("Dropped performance data of %d check results as the export can't keep up", count)
+		}
+	}).Stop()
+
+	var batch bytes.Buffer
+	var batchSize int
+
+	flush := func() {
+		e.flush(ctx, s, buffer, batch.Bytes(), batchSize, &counter)
+		batch.Reset()
+		batchSize = 0
+	}
+
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case item := <-e.queue:
+			t, err := tags.get(ctx, item.hostId, item.serviceId)
+			if err != nil {
+				return errors.Wrap(err, "can't look up performance data tags")
+			}
+
+			if t == nil {
+				// The object has been deleted in the meantime.
+				continue
+			}
+
+			for _, v := range item.values {
+				e.encode(&batch, e.config.Prefix, metric{
+					Value:        v,
+					Host:         t.Host,
+					Service:      t.Service,
+					CheckCommand: t.CheckCommand,
+					Vars:         t.Vars,
+					Time:         item.time,
+				})
+			}
+
+			if batchSize += len(item.values); batchSize >= e.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			tags.purge()
+		case <-ctx.Done():
+			if buffer != nil && batchSize > 0 {
+				// Don't lose the pending batch, e.g. on HA handover.
+				if _, err := buffer.push(batch.Bytes()); err != nil {
+					e.logger.Errorw("Can't buffer performance data", zap.Error(err))
+				}
+			}
+
+			return ctx.Err()
+		}
+	}
+}
+
+// flush writes the buffered batches, oldest first, followed by the given batch.
+// If the target is unavailable, the given batch is buffered if possible or dropped otherwise.
+func (e *Exporter) flush(ctx context.Context, s sink, buffer *diskBuffer, batch []byte, size int, counter *com.Counter) {
+	var err error
+
+	for buffer != nil && buffer.len() > 0 {
+		var name string
+		var buffered []byte
+
+		if name, buffered, err = buffer.peek(); err != nil {
+			e.logger.Errorw("Can't read buffered performance data", zap.Error(err))
+
+			break
+		}
+
+		if err = e.write(ctx, s, buffered); err != nil {
+			break
+		}
+
+		if err := buffer.remove(name); err != nil {
+			e.logger.Errorw("Can't remove buffered performance data", zap.Error(err))
+
+			break
+		}
+	}
+
+	if size == 0 {
+		return
+	}
+
+	if err == nil {
+		if err = e.write(ctx, s, batch); err == nil {
+			counter.Add(uint64(size))
+
+			return
+		}
+	}
+
+	if buffer == nil {
+		e.logger.Warnw("Dropping performance data as it can't be exported", zap.Int("values", size), zap.Error(err))
+
+		return
+	}
+
+	dropped, bufErr := buffer.push(batch)
+	if bufErr != nil {
+		e.logger.Errorw("Can't buffer performance data", zap.Int("values", size), zap.Error(bufErr))
+
+		return
+	}
+
+	e.logger.Warnw("Buffering performance data as it can't be exported",
+		zap.Int("values", size), zap.Int("buffered_batches", buffer.len()), zap.Error(err))
+
+	if dropped > 0 {
+		e.logger.Warnf("Dropped the %d oldest buffered batches of performance data as the buffer is full", dropped)
+	}
+}
+
+// write writes a batch to the sink, limiting the time it may take.
+func (e *Exporter) write(ctx context.Context, s sink, batch []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+	defer cancel()
+
+	return s.write(ctx, batch)
+}
+
+// newSink returns a new sink for the configured export type.
+func (e *Exporter) newSink() (sink, error) {
+	switch e.config.Type {
+	case ExportInfluxdb:
+		u, err := url.Parse(e.config.Url)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't parse InfluxDB url")
+		}
+
+		tlsConfig, err := e.config.TlsOptions.MakeConfig(u.Hostname())
+		if err != nil {
+			return nil, errors.Wrap(err, "can't create TLS config")
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+
+		return &httpSink{
+			client:   &http.Client{Transport: transport, Timeout: sinkTimeout},
+			url:      e.config.Url,
+			token:    e.config.Token,
+			username: e.config.Username,
+			password: e.config.Password,
+		}, nil
+	case ExportInfluxdbFile:
+		return &fileSink{path: e.config.Path}, nil
+	default:
+		host, _, err := net.SplitHostPort(e.config.Address)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't parse address")
+		}
+
+		tlsConfig, err := e.config.TlsOptions.MakeConfig(host)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't create TLS config")
+		}
+
+		return &tcpSink{address: e.config.Address, tlsConfig: tlsConfig}, nil
+	}
+}
@@ -0,0 +1,146 @@
+package perfdata
+
+import (
+	stderrors "errors"
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Value is a single performance data value as defined by the Monitoring Plugins Development Guidelines,
+// i.e. 'label'=value[UOM];[warn];[crit];[min];[max].
+type Value struct {
+	Label string
+	Value float64
+	Unit  string
+
+	// Warn and Crit are the thresholds as ranges, e.g. "10", "10:" or "@10:20", or empty if not set.
+	Warn string
+	Crit string
+
+	// Min and Max are nil if not set.
+	Min *float64
+	Max *float64
+}
+
+// valueWithUnit matches a number followed by an optional unit of measurement.
+var valueWithUnit = regexp.MustCompile(`^([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)(\D*)$`)
+
+// Parse parses a performance data string as written by Icinga 2.
+//
+// Malformed values are skipped and reported as a joined error after parsing the remaining ones, so callers can
+// decide whether to use the valid values anyway. Values which are unknown ("U") are skipped without an error.
+func Parse(perfdata string) ([]Value, error) {
+	var values []Value
+	var errs []error
+
+	for _, raw := range split(perfdata) {
+		v, ok, err := parseValue(raw)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "can't parse performance data value %q", raw))
+			continue
+		}
+
+		if ok {
+			values = append(values, v)
+		}
+	}
+
+	return values, stderrors.Join(errs...)
+}
+
+// split splits a performance data string into its space-separated values, respecting quoted labels.
+func split(perfdata string) []string {
+	var values []string
+
+	for i := 0; i < len(perfdata); {
+		if perfdata[i] == ' ' {
+			i++
+			continue
+		}
+
+		start := i
+		if perfdata[i] == '\'' {
+			// Skip the quoted label, two consecutive quotes are an escaped quote.
+			for i++; i < len(perfdata); i++ {
+				if perfdata[i] == '\'' {
+					if i+1 < len(perfdata) && perfdata[i+1] == '\'' {
+						i++
+						continue
+					}
+
+					break
+				}
+			}
+		}
+
+		if end := strings.IndexByte(perfdata[min(i, len(perfdata)):], ' '); end >= 0 {
+			i += end
+		} else {
+			i = len(perfdata)
+		}
+
+		values = append(values, perfdata[start:i])
+	}
+
+	return values
+}
+
+// parseValue parses a single performance data value.
+// It returns false if the value is unknown and should be skipped.
+func parseValue(raw string) (Value, bool, error) {
+	eq := strings.LastIndexByte(raw, '=')
+	if eq < 0 {
+		return Value{}, false, errors.New("missing '='")
+	}
+
+	label := raw[:eq]
+	if len(label) >= 2 && label[0] == '\'' && label[len(label)-1] == '\'' {
+		label = strings.ReplaceAll(label[1:len(label)-1], "''", "'")
+	}
+	if label == "" {
+		return Value{}, false, errors.New("empty label")
+	}
+
+	fields := strings.Split(raw[eq+1:], ";")
+	if fields[0] == "U" {
+		return Value{}, false, nil
+	}
+
+	match := valueWithUnit.FindStringSubmatch(fields[0])
+	if match == nil {
+		return Value{}, false, errors.New("invalid value")
+	}
+
+	v := Value{Label: label, Unit: match[2]}
+
+	var err error
+	if v.Value, err = strconv.ParseFloat(match[1], 64); err != nil {
+		return Value{}, false, err
+	}
+
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+
+		return ""
+	}
+
+	v.Warn = field(1)
+	v.Crit = field(2)
+
+	for i, limit := range []**float64{&v.Min, &v.Max} {
+		if s := field(3 + i); s != "" && s != "U" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return Value{}, false, err
+			}
+
+			*limit = &f
+		}
+	}
+
+	return v, true, nil
+}
@@ -0,0 +1,72 @@
+package perfdata
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	ptr := func(f float64) *float64 { return &f }
+
+	subtests := []struct {
+		name    string
+		input   string
+		output  []Value
+		wantErr bool
+	}{
+		{name: "empty", input: ""},
+		{
+			name:   "value-only",
+			input:  "load1=0.42",
+			output: []Value{{Label: "load1", Value: 0.42}},
+		},
+		{
+			name:  "full",
+			input: "rta=0.123ms;100;200;0;1000",
+			output: []Value{{
+				Label: "rta", Value: 0.123, Unit: "ms", Warn: "100", Crit: "200", Min: ptr(0), Max: ptr(1000),
+			}},
+		},
+		{
+			name:  "multiple",
+			input: "  time=1s;;;0  size=1024B   pl=0%;80:;@90:100 ",
+			output: []Value{
+				{Label: "time", Value: 1, Unit: "s", Min: ptr(0)},
+				{Label: "size", Value: 1024, Unit: "B"},
+				{Label: "pl", Value: 0, Unit: "%", Warn: "80:", Crit: "@90:100"},
+			},
+		},
+		{
+			name:  "quoted-labels",
+			input: "'disk /var'=5GB 'it''s=fine'=-1.5e3",
+			output: []Value{
+				{Label: "disk /var", Value: 5, Unit: "GB"},
+				{Label: "it's=fine", Value: -1500},
+			},
+		},
+		{
+			name:   "unknown",
+			input:  "a=U b=1c;;;U;U",
+			output: []Value{{Label: "b", Value: 1, Unit: "c"}},
+		},
+		{
+			name:    "malformed",
+			input:   "a=1 garbage b=x c=2 =3",
+			output:  []Value{{Label: "a", Value: 1}, {Label: "c", Value: 2}},
+			wantErr: true,
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			output, err := Parse(st.input)
+			if st.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, st.output, output)
+		})
+	}
+}
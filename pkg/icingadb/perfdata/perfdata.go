@@ -0,0 +1,168 @@
+// Package perfdata persists the performance data of check results and downsamples it into hourly and daily rollups,
+// or exports it to an external time series database.
+package perfdata
+
+import (
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/objectpacker"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icinga-go-library/utils"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"strconv"
+)
+
+// Series identifies the values of a single performance data label of a host or service over time.
+type Series struct {
+	v1.EntityWithoutChecksum `json:",inline"`
+	v1.EnvironmentMeta       `json:",inline"`
+	ObjectType               string       `json:"object_type"`
+	HostId                   types.Binary `json:"host_id"`
+	ServiceId                types.Binary `json:"service_id"`
+	Label                    string       `json:"label"`
+	Unit                     string       `json:"unit"`
+}
+
+// NewSeries returns a new Series with its ID computed from the given environment, object and label.
+func NewSeries(environmentId, hostId, serviceId types.Binary, label, unit string) *Series {
+	s := &Series{
+		EnvironmentMeta: v1.EnvironmentMeta{EnvironmentId: environmentId},
+		ObjectType:      "host",
+		HostId:          hostId,
+		ServiceId:       serviceId,
+		Label:           label,
+		Unit:            unit,
+	}
+
+	if serviceId != nil {
+		s.ObjectType = "service"
+	}
+
+	s.Id = utils.Checksum(objectpacker.MustPackSlice(environmentId, hostId, serviceId, label))
+
+	return s
+}
+
+// TableName implements the [database.TableNamer] interface.
+func (*Series) TableName() string {
+	return "perfdata_series"
+}
+
+// Upsert implements the [database.Upserter] interface.
+// The unit may change, e.g. if the check plugin was replaced.
+func (s *Series) Upsert() any {
+	return struct{ Unit string }{s.Unit}
+}
+
+// Sample is a single value of a Series.
+type Sample struct {
+	v1.EntityWithoutChecksum `json:",inline"`
+	v1.EnvironmentMeta       `json:",inline"`
+	SeriesId                 types.Binary    `json:"series_id"`
+	SampleTime               types.UnixMilli `json:"sample_time"`
+	Value                    float64         `json:"value"`
+	Warn                     types.String    `json:"warn"`
+	Crit                     types.String    `json:"crit"`
+	Min                      types.Float     `json:"min"`
+	Max                      types.Float     `json:"max"`
+}
+
+// NewSample returns a new Sample of the given Series with its ID computed from the series and sample time.
+func NewSample(series *Series, sampleTime types.UnixMilli, v Value) *Sample {
+	s := &Sample{
+		EnvironmentMeta: series.EnvironmentMeta,
+		SeriesId:        series.Id,
+		SampleTime:      sampleTime,
+		Value:           v.Value,
+		Warn:            types.MakeString(v.Warn, types.TransformEmptyStringToNull),
+		Crit:            types.MakeString(v.Crit, types.TransformEmptyStringToNull),
+		Min:             makeFloat(v.Min),
+		Max:             makeFloat(v.Max),
+	}
+
+	s.Id = utils.Checksum(objectpacker.MustPackSlice(series.Id, strconv.FormatInt(sampleTime.Time().UnixMilli(), 10)))
+
+	return s
+}
+
+// TableName implements the [database.TableNamer] interface.
+func (*Sample) TableName() string {
+	return "perfdata_sample"
+}
+
+// Rollup aggregates the samples of a Series within a time bucket.
+type Rollup struct {
+	v1.EntityWithoutChecksum `json:",inline"`
+	v1.EnvironmentMeta       `json:",inline"`
+	SeriesId                 types.Binary    `json:"series_id"`
+	BucketStart              types.UnixMilli `json:"bucket_start"`
+	SampleCount              uint64          `json:"sample_count"`
+	MinValue                 float64         `json:"min_value"`
+	MaxValue                 float64         `json:"max_value"`
+	AvgValue                 float64         `json:"avg_value"`
+}
+
+// HourlyRollup is a Rollup of one hour.
+type HourlyRollup struct {
+	Rollup `json:",inline"`
+}
+
+// TableName implements the [database.TableNamer] interface.
+func (*HourlyRollup) TableName() string {
+	return "perfdata_hourly"
+}
+
+// DailyRollup is a Rollup of one day (UTC).
+type DailyRollup struct {
+	Rollup `json:",inline"`
+}
+
+// TableName implements the [database.TableNamer] interface.
+func (*DailyRollup) TableName() string {
+	return "perfdata_daily"
+}
+
+// checkResultOf returns the state, host and service ID and performance data of host and service states.
+// For all other entities and states without performance data, the returned performance data is empty.
+func checkResultOf(entity database.Entity) (state *v1.State, hostId, serviceId types.Binary, perfdata string) {
+	switch e := entity.(type) {
+	case *v1.HostState:
+		state, hostId = &e.State, e.HostId
+	case *v1.ServiceState:
+		state, hostId, serviceId = &e.State, e.HostId, e.ServiceId
+	default:
+		return nil, nil, nil, ""
+	}
+
+	perfdata = state.NormalizedPerformanceData.String
+	if perfdata == "" {
+		perfdata = state.PerformanceData.String
+	}
+
+	return state, hostId, serviceId, perfdata
+}
+
+// makeFloat converts an optional float64 to types.Float.
+func makeFloat(f *float64) types.Float {
+	if f == nil {
+		return types.Float{}
+	}
+
+	var tf types.Float
+	tf.Float64 = *f
+	tf.Valid = true
+
+	return tf
+}
+
+// Assert interface compliance.
+var (
+	_ database.Entity     = (*Series)(nil)
+	_ database.Upserter   = (*Series)(nil)
+	_ database.TableNamer = (*Series)(nil)
+	_ database.Entity     = (*Sample)(nil)
+	_ database.TableNamer = (*Sample)(nil)
+	_ database.Entity     = (*HourlyRollup)(nil)
+	_ database.TableNamer = (*HourlyRollup)(nil)
+	_ database.Entity     = (*DailyRollup)(nil)
+	_ database.TableNamer = (*DailyRollup)(nil)
+)
@@ -0,0 +1,151 @@
+package perfdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/objectpacker"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icinga-go-library/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"strconv"
+	"time"
+)
+
+// rollupLevel describes how to downsample one table into the next coarser one.
+type rollupLevel struct {
+	// bucket is the size of the time buckets to aggregate.
+	bucket time.Duration
+
+	// source and sourceTime are the table to aggregate and its time column.
+	source     string
+	sourceTime string
+
+	// aggregates are the SQL expressions for the sample_count, min_value, max_value and avg_value columns.
+	aggregates string
+
+	// target creates an empty entity of the target table.
+	target func() rollupEntity
+}
+
+// rollupEntity is implemented by HourlyRollup and DailyRollup.
+type rollupEntity interface {
+	database.Entity
+	database.TableNamer
+	rollup() *Rollup
+}
+
+func (r *HourlyRollup) rollup() *Rollup { return &r.Rollup }
+func (r *DailyRollup) rollup() *Rollup  { return &r.Rollup }
+
+// rollups lists the rollup levels from the finest to the coarsest,
+// as each level is computed from the previous one.
+var rollups = []rollupLevel{{
+	bucket:     time.Hour,
+	source:     "perfdata_sample",
+	sourceTime: "sample_time",
+	aggregates: "COUNT(*), MIN(value), MAX(value), AVG(value)",
+	target:     func() rollupEntity { return &HourlyRollup{} },
+}, {
+	bucket:     24 * time.Hour,
+	source:     "perfdata_hourly",
+	sourceTime: "bucket_start",
+	aggregates: "SUM(sample_count), MIN(min_value), MAX(max_value), SUM(avg_value * sample_count) / SUM(sample_count)",
+	target:     func() rollupEntity { return &DailyRollup{} },
+}}
+
+// rollup computes the rollups of the given level for all complete buckets since the latest existing rollup.
+// The latest existing rollup is computed again as it may have been incomplete.
+func (w *Writer) rollup(ctx context.Context, envId types.Binary, level rollupLevel, now time.Time) error {
+	table := level.target().TableName()
+	bucket := level.bucket.Milliseconds()
+	end := now.UnixMilli() / bucket * bucket
+
+	// #nosec G201 -- table and column names are constants
+	queries := []string{
+		fmt.Sprintf(`SELECT MAX(bucket_start) FROM %s WHERE environment_id = ?`, table),
+		fmt.Sprintf(`SELECT MIN(%s) FROM %s WHERE environment_id = ?`, level.sourceTime, level.source),
+	}
+
+	var start sql.NullInt64
+	for _, query := range queries {
+		query = w.db.Rebind(query)
+		if err := w.db.GetContext(ctx, &start, query, envId); err != nil {
+			return database.CantPerformQuery(err, query)
+		}
+
+		if start.Valid {
+			break
+		}
+	}
+
+	if !start.Valid {
+		// Nothing to roll up yet.
+		return nil
+	}
+
+	if start.Int64 >= end {
+		return nil
+	}
+
+	bucketExpr := fmt.Sprintf("%[1]s - %[1]s %% %[2]d", level.sourceTime, bucket)
+	query := w.db.Rebind(fmt.Sprintf(
+		`SELECT series_id, %[1]s, %[2]s FROM %[3]s WHERE environment_id = ? AND %[4]s >= ? AND %[4]s < ? GROUP BY series_id, %[1]s`,
+		bucketExpr, level.aggregates, level.source, level.sourceTime,
+	)) // #nosec G201 -- all parts are constants
+
+	rows, err := w.db.QueryContext(ctx, query, envId, start.Int64/bucket*bucket, end)
+	if err != nil {
+		return database.CantPerformQuery(err, query)
+	}
+	defer func() { _ = rows.Close() }()
+
+	g, ctx := errgroup.WithContext(ctx)
+	entities := make(chan database.Entity)
+
+	g.Go(func() error {
+		defer close(entities)
+
+		for rows.Next() {
+			e := level.target()
+			r := e.rollup()
+
+			var bucketStart int64
+			if err := rows.Scan(&r.SeriesId, &bucketStart, &r.SampleCount, &r.MinValue, &r.MaxValue, &r.AvgValue); err != nil {
+				return errors.Wrap(err, "can't scan rollup")
+			}
+
+			r.EnvironmentId = envId
+			r.BucketStart = types.UnixMilli(time.UnixMilli(bucketStart))
+			r.Id = utils.Checksum(objectpacker.MustPackSlice(r.SeriesId, strconv.FormatInt(bucketStart, 10)))
+
+			select {
+			case entities <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		return errors.Wrap(rows.Err(), "can't read rollups")
+	})
+
+	g.Go(func() error {
+		// Not using UpsertStreamed as there may be no rows at all.
+		stmt, placeholders := w.db.BuildUpsertStmt(level.target())
+
+		return w.db.NamedBulkExec(
+			ctx, stmt, w.db.BatchSizeByPlaceholders(placeholders), w.db.GetSemaphoreForTable(table),
+			entities, database.SplitOnDupId[database.Entity],
+		)
+	})
+
+	if err := g.Wait(); err != nil {
+		return errors.Wrapf(err, "can't compute rollups for %s", table)
+	}
+
+	w.logger.Debugf("Computed %s rollups from %s to %s", table, time.UnixMilli(start.Int64), time.UnixMilli(end))
+
+	return nil
+}
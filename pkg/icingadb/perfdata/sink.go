@@ -0,0 +1,155 @@
+package perfdata
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// sink writes batches of encoded metrics to an export target.
+type sink interface {
+	// write writes a complete batch. If an error is returned, the whole batch must be written again.
+	write(ctx context.Context, batch []byte) error
+
+	io.Closer
+}
+
+// sinkTimeout limits the time for writing a single batch.
+const sinkTimeout = 30 * time.Second
+
+// httpSink POSTs batches to an InfluxDB write endpoint.
+type httpSink struct {
+	client   *http.Client
+	url      string
+	token    string
+	username string
+	password string
+}
+
+func (s *httpSink) write(ctx context.Context, batch []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(batch))
+	if err != nil {
+		return errors.Wrap(err, "can't create request")
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	} else if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "can't POST to %s", s.url)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1<<10))
+
+		return errors.Errorf("POST %s returned %s: %s", s.url, res.Status, bytes.TrimSpace(body))
+	}
+
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+
+	return nil
+}
+
+// fileSink appends batches to a local file.
+// The file is opened again for every batch so that it can be rotated or consumed by other processes.
+type fileSink struct {
+	path string
+}
+
+func (s *fileSink) write(_ context.Context, batch []byte) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640) // #nosec G302 -- readable by the group for consumers
+	if err != nil {
+		return errors.Wrap(err, "can't open file")
+	}
+
+	if _, err := f.Write(batch); err != nil {
+		_ = f.Close()
+
+		return errors.Wrapf(err, "can't write to %s", s.path)
+	}
+
+	return errors.Wrapf(f.Close(), "can't close %s", s.path)
+}
+
+func (s *fileSink) Close() error {
+	return nil
+}
+
+// tcpSink writes batches to a plaintext TCP socket, optionally using TLS.
+// The connection is established lazily and again after write errors.
+type tcpSink struct {
+	address   string
+	tlsConfig *tls.Config
+	conn      net.Conn
+}
+
+func (s *tcpSink) write(ctx context.Context, batch []byte) error {
+	if s.conn == nil {
+		var dialer interface {
+			DialContext(ctx context.Context, network, address string) (net.Conn, error)
+		} = &net.Dialer{Timeout: sinkTimeout}
+		if s.tlsConfig != nil {
+			dialer = &tls.Dialer{NetDialer: &net.Dialer{Timeout: sinkTimeout}, Config: s.tlsConfig}
+		}
+
+		conn, err := dialer.DialContext(ctx, "tcp", s.address)
+		if err != nil {
+			return errors.Wrapf(err, "can't connect to %s", s.address)
+		}
+
+		s.conn = conn
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout)); err != nil {
+		return s.reset(errors.Wrap(err, "can't set write deadline"))
+	}
+
+	if _, err := s.conn.Write(batch); err != nil {
+		// A partially written batch is written again completely,
+		// as the target can't tell which values it has already received anyway.
+		return s.reset(errors.Wrapf(err, "can't write to %s", s.address))
+	}
+
+	return nil
+}
+
+// reset closes the connection after an error, so that the next write establishes a new one.
+func (s *tcpSink) reset(err error) error {
+	_ = s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+func (s *tcpSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.reset(nil)
+}
+
+// Assert interface compliance.
+var (
+	_ sink = (*httpSink)(nil)
+	_ sink = (*fileSink)(nil)
+	_ sink = (*tcpSink)(nil)
+)
@@ -0,0 +1,148 @@
+package perfdata
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// checkableTags are the tags of the metrics of a host or service taken from the synced config.
+type checkableTags struct {
+	Host         string
+	Service      string
+	CheckCommand string
+	Vars         map[string]string
+}
+
+// tagCacheTTL is the time after which the tags of a host or service are looked up again to pick up config changes.
+const tagCacheTTL = 5 * time.Minute
+
+// tagCache looks up and caches the tags of hosts and services in the database.
+//
+// tagCache is not safe for concurrent use.
+type tagCache struct {
+	db   *database.DB
+	vars []string
+
+	entries map[string]tagCacheEntry
+}
+
+type tagCacheEntry struct {
+	tags    *checkableTags
+	expires time.Time
+}
+
+// newTagCache returns a new tagCache which includes the given custom variables in the tags.
+func newTagCache(db *database.DB, vars []string) *tagCache {
+	return &tagCache{db: db, vars: vars, entries: make(map[string]tagCacheEntry)}
+}
+
+// get returns the tags of the given host or service, or nil if it does not exist (anymore).
+func (c *tagCache) get(ctx context.Context, hostId, serviceId types.Binary) (*checkableTags, error) {
+	key := hostId.String() + serviceId.String()
+	now := time.Now()
+
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		return e.tags, nil
+	}
+
+	tags, err := c.load(ctx, hostId, serviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries[key] = tagCacheEntry{tags: tags, expires: now.Add(tagCacheTTL)}
+
+	return tags, nil
+}
+
+// purge removes all expired entries.
+func (c *tagCache) purge() {
+	now := time.Now()
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// load looks up the tags of the given host or service.
+func (c *tagCache) load(ctx context.Context, hostId, serviceId types.Binary) (*checkableTags, error) {
+	typ, id := "host", hostId
+	query := `SELECT host.name AS host, '' AS service, host.checkcommand_name AS check_command
+		FROM host WHERE host.id = ?`
+
+	if serviceId != nil {
+		typ, id = "service", serviceId
+		query = `SELECT host.name AS host, service.name AS service, service.checkcommand_name AS check_command
+			FROM service INNER JOIN host ON host.id = service.host_id WHERE service.id = ?`
+	}
+
+	var rows []struct {
+		Host         string `db:"host"`
+		Service      string `db:"service"`
+		CheckCommand string `db:"check_command"`
+	}
+
+	query = c.db.Rebind(query)
+	if err := c.db.SelectContext(ctx, &rows, query, id); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	tags := &checkableTags{Host: rows[0].Host, Service: rows[0].Service, CheckCommand: rows[0].CheckCommand}
+
+	if len(c.vars) > 0 {
+		vars, err := c.loadVars(ctx, typ, id)
+		if err != nil {
+			return nil, err
+		}
+
+		tags.Vars = vars
+	}
+
+	return tags, nil
+}
+
+// loadVars looks up the selected custom variables of the given host or service.
+// String values are used as they are, all other values as JSON.
+func (c *tagCache) loadVars(ctx context.Context, typ string, id types.Binary) (map[string]string, error) {
+	query, args, err := sqlx.In(
+		`SELECT customvar.name AS name, customvar.value AS value FROM `+typ+`_customvar
+		INNER JOIN customvar ON customvar.id = `+typ+`_customvar.customvar_id
+		WHERE `+typ+`_customvar.`+typ+`_id = ? AND customvar.name IN (?)`,
+		id, c.vars,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create IN query")
+	}
+
+	var rows []struct {
+		Name  string `db:"name"`
+		Value string `db:"value"`
+	}
+
+	query = c.db.Rebind(query)
+	if err := c.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	vars := make(map[string]string, len(rows))
+	for _, row := range rows {
+		var s string
+		if err := json.Unmarshal([]byte(row.Value), &s); err == nil {
+			vars[row.Name] = s
+		} else {
+			vars[row.Name] = row.Value
+		}
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,149 @@
+package perfdata
+
+import (
+	"context"
+	"github.com/icinga/icinga-go-library/com"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icinga-go-library/periodic"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"sync"
+	"time"
+)
+
+// Writer persists the performance data of check results received as state runtime updates.
+type Writer struct {
+	db       *database.DB
+	logger   *logging.Logger
+	interval time.Duration
+
+	series  chan database.Entity
+	samples chan database.Entity
+
+	// knownSeries caches the units of already written series not to upsert them for every sample.
+	knownSeries   map[string]string
+	knownSeriesMu sync.Mutex
+}
+
+// NewWriter returns a new Writer which computes rollups every rollupInterval.
+func NewWriter(db *database.DB, logger *logging.Logger, rollupInterval time.Duration) *Writer {
+	return &Writer{
+		db:          db,
+		logger:      logger,
+		interval:    rollupInterval,
+		series:      make(chan database.Entity, 1<<10),
+		samples:     make(chan database.Entity, 1<<13),
+		knownSeries: make(map[string]string),
+	}
+}
+
+// Submit parses the performance data of host and service states and queues it to be written by Run.
+// All other entities are ignored.
+//
+// Submit implements the [github.com/icinga/icingadb/pkg/icingadb.RUUpsertFunc] type.
+func (w *Writer) Submit(ctx context.Context, entity database.Entity) error {
+	state, hostId, serviceId, perfdata := checkResultOf(entity)
+	if perfdata == "" {
+		return nil
+	}
+
+	values, err := Parse(perfdata)
+	if err != nil {
+		w.logger.Debugw("Ignoring malformed performance data", zap.String("id", entity.ID().String()), zap.Error(err))
+	}
+
+	for _, v := range values {
+		series := NewSeries(state.EnvironmentId, hostId, serviceId, v.Label, v.Unit)
+
+		if w.isNewSeries(series) {
+			select {
+			case w.series <- series:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case w.samples <- NewSample(series, state.LastUpdate, v):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// isNewSeries reports whether the series or its unit has not been seen before and remembers it.
+func (w *Writer) isNewSeries(series *Series) bool {
+	w.knownSeriesMu.Lock()
+	defer w.knownSeriesMu.Unlock()
+
+	id := series.Id.String()
+	if unit, ok := w.knownSeries[id]; ok && unit == series.Unit {
+		return false
+	}
+
+	w.knownSeries[id] = series.Unit
+
+	return true
+}
+
+// Run writes the performance data queued by Submit and periodically computes the rollups of the environment
+// from the context until the context is canceled.
+func (w *Writer) Run(ctx context.Context) error {
+	env, ok := v1.EnvironmentFromContext(ctx)
+	if !ok {
+		return errors.New("can't get environment from context")
+	}
+
+	// Series queued but not written before a previous Run was canceled must be written again.
+	w.knownSeriesMu.Lock()
+	clear(w.knownSeries)
+	w.knownSeriesMu.Unlock()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	var counter com.Counter
+	defer periodic.Start(ctx, w.logger.Interval(), func(_ periodic.Tick) {
+		if count := counter.Reset(); count > 0 {
+			w.logger.Infof("Inserted %d performance data samples", count)
+		}
+	}).Stop()
+
+	g.Go(func() error {
+		return w.db.UpsertStreamed(ctx, w.series)
+	})
+
+	g.Go(func() error {
+		return w.db.CreateIgnoreStreamed(ctx, w.samples, database.OnSuccessIncrement[database.Entity](&counter))
+	})
+
+	g.Go(func() error {
+		errs := make(chan error, 1)
+
+		defer periodic.Start(ctx, w.interval, func(tick periodic.Tick) {
+			for _, r := range rollups {
+				if err := w.rollup(ctx, env.Id, r, tick.Time); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+
+					return
+				}
+			}
+		}, periodic.Immediate()).Stop()
+
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	return g.Wait()
+}
@@ -99,7 +99,7 @@ func (r *RuntimeUpdates) prepareCustomVarsForSync(ctx context.Context, g *errgro
 				ctx, stmt, r.db.BatchSizeByPlaceholders(placeholders), sem, cvInCh,
 				database.SplitOnDupId[database.Entity],
 				database.OnSuccessIncrement[database.Entity](&counter),
-				database.OnSuccessIncrement[database.Entity](&telemetry.Stats.Config),
+				database.OnSuccessIncrement[database.Entity](&telemetry.StatsFromContext(ctx).Config),
 			)
 		})
 	}
@@ -156,6 +156,25 @@ func (r *RuntimeUpdates) Sync(
 	}
 
 	g, ctx := errgroup.WithContext(ctx)
+
+	// Lossy onUpsert callbacks process the entities from their own queue, so that a slow callback doesn't stall the
+	// other ones and the main sync. If its queue is full, entities are dropped for it. All other callbacks are called
+	// for each entity before the next message is processed, thus applying backpressure to the runtime updates.
+	var upserts []ruUpsert
+	var sinks []*ruUpsertSink
+	for _, upsert := range opts.upserts {
+		if !upsert.lossy {
+			upserts = append(upserts, upsert)
+			continue
+		}
+
+		sink := &ruUpsertSink{ruUpsert: upsert, queue: make(chan database.Entity, ruUpsertQueueSize)}
+		sinks = append(sinks, sink)
+
+		g.Go(func() error { return sink.run(ctx, r.logger) })
+	}
+	var dispatchers sync.WaitGroup
+
 	prepareForSync := func(s *common.SyncSubject, serializerCh <-chan any) (chan<- redis.XMessage, <-chan database.Entity, <-chan any) {
 		var upsertEntities chan database.Entity
 		var updateMessages chan redis.XMessage
@@ -186,14 +205,14 @@ func (r *RuntimeUpdates) Sync(
 
 	for _, factoryFunc := range factoryFuncs {
 		s := common.NewSyncSubject(factoryFunc)
-		stat := getCounterForEntity(s.Entity())
+		stat := getCounterForEntity(ctx, s.Entity())
 
 		r.logger.Debugf("Syncing runtime updates of %s", s.Name())
 
 		key := fmt.Sprintf("icinga:%s", strcase.Delimited(s.Name(), ':'))
 
-		if opts.upsertFn != nil {
-			r.logger.Debugf("Starting additional sync with custom onUpsert callback for %s", s.Name())
+		if len(sinks) > 0 {
+			r.logger.Debugf("Starting additional sync with custom onUpsert callbacks for %s", s.Name())
 
 			serializerCh := make(chan any)
 			updateMessages, upsertEntities, deleteIds := prepareForSync(s, serializerCh)
@@ -202,7 +221,9 @@ func (r *RuntimeUpdates) Sync(
 			}
 			xReads[1][key] = updateMessages
 
+			dispatchers.Add(1)
 			g.Go(func() error {
+				defer dispatchers.Done()
 				defer close(serializerCh)
 
 				for {
@@ -213,8 +234,13 @@ func (r *RuntimeUpdates) Sync(
 						if !ok {
 							return nil
 						}
-						if err := opts.upsertFn(ctx, entity); err != nil {
-							return errors.Wrapf(err, "onUpsert callback failed for entity with ID %v", entity.ID())
+						for _, upsert := range upserts {
+							if err := upsert.fn(ctx, entity); err != nil {
+								return errors.Wrapf(err, "%s callback failed for entity with ID %v", upsert.name, entity.ID())
+							}
+						}
+						for _, sink := range sinks {
+							sink.submit(entity)
 						}
 					case _, ok := <-deleteIds:
 						if !ok {
@@ -296,6 +322,17 @@ func (r *RuntimeUpdates) Sync(
 		})
 	}
 
+	if len(sinks) > 0 {
+		g.Go(func() error {
+			dispatchers.Wait()
+			for _, sink := range sinks {
+				close(sink.queue)
+			}
+
+			return nil
+		})
+	}
+
 	// customvar and customvar_flat sync don't need to be processed with state updates too.
 	if _, exists := streams["icinga:runtime"]; exists {
 		if xReads[0] == nil {
@@ -307,10 +344,12 @@ func (r *RuntimeUpdates) Sync(
 	// Since all xRead goroutines are going to consume messages from the same stream independently, we are only
 	// allowed to send XDel commands after we've successfully dispatched all messages to the corresponding
 	// updateMessages channels. For the database ops, it doesn't really matter when the msgs are acked, but for
-	// the onUpsert callback, the per type updates are processed sequentially, so the xRead will block each time
-	// it tries to send a message to the chOuts channel until the callback has processed the previous one. When
-	// the callback fails to process the previous one (which we already deleted from the stream), we'll either
-	// trigger a HA handover or crash Icinga DB fatally, in which case losing that message is not a big deal.
+	// the onUpsert callbacks, the per type updates are processed sequentially, so the xRead will block each time
+	// it tries to send a message to the chOuts channel until the callbacks have processed the previous one. Thus,
+	// at most that message is lost if a callback fails, which triggers a HA handover or restarts the environment.
+	// Callbacks which must not lose it, such as the Icinga Notifications outbox, take care of that themselves,
+	// e.g. by retrying. Lossy callbacks only queue the entities, so the xRead doesn't wait for them, and queued
+	// entities are lost along with the ones dropped due to a full queue.
 	var xRedisMessageAcks []<-chan string
 	for _, chOuts := range xReads {
 		if chOuts == nil {
@@ -468,7 +507,7 @@ type RUUpsertFunc func(context.Context, database.Entity) error
 // RUOptions defines options for the [RuntimeUpdates.Sync] method.
 type RUOptions struct {
 	allowParallel bool
-	upsertFn      RUUpsertFunc
+	upserts       []ruUpsert
 }
 
 // WithAllowParallel allows parallel execution of runtime updates for the same entity type.
@@ -477,12 +516,79 @@ func WithAllowParallel() RUOption {
 }
 
 // WithRUUpsert allows providing a callback that is called for each Redis stream message of type "upsert".
+// The name identifies the callback in log messages.
 //
 // If this option is used, [RuntimeUpdates.Sync] will start a separate xRead goroutine for the corresponding stream,
 // thus allowing the provided callback to run in parallel with the main database upsert operations for the same entity
 // type. Note that the callback is called for each message of type "upsert", regardless of the entity type, so it is
-// the responsibility of that callback to filter the entities if necessary. Also note that the callback must be safe
-// for concurrent execution, as it may be called in parallel with different types of entities.
-func WithRUUpsert(fn RUUpsertFunc) RUOption {
-	return func(opts *RUOptions) { opts.upsertFn = fn }
+// the responsibility of that callback to filter the entities if necessary.
+//
+// The callback is called before the next message of the same entity type is processed, so a slow callback slows down
+// the runtime updates of the other callbacks as well, but doesn't miss any entity. If this option is used multiple
+// times, the callbacks are called one after another.
+func WithRUUpsert(name string, fn RUUpsertFunc) RUOption {
+	return func(opts *RUOptions) {
+		opts.upserts = append(opts.upserts, ruUpsert{name: name, fn: fn})
+	}
+}
+
+// WithLossyRUUpsert is like [WithRUUpsert], but the callback is called from its own goroutine with its own queue of
+// up to ruUpsertQueueSize entities. If it can't keep up, further entities are dropped for it and the number of dropped
+// entities is logged periodically, so that it doesn't stall the other callbacks or the sync. Use this only for
+// callbacks for which missing some entities is acceptable.
+func WithLossyRUUpsert(name string, fn RUUpsertFunc) RUOption {
+	return func(opts *RUOptions) {
+		opts.upserts = append(opts.upserts, ruUpsert{name: name, fn: fn, lossy: true})
+	}
+}
+
+// ruUpsertQueueSize is the number of entities queued for each [WithLossyRUUpsert] callback.
+const ruUpsertQueueSize = 1 << 12
+
+// ruUpsert is a named callback provided via [WithRUUpsert] or [WithLossyRUUpsert].
+type ruUpsert struct {
+	name  string
+	fn    RUUpsertFunc
+	lossy bool
+}
+
+// ruUpsertSink queues entities for a lossy ruUpsert callback and counts the ones dropped as the queue is full.
+type ruUpsertSink struct {
+	ruUpsert
+
+	queue   chan database.Entity
+	dropped com.Counter
+}
+
+// submit queues entity without blocking, dropping it if the queue is full.
+func (s *ruUpsertSink) submit(entity database.Entity) {
+	select {
+	case s.queue <- entity:
+	default:
+		s.dropped.Inc()
+	}
+}
+
+// run calls the callback for each queued entity until the queue is closed or ctx is done.
+func (s *ruUpsertSink) run(ctx context.Context, logger *logging.Logger) error {
+	defer periodic.Start(ctx, logger.Interval(), func(_ periodic.Tick) {
+		if count := s.dropped.Reset(); count > 0 {
+			logger.Warnf("Dropped %d runtime updates for %s as it can't keep up", count, s.name)
+		}
+	}).Stop()
+
+	for {
+		select {
+		case entity, ok := <-s.queue:
+			if !ok {
+				return nil
+			}
+
+			if err := s.fn(ctx, entity); err != nil {
+				return errors.Wrapf(err, "%s callback failed for entity with ID %v", s.name, entity.ID())
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
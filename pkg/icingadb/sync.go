@@ -112,7 +112,7 @@ func (s Sync) ApplyDelta(ctx context.Context, delta *Delta, hook DeltaHook) erro
 	}
 
 	g, ctx := errgroup.WithContext(ctx)
-	stat := getCounterForEntity(delta.Subject.Entity())
+	stat := getCounterForEntity(ctx, delta.Subject.Entity())
 
 	if hook != nil {
 		g.Go(func() error { return hook(ctx, delta) })
@@ -231,12 +231,14 @@ func (s Sync) SyncCustomvars(ctx context.Context) error {
 	return g.Wait()
 }
 
-// getCounterForEntity returns the appropriate counter (config/state) from telemetry.Stats for e.
-func getCounterForEntity(e database.Entity) *com.Counter {
+// getCounterForEntity returns the appropriate counter (config/state) from the telemetry.Counters of ctx for e.
+func getCounterForEntity(ctx context.Context, e database.Entity) *com.Counter {
+	stats := telemetry.StatsFromContext(ctx)
+
 	switch e.(type) {
 	case *v1.HostState, *v1.ServiceState:
-		return &telemetry.Stats.State
+		return &stats.State
 	default:
-		return &telemetry.Stats.Config
+		return &stats.Config
 	}
 }
@@ -0,0 +1,27 @@
+package history
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStateType_UnmarshalText(t *testing.T) {
+	subtests := []struct {
+		name   string
+		input  string
+		output StateType
+	}{
+		{"soft-int", "0", "soft"},
+		{"hard-int", "1", "hard"},
+		{"soft", "soft", "soft"},
+		{"hard", "hard", "hard"},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			var actual StateType
+			require.NoError(t, actual.UnmarshalText([]byte(st.input)))
+			require.Equal(t, st.output, actual)
+		})
+	}
+}
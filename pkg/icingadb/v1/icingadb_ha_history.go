@@ -0,0 +1,17 @@
+package v1
+
+import (
+	"github.com/icinga/icinga-go-library/types"
+)
+
+// IcingadbHaHistory is a takeover or handover of an Icinga DB instance.
+type IcingadbHaHistory struct {
+	EntityWithoutChecksum `json:",inline"`
+	EnvironmentMeta       `json:",inline"`
+	InstanceId            types.Binary    `json:"instance_id"`
+	EndpointId            types.Binary    `json:"endpoint_id"`
+	EventType             string          `json:"event_type"`
+	Reason                string          `json:"reason"`
+	EventTime             types.UnixMilli `json:"event_time"`
+	SyncDuration          types.Int       `json:"sync_duration"`
+}
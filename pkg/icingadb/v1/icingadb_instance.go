@@ -10,6 +10,11 @@ type IcingadbInstance struct {
 	EndpointId                        types.Binary    `json:"endpoint_id"`
 	Heartbeat                         types.UnixMilli `json:"heartbeat"`
 	Responsible                       types.Bool      `json:"responsible"`
+	Priority                          uint16          `json:"-"`
+	Standby                           types.Bool      `json:"-"`
+	ClockSkewRedis                    int64           `json:"-"`
+	ClockSkewDatabase                 int64           `json:"-"`
+	ClockSkewIcinga2                  int64           `json:"-"`
 	Icinga2Version                    string          `json:"icinga2_version"`
 	Icinga2StartTime                  types.UnixMilli `json:"icinga2_start_time"`
 	Icinga2NotificationsEnabled       types.Bool      `json:"icinga2_notifications_enabled"`
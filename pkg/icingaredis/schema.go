@@ -0,0 +1,77 @@
+package icingaredis
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SchemaVersion is the version of the data Icinga 2 writes to Redis, as announced in the icinga:schema stream.
+type SchemaVersion uint64
+
+const (
+	// MinSchemaVersion is the oldest Redis schema version fully supported by Icinga DB.
+	MinSchemaVersion SchemaVersion = 6
+
+	// MaxSchemaVersion is the newest Redis schema version fully supported by Icinga DB.
+	MaxSchemaVersion SchemaVersion = 6
+
+	// MaxHistorySchemaVersion is the newest Redis schema version of which Icinga DB still syncs the history.
+	// This allows upgrading Icinga 2 before Icinga DB in a rolling upgrade without losing history.
+	// Only raise it once the history stream fields of the newer version are known
+	// and changed ones are taken care of by the adapters of the history sync.
+	MaxHistorySchemaVersion = MaxSchemaVersion
+)
+
+// ParseSchemaVersion parses the version field of an icinga:schema stream entry.
+func ParseSchemaVersion(version string) (SchemaVersion, error) {
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		// Like the unexpected version errors, this is caused by the environment and doesn't need a stack trace.
+		return 0, fmt.Errorf("can't parse Redis schema version %q", version)
+	}
+
+	return SchemaVersion(v), nil
+}
+
+// SchemaCompatibility describes to which extent Icinga DB supports a Redis schema version.
+type SchemaCompatibility byte
+
+const (
+	// SchemaIncompatible means that Icinga DB can't process the data written by Icinga 2 at all.
+	SchemaIncompatible SchemaCompatibility = iota
+
+	// SchemaHistoryOnly means that Icinga DB can only sync the history, but not the configuration and state.
+	SchemaHistoryOnly
+
+	// SchemaCompatible means that Icinga DB supports the Redis schema version fully.
+	SchemaCompatible
+)
+
+// String implements the fmt.Stringer interface.
+func (c SchemaCompatibility) String() string {
+	switch c {
+	case SchemaIncompatible:
+		return "incompatible"
+	case SchemaHistoryOnly:
+		return "history only"
+	case SchemaCompatible:
+		return "compatible"
+	default:
+		return fmt.Sprintf("SchemaCompatibility(%d)", byte(c))
+	}
+}
+
+// Compatibility returns to which extent Icinga DB supports v.
+func (v SchemaVersion) Compatibility() SchemaCompatibility {
+	switch {
+	case v >= MinSchemaVersion && v <= MaxSchemaVersion:
+		return SchemaCompatible
+	case v > MaxSchemaVersion && v <= MaxHistorySchemaVersion:
+		return SchemaHistoryOnly
+	default:
+		return SchemaIncompatible
+	}
+}
+
+// Assert interface compliance.
+var _ fmt.Stringer = SchemaCompatibility(0)
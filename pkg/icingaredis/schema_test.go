@@ -0,0 +1,52 @@
+package icingaredis
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseSchemaVersion(t *testing.T) {
+	subtests := []struct {
+		name    string
+		input   string
+		output  SchemaVersion
+		wantErr bool
+	}{
+		{"empty", "", 0, true},
+		{"number", "6", 6, false},
+		{"negative", "-1", 0, true},
+		{"text", "six", 0, true},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			v, err := ParseSchemaVersion(st.input)
+			if st.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, st.output, v)
+			}
+		})
+	}
+}
+
+func TestSchemaVersion_Compatibility(t *testing.T) {
+	subtests := []struct {
+		name    string
+		version SchemaVersion
+		output  SchemaCompatibility
+	}{
+		{"older", MinSchemaVersion - 1, SchemaIncompatible},
+		{"min", MinSchemaVersion, SchemaCompatible},
+		{"max", MaxSchemaVersion, SchemaCompatible},
+		{"newer", MaxSchemaVersion + 1, SchemaIncompatible},
+		{"newer-history", MaxHistorySchemaVersion + 1, SchemaIncompatible},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.output, st.version.Compatibility())
+		})
+	}
+}
@@ -25,6 +25,13 @@ type ha interface {
 	State() (weResponsibleMilli int64, weResponsible, otherResponsible bool)
 }
 
+// clockSkew represents icingadb.ClockSkew to avoid import cycles.
+type clockSkew interface {
+	Skews() (redisSkew, databaseSkew, icinga2Skew time.Duration)
+	Err() error
+	ErrSinceMilli() int64
+}
+
 type SuccessfulSync struct {
 	FinishMilli   int64
 	DurationMilli int64
@@ -76,17 +83,16 @@ func GetCurrentDbConnErr() (string, int64) {
 	return currentDbConnErr.message, currentDbConnErr.sinceMilli
 }
 
-// OngoingSyncStartMilli is to be updated by the main() function.
-var OngoingSyncStartMilli atomic.Int64
-
 var boolToStr = map[bool]string{false: "0", true: "1"}
 var startTime = time.Now().UnixMilli()
 
 // StartHeartbeat periodically writes heartbeats to Redis for being monitored by Icinga 2.
 // It returns an atomic pointer to SuccessfulSync,
 // which contains synchronisation statistics that the caller should update.
+// The caller should also keep ongoingSyncStartMilli at the start of an ongoing config sync, or zero.
 func StartHeartbeat(
 	ctx context.Context, client *redis.Client, logger *logging.Logger, ha ha, heartbeat *icingaredis.Heartbeat,
+	clock clockSkew, ongoingSyncStartMilli *atomic.Int64,
 ) *atomic.Pointer[SuccessfulSync] {
 	var syncStats atomic.Pointer[SuccessfulSync]
 	syncStats.Store(&SuccessfulSync{})
@@ -100,11 +106,17 @@ func StartHeartbeat(
 	periodic.Start(ctx, interval, func(tick periodic.Tick) {
 		heartbeat := heartbeat.LastReceived()
 		responsibleTsMilli, responsible, otherResponsible := ha.State()
-		ongoingSyncStart := OngoingSyncStartMilli.Load()
+		ongoingSyncStart := ongoingSyncStartMilli.Load()
 		lastSync := syncStats.Load()
 		dbConnErr, dbConnErrSinceMilli := GetCurrentDbConnErr()
+		redisSkew, databaseSkew, icinga2Skew := clock.Skews()
 		now := time.Now()
 
+		// Database connection errors take precedence, as they also prevent measuring the database clock skew.
+		if err := clock.Err(); dbConnErr == "" && err != nil {
+			dbConnErr, dbConnErrSinceMilli = err.Error(), clock.ErrSinceMilli()
+		}
+
 		values := map[string]string{
 			"version":                 internal.Version.Version,
 			"time":                    strconv.FormatInt(now.UnixMilli(), 10),
@@ -119,6 +131,9 @@ func StartHeartbeat(
 			"sync-ongoing-since":      strconv.FormatInt(ongoingSyncStart, 10),
 			"sync-success-finish":     strconv.FormatInt(lastSync.FinishMilli, 10),
 			"sync-success-duration":   strconv.FormatInt(lastSync.DurationMilli, 10),
+			"clock-skew-redis":        strconv.FormatInt(redisSkew.Milliseconds(), 10),
+			"clock-skew-database":     strconv.FormatInt(databaseSkew.Milliseconds(), 10),
+			"clock-skew-icinga2":      strconv.FormatInt(icinga2Skew.Milliseconds(), 10),
 		}
 
 		ctx, cancel := context.WithDeadline(ctx, tick.Time.Add(interval))
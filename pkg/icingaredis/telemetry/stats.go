@@ -12,7 +12,8 @@ import (
 	"time"
 )
 
-var Stats struct {
+// Counters holds the statistics of an environment forwarded by WriteStats.
+type Counters struct {
 	// Config & co. are to be increased by the T sync once for every T object synced.
 	Config           com.Counter
 	State            com.Counter
@@ -22,15 +23,42 @@ var Stats struct {
 	NotificationSync com.Counter
 }
 
-// WriteStats periodically forwards Stats to Redis for being monitored by Icinga 2.
+// NewContext returns a new Context that carries c as value.
+func (c *Counters) NewContext(parent context.Context) context.Context {
+	return context.WithValue(parent, countersContextKey, c)
+}
+
+// Stats are the Counters used by StatsFromContext if a context doesn't carry any.
+var Stats Counters
+
+// StatsFromContext returns the Counters stored in ctx, if any, and Stats otherwise.
+func StatsFromContext(ctx context.Context) *Counters {
+	if c, ok := ctx.Value(countersContextKey).(*Counters); ok {
+		return c
+	}
+
+	return &Stats
+}
+
+// countersContextKey is the key for Counters values in contexts.
+// It's not exported, so callers use Counters.NewContext and StatsFromContext
+// instead of using that key directly.
+var countersContextKey contextKey
+
+// contextKey is an unexported type for context keys defined in this package.
+// This prevents collisions with keys defined in other packages.
+type contextKey struct{}
+
+// WriteStats periodically forwards the Counters of ctx to Redis for being monitored by Icinga 2.
 func WriteStats(ctx context.Context, client *redis.Client, logger *logging.Logger) {
+	stats := StatsFromContext(ctx)
 	counters := map[string]*com.Counter{
-		"config_sync":       &Stats.Config,
-		"state_sync":        &Stats.State,
-		"history_sync":      &Stats.History,
-		"overdue_sync":      &Stats.Overdue,
-		"history_cleanup":   &Stats.HistoryCleanup,
-		"notification_sync": &Stats.NotificationSync,
+		"config_sync":       &stats.Config,
+		"state_sync":        &stats.State,
+		"history_sync":      &stats.History,
+		"overdue_sync":      &stats.Overdue,
+		"history_cleanup":   &stats.HistoryCleanup,
+		"notification_sync": &stats.NotificationSync,
 	}
 
 	periodic.Start(ctx, time.Second, func(_ periodic.Tick) {
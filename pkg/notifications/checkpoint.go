@@ -0,0 +1,78 @@
+package notifications
+
+import (
+	"context"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"time"
+)
+
+// checkpointRetention is the duration for which submitted history events are remembered by the historyCheckpoint.
+//
+// It must exceed the time a history stream entry may remain undeleted in the Redis® of another Icinga DB instance
+// of the same environment. Otherwise, that instance would submit the history event again after taking over.
+const checkpointRetention = 7 * 24 * time.Hour
+
+// checkpointEntry records that a history event has been submitted to Icinga Notifications.
+type checkpointEntry struct {
+	v1.EntityWithoutChecksum `json:",inline"` // The ID is the ID of the history event.
+	v1.EnvironmentMeta       `json:",inline"`
+	HistoryType              string          `json:"history_type"`
+	SubmitTime               types.UnixMilli `json:"submit_time"`
+}
+
+// TableName implements the [database.TableNamer] interface.
+func (*checkpointEntry) TableName() string {
+	return "notifications_checkpoint"
+}
+
+// historyCheckpoint durably records which history events have been submitted to Icinga Notifications.
+//
+// In an HA setup, each Icinga 2 node writes the same history events, identified by the same ID, to its own Redis®,
+// which are then synchronized by the Icinga DB instance connected to it. As history stream entries are only deleted
+// from Redis® after they have been submitted, all entries left over from a restart or an HA takeover are replayed.
+// The historyCheckpoint allows skipping those that have already been submitted, either by this or another instance.
+type historyCheckpoint struct {
+	db *database.DB
+}
+
+// isSubmitted reports whether the given history event has already been submitted.
+func (c *historyCheckpoint) isSubmitted(ctx context.Context, eventId types.Binary) (bool, error) {
+	query := c.db.Rebind(`SELECT COUNT(*) FROM notifications_checkpoint WHERE id = ?`)
+
+	var count int
+	if err := c.db.GetContext(ctx, &count, query, eventId); err != nil {
+		return false, database.CantPerformQuery(err, query)
+	}
+
+	return count > 0, nil
+}
+
+// commit records the given history event as submitted.
+func (c *historyCheckpoint) commit(ctx context.Context, entry *checkpointEntry) error {
+	stmt, _ := c.db.BuildUpsertStmt(entry)
+	if _, err := c.db.NamedExecContext(ctx, stmt, entry); err != nil {
+		return database.CantPerformQuery(err, stmt)
+	}
+
+	return nil
+}
+
+// cleanup forgets all history events submitted before olderThan.
+func (c *historyCheckpoint) cleanup(ctx context.Context, olderThan time.Time) (int64, error) {
+	stmt := c.db.Rebind(`DELETE FROM notifications_checkpoint WHERE submit_time < ?`)
+
+	rs, err := c.db.ExecContext(ctx, stmt, olderThan.UnixMilli())
+	if err != nil {
+		return 0, database.CantPerformQuery(err, stmt)
+	}
+
+	return rs.RowsAffected()
+}
+
+// Assert interface compliance.
+var (
+	_ database.Entity     = (*checkpointEntry)(nil)
+	_ database.TableNamer = (*checkpointEntry)(nil)
+)
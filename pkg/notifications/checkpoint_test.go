@@ -0,0 +1,101 @@
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/icinga/icinga-go-library/config"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// checkpointDatabaseEnvPrefix prefixes the environment variables configuring the database of TestHistoryCheckpoint_commit
+// like the database section of the config file, e.g. ICINGADB_NOTIFICATIONS_TESTS_DATABASE_HOST.
+const checkpointDatabaseEnvPrefix = "ICINGADB_NOTIFICATIONS_TESTS_DATABASE_"
+
+func TestCheckpointEntry_HistoryTypeSchema(t *testing.T) {
+	mysqlEnum := regexp.MustCompile(`history_type enum\(([^)]*)\)`)
+	pgsqlEnum := regexp.MustCompile(`notifications_checkpoint_history_type AS ENUM \(([^)]*)\)`)
+
+	subtests := []struct {
+		file string
+		enum *regexp.Regexp
+	}{
+		{"../../schema/mysql/schema.sql", mysqlEnum},
+		{"../../schema/mysql/upgrades/notifications-checkpoint.sql", mysqlEnum},
+		{"../../schema/mysql/upgrades/notifications-checkpoint-history-types.sql", mysqlEnum},
+		{"../../schema/pgsql/schema.sql", pgsqlEnum},
+		{"../../schema/pgsql/upgrades/notifications-checkpoint.sql", pgsqlEnum},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.file, func(t *testing.T) {
+			ddl, err := os.ReadFile(st.file)
+			require.NoError(t, err)
+
+			match := st.enum.FindSubmatch(ddl)
+			require.NotNil(t, match, "enum not found")
+
+			for key := range historyStructPtrs {
+				require.Contains(t, string(match[1]), "'"+key+"'")
+			}
+		})
+	}
+
+	ddl, err := os.ReadFile("../../schema/pgsql/upgrades/notifications-checkpoint-history-types.sql")
+	require.NoError(t, err)
+
+	for key := range historyStructPtrs {
+		if !strings.Contains(string(ddl), "'"+key+"'") {
+			require.Contains(t, []string{"acknowledgement", "downtime", "flapping"}, key,
+				"history type %q is neither in the original enum nor added by the PostgreSQL upgrade", key)
+		}
+	}
+}
+
+// TestHistoryCheckpoint_commit commits a checkpoint for every history type to a database with the Icinga DB schema
+// imported, configured by the ICINGADB_NOTIFICATIONS_TESTS_DATABASE_* environment variables. It is skipped unless
+// ICINGADB_NOTIFICATIONS_TESTS_DATABASE_HOST is set.
+func TestHistoryCheckpoint_commit(t *testing.T) {
+	if os.Getenv(checkpointDatabaseEnvPrefix+"HOST") == "" {
+		t.Skip("database not configured via " + checkpointDatabaseEnvPrefix + "* environment variables")
+	}
+
+	c := &database.Config{}
+	require.NoError(t, config.FromEnv(c, config.EnvOptions{Prefix: checkpointDatabaseEnvPrefix}))
+
+	db, err := database.NewDbFromConfig(c, logging.NewLogger(zap.NewNop().Sugar(), time.Second), database.RetryConnectorCallbacks{})
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	checkpoint := &historyCheckpoint{db: db}
+
+	for key := range historyStructPtrs {
+		t.Run(key, func(t *testing.T) {
+			id := make(types.Binary, 20)
+			_, _ = rand.Read(id)
+
+			entry := &checkpointEntry{HistoryType: key, SubmitTime: types.UnixMilli(time.Now())}
+			entry.Id = id
+			entry.EnvironmentId = make(types.Binary, 20)
+
+			require.NoError(t, checkpoint.commit(ctx, entry))
+			defer func() {
+				_, _ = db.ExecContext(ctx, db.Rebind(`DELETE FROM notifications_checkpoint WHERE id = ?`), id)
+			}()
+
+			submitted, err := checkpoint.isSubmitted(ctx, id)
+			require.NoError(t, err)
+			require.True(t, submitted)
+		})
+	}
+}
@@ -84,7 +84,7 @@ func streamRedisHashObjects[T any](
 func (client *Client) fetchObjectsFromRedis(
 	ctx context.Context, typ string, ids ...types.Binary,
 ) (map[string]*icingaObject, error) {
-	panicForInvalidType([]string{"host", "service"}, typ)
+	panicForInvalidType([]string{"host", "service", "user"}, typ)
 
 	if len(ids) == 0 {
 		return nil, nil
@@ -192,6 +192,152 @@ func (client *Client) fetchGroupsFromSql(
 	return objs, nil
 }
 
+// fetchDependenciesFromSql returns all hosts and services the given host, or the service if serviceId is not nil,
+// directly depends on if parents is set, or otherwise all hosts and services directly depending on it. Dependencies
+// through a redundancy group are included.
+//
+// The name of a returned service is prefixed by its host name and "!", as known from Icinga 2.
+func (client *Client) fetchDependenciesFromSql(
+	ctx context.Context, hostId, serviceId types.Binary, parents bool,
+) ([]*icingaObject, error) {
+	// An edge points from the child to the parent node. ownNode is the edge column of the node of the given object,
+	// while otherNode is the one of the nodes to be returned.
+	ownNode, otherNode := "to_node_id", "from_node_id"
+	if parents {
+		ownNode, otherNode = otherNode, ownNode
+	}
+
+	ownCondition := `own.host_id = ? AND own.service_id IS NULL`
+	args := []any{hostId}
+	if serviceId != nil {
+		ownCondition = `own.host_id = ? AND own.service_id = ?`
+		args = append(args, serviceId)
+	}
+
+	// The first part of the union selects the direct dependencies, the second one those through a redundancy group.
+	query := `SELECT host.name AS host_name, host.display_name AS host_display_name,
+	service.name AS service_name, service.display_name AS service_display_name
+FROM dependency_node own
+JOIN dependency_edge edge ON edge.` + ownNode + ` = own.id
+JOIN dependency_node other ON other.id = edge.` + otherNode + `
+JOIN host ON host.id = other.host_id
+LEFT JOIN service ON service.id = other.service_id
+WHERE ` + ownCondition + `
+UNION
+SELECT host.name AS host_name, host.display_name AS host_display_name,
+	service.name AS service_name, service.display_name AS service_display_name
+FROM dependency_node own
+JOIN dependency_edge group_edge ON group_edge.` + ownNode + ` = own.id
+JOIN dependency_node redundancy_group ON redundancy_group.id = group_edge.` + otherNode + `
+	AND redundancy_group.redundancy_group_id IS NOT NULL
+JOIN dependency_edge edge ON edge.` + ownNode + ` = redundancy_group.id
+JOIN dependency_node other ON other.id = edge.` + otherNode + `
+JOIN host ON host.id = other.host_id
+LEFT JOIN service ON service.id = other.service_id
+WHERE ` + ownCondition
+
+	var rows []struct {
+		HostName           string       `db:"host_name"`
+		HostDisplayName    string       `db:"host_display_name"`
+		ServiceName        types.String `db:"service_name"`
+		ServiceDisplayName types.String `db:"service_display_name"`
+	}
+
+	err := client.fetchObjectFromSql(ctx, &rows, query, append(args, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]*icingaObject, 0, len(rows))
+	for _, row := range rows {
+		obj := &icingaObject{Name: row.HostName, DisplayName: row.HostDisplayName}
+		if row.ServiceName.Valid {
+			obj.Name += "!" + row.ServiceName.String
+			obj.DisplayName += ": " + row.ServiceDisplayName.String
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// fetchParentFailedFromSql reports whether a parent of the given host, or the service if serviceId is not nil, failed
+// according to the dependency graph, i.e. whether the edge to a parent or a redundancy group failed, or a parent
+// itself is unreachable, which is the case if the failure is further up in the graph.
+func (client *Client) fetchParentFailedFromSql(ctx context.Context, hostId, serviceId types.Binary) (bool, error) {
+	ownCondition := `own.host_id = ? AND own.service_id IS NULL`
+	args := []any{hostId}
+	if serviceId != nil {
+		ownCondition = `own.host_id = ? AND own.service_id = ?`
+		args = append(args, serviceId)
+	}
+
+	query := `SELECT COUNT(*)
+FROM dependency_node own
+JOIN dependency_edge edge ON edge.from_node_id = own.id
+JOIN dependency_edge_state edge_state ON edge_state.id = edge.dependency_edge_state_id
+JOIN dependency_node parent ON parent.id = edge.to_node_id
+LEFT JOIN redundancy_group_state ON redundancy_group_state.redundancy_group_id = parent.redundancy_group_id
+LEFT JOIN host_state ON host_state.host_id = parent.host_id AND parent.service_id IS NULL
+LEFT JOIN service_state ON service_state.service_id = parent.service_id
+WHERE ` + ownCondition + ` AND (
+	edge_state.failed = 'y'
+	OR redundancy_group_state.failed = 'y' OR redundancy_group_state.is_reachable = 'n'
+	OR host_state.is_reachable = 'n' OR service_state.is_reachable = 'n'
+)`
+
+	var counts []int64
+	if err := client.fetchObjectFromSql(ctx, &counts, query, args...); err != nil {
+		return false, err
+	}
+
+	return len(counts) > 0 && counts[0] > 0, nil
+}
+
+// objectAttributes are attributes of a host or service referencing other objects, see Client.fetchAttributesFromSql.
+type objectAttributes struct {
+	Zone         types.String `db:"zone"`
+	Endpoint     types.String `db:"endpoint"`
+	Checkcommand types.String `db:"checkcommand"`
+	NotesUrl     types.String `db:"notes_url"`
+	ActionUrl    types.String `db:"action_url"`
+}
+
+// fetchAttributesFromSql returns the objectAttributes of the given host, or the service if serviceId is not nil.
+//
+// The endpoint is the command endpoint, if configured, or otherwise the endpoint which executed the last check.
+func (client *Client) fetchAttributesFromSql(
+	ctx context.Context, hostId, serviceId types.Binary,
+) (*objectAttributes, error) {
+	typ, id := "host", hostId
+	if serviceId != nil {
+		typ, id = "service", serviceId
+	}
+
+	query := `SELECT zone.name AS zone, COALESCE(endpoint.name, ` + typ + `_state.check_source) AS endpoint,
+	checkcommand.name AS checkcommand, notes_url.notes_url AS notes_url, action_url.action_url AS action_url
+FROM ` + typ + `
+LEFT JOIN ` + typ + `_state ON ` + typ + `_state.` + typ + `_id = ` + typ + `.id
+LEFT JOIN zone ON zone.id = ` + typ + `.zone_id
+LEFT JOIN endpoint ON endpoint.id = ` + typ + `.command_endpoint_id
+LEFT JOIN checkcommand ON checkcommand.id = ` + typ + `.checkcommand_id
+LEFT JOIN notes_url ON notes_url.id = ` + typ + `.notes_url_id
+LEFT JOIN action_url ON action_url.id = ` + typ + `.action_url_id
+WHERE ` + typ + `.id = ?`
+
+	var attrs []*objectAttributes
+	if err := client.fetchObjectFromSql(ctx, &attrs, query, id); err != nil {
+		return nil, err
+	}
+
+	if len(attrs) == 0 {
+		return nil, errors.Errorf("%s %q does not exist", typ, id)
+	}
+
+	return attrs[0], nil
+}
+
 // fetchMultiCustomVarsFromSql returns all custom variables for requested IDs of type typ from the relational database.
 func (client *Client) fetchMultiCustomVarsFromSql(
 	ctx context.Context, typ string, ids ...types.Binary,
@@ -293,6 +439,49 @@ type relations struct {
 	Services      []*icingaObject
 	Hostgroups    []*icingaObject
 	Servicegroups []*icingaObject
+	Children      []*icingaObject // Hosts and services depending on this object, see Client.fetchDependenciesFromSql.
+	Parents       []*icingaObject // Hosts and services this object depends on, see Client.fetchDependenciesFromSql.
+	Zone          *namedObject
+	Endpoint      *namedObject
+	Checkcommand  *namedObject
+	NotesUrl      string
+	ActionUrl     string
+	Comment       *commentRelation
+	Notification  *notificationRelation
+}
+
+// namedObject represents an Icinga object which is only identified by its name, such as a zone.
+type namedObject struct {
+	Name string `json:"name"`
+}
+
+// commentRelation describes the comment of a comment history event.
+type commentRelation struct {
+	Author    string `json:"author"`
+	Text      string `json:"text"`
+	RemovedBy string `json:"removed_by,omitempty"`
+}
+
+// notificationRelation describes the Icinga 2 notification of a notification history event.
+type notificationRelation struct {
+	Type   string          `json:"type"`
+	Author string          `json:"author,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Users  []*icingaObject `json:"users"`
+}
+
+// setComment sets the Comment relation, which is complete from the start.
+func (rel *relations) setComment(comment *commentRelation) {
+	rel.Comment = comment
+	rel.completeRelations = append(rel.completeRelations, "comment.author", "comment.text", "comment.removed_by")
+}
+
+// setNotification sets the Notification relation, which is complete from the start.
+func (rel *relations) setNotification(notification *notificationRelation) {
+	rel.Notification = notification
+	rel.completeRelations = append(rel.completeRelations,
+		"notification.type", "notification.author", "notification.text",
+		"notification.users[*].name", "notification.users[*].display_name")
 }
 
 // populateObject fills the Object struct and completeRelations.
@@ -310,7 +499,8 @@ func (rel *relations) populateObject() {
 
 // asMap creates a Go map populated by this relations and all its fields to be used in event.Event.
 //
-// Except the "object", all map values are one or multiple icingaObjects, which are JSON serializable.
+// Most map values are one or multiple icingaObjects, while "zone", "endpoint" and "checkcommand" are namedObjects,
+// "notes_url" and "action_url" are strings, and the remaining ones special objects. All of them are JSON serializable.
 func (rel *relations) asMap() map[string]any {
 	out := make(map[string]any)
 
@@ -328,10 +518,91 @@ func (rel *relations) asMap() map[string]any {
 	if len(rel.Servicegroups) > 0 {
 		out["servicegroups"] = rel.Servicegroups
 	}
+	if len(rel.Children) > 0 {
+		out["children"] = rel.Children
+	}
+	if len(rel.Parents) > 0 {
+		out["parents"] = rel.Parents
+	}
+	if rel.Zone != nil {
+		out["zone"] = rel.Zone
+	}
+	if rel.Endpoint != nil {
+		out["endpoint"] = rel.Endpoint
+	}
+	if rel.Checkcommand != nil {
+		out["checkcommand"] = rel.Checkcommand
+	}
+	if rel.NotesUrl != "" {
+		out["notes_url"] = rel.NotesUrl
+	}
+	if rel.ActionUrl != "" {
+		out["action_url"] = rel.ActionUrl
+	}
+	if rel.Comment != nil {
+		out["comment"] = rel.Comment
+	}
+	if rel.Notification != nil {
+		out["notification"] = rel.Notification
+	}
 
 	return out
 }
 
+// supportedRelations maps the root names of the supported relations to whether they are strings, which have no fields.
+var supportedRelations = map[string]bool{
+	"object":        false,
+	"host":          false,
+	"services":      false,
+	"hostgroups":    false,
+	"servicegroups": false,
+	"children":      false,
+	"parents":       false,
+	"zone":          false,
+	"endpoint":      false,
+	"checkcommand":  false,
+	"notes_url":     true,
+	"action_url":    true,
+	"comment":       false,
+	"notification":  false,
+}
+
+// ValidateRelation checks whether the given JSONPath is valid and refers to supported relations only.
+//
+// It does not check whether the referenced fields of the relations exist.
+func ValidateRelation(query string) error {
+	path, err := jsonpath.Parse(query)
+	if err != nil {
+		return errors.Wrapf(err, "cannot parse JSONPath %q", query)
+	}
+
+	segments := path.Query().Segments()
+	if len(segments) == 0 {
+		return errors.Errorf("JSONPath %q does not select any relation", query)
+	}
+	if segments[0].IsDescendant() {
+		return errors.Errorf("JSONPath %q must select relations directly, not as descendants", query)
+	}
+
+	for _, selector := range segments[0].Selectors() {
+		name, ok := selector.(spec.Name)
+		if !ok {
+			return errors.Errorf("JSONPath %q must select relations by name, not by %q", query, selector)
+		}
+
+		isString, ok := supportedRelations[string(name)]
+		if !ok {
+			return errors.Errorf("JSONPath %q selects unsupported relation %q", query, string(name))
+		}
+
+		if isString && len(segments) > 1 {
+			return errors.Errorf("JSONPath %q selects a field of relation %q, which is a string", query, string(name))
+		}
+	}
+
+	return nil
+}
+
 // complete the relations based on the query path.
 func (rel *relations) complete(ctx context.Context, query string) error {
 	path, err := jsonpath.Parse(query)
@@ -352,8 +623,8 @@ func (client *Client) relationsProvider(ctx context.Context, rel *relations, seg
 		rootSelector := selector.String()
 		childSegments := segments[1:]
 
-		if rootSelector == `"object"` {
-			// "object" is a special case and already completely populated.
+		if rootSelector == `"object"` || rootSelector == `"comment"` || rootSelector == `"notification"` {
+			// These are special cases and already completely populated, if present for this event at all.
 			continue
 		}
 
@@ -582,6 +853,48 @@ func (client *Client) relationsProvider(ctx context.Context, rel *relations, seg
 
 			updateCompleteRelations("servicegroups[*]", true)
 			rel.Servicegroups = serviceGroups
+		case `"children"`, `"parents"`:
+			name := strings.Trim(rootSelector, `"`)
+			if slices.Contains(rel.completeRelations, name+"[*].name") {
+				continue
+			}
+
+			objs, err := client.fetchDependenciesFromSql(ctx, rel.hostId, rel.serviceId, name == "parents")
+			if err != nil {
+				return errors.Wrapf(err, "cannot fetch %s", name)
+			}
+
+			// Custom variables of dependencies are not supported, so treat them like groups.
+			fetchObject = true
+			updateCompleteRelations(name+"[*]", true)
+			if name == "parents" {
+				rel.Parents = objs
+			} else {
+				rel.Children = objs
+			}
+		case `"zone"`, `"endpoint"`, `"checkcommand"`, `"notes_url"`, `"action_url"`:
+			// All of them are fetched at once.
+			if slices.Contains(rel.completeRelations, "zone.name") {
+				continue
+			}
+
+			attrs, err := client.fetchAttributesFromSql(ctx, rel.hostId, rel.serviceId)
+			if err != nil {
+				return errors.Wrap(err, "cannot fetch object attributes")
+			}
+
+			named := func(name types.String) *namedObject {
+				if !name.Valid {
+					return nil
+				}
+
+				return &namedObject{Name: name.String}
+			}
+
+			rel.Zone, rel.Endpoint, rel.Checkcommand = named(attrs.Zone), named(attrs.Endpoint), named(attrs.Checkcommand)
+			rel.NotesUrl, rel.ActionUrl = attrs.NotesUrl.String, attrs.ActionUrl.String
+			rel.completeRelations = append(rel.completeRelations,
+				"zone.name", "endpoint.name", "checkcommand.name", "notes_url", "action_url")
 		default:
 			return errors.Errorf("unsupported JSONPath root segment selector %q", rootSelector)
 		}
@@ -87,3 +87,34 @@ func Test_relations_complete(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestValidateRelation(t *testing.T) {
+	subtests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "host-vars", input: "$.host.vars"},
+		{name: "services", input: "$.services[*].name"},
+		{name: "zone", input: "$.zone.name"},
+		{name: "multiple", input: "$['parents', 'checkcommand']"},
+		{name: "url", input: "$.notes_url"},
+		{name: "invalid", input: "$[invalid", error: "cannot parse JSONPath"},
+		{name: "root", input: "$", error: "does not select any relation"},
+		{name: "unsupported", input: "$.timeperiod.name", error: `unsupported relation "timeperiod"`},
+		{name: "wildcard", input: "$.*", error: "must select relations by name"},
+		{name: "descendant", input: "$..name", error: "not as descendants"},
+		{name: "url-field", input: "$.action_url.host", error: "which is a string"},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			err := ValidateRelation(st.input)
+			if st.error == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, st.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,483 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// Filter selects the hosts and services whose events are submitted to Icinga Notifications.
+//
+// An object is selected if it matches any of the Include rules, or if there are none, and none of the Exclude rules.
+type Filter struct {
+	Include []FilterRule `yaml:"include"`
+	Exclude []FilterRule `yaml:"exclude"`
+}
+
+// Validate checks constraints in the supplied filter and returns an error if they are violated.
+func (f *Filter) Validate() error {
+	for i := range f.Include {
+		if err := f.Include[i].Validate(); err != nil {
+			return errors.Wrapf(err, "invalid include rule #%d", i+1)
+		}
+	}
+
+	for i := range f.Exclude {
+		if err := f.Exclude[i].Validate(); err != nil {
+			return errors.Wrapf(err, "invalid exclude rule #%d", i+1)
+		}
+	}
+
+	return nil
+}
+
+// IsEmpty reports whether the filter selects all objects.
+func (f *Filter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// match reports whether the given object is selected by the filter.
+func (f *Filter) match(o *filterObject) bool {
+	if len(f.Include) > 0 && !matchAnyRule(f.Include, o) {
+		return false
+	}
+
+	return !matchAnyRule(f.Exclude, o)
+}
+
+// matchAnyRule reports whether the given object matches any of the rules.
+func matchAnyRule(rules []FilterRule, o *filterObject) bool {
+	for i := range rules {
+		if rules[i].match(o) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterRule matches hosts and services by patterns, as understood by [path.Match].
+// An object matches the rule if it matches all of its non-empty criteria.
+type FilterRule struct {
+	Host    string `yaml:"host"`
+	Service string `yaml:"service"` // If set, hosts never match.
+	Zone    string `yaml:"zone"`
+
+	// Hostgroup and Servicegroup match if any group of the host or service matches.
+	Hostgroup    string `yaml:"hostgroup"`
+	Servicegroup string `yaml:"servicegroup"` // If set, hosts never match.
+
+	// HostVars and ServiceVars map custom variable names to patterns. Values other than strings are matched as JSON.
+	HostVars    map[string]string `yaml:"host-vars"`
+	ServiceVars map[string]string `yaml:"service-vars"` // If set, hosts never match.
+}
+
+// Validate checks constraints in the supplied filter rule and returns an error if they are violated.
+func (r *FilterRule) Validate() error {
+	patterns := []string{r.Host, r.Service, r.Zone, r.Hostgroup, r.Servicegroup}
+	for _, pattern := range r.HostVars {
+		patterns = append(patterns, pattern)
+	}
+	for _, pattern := range r.ServiceVars {
+		patterns = append(patterns, pattern)
+	}
+
+	empty := true
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+
+		empty = false
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+	}
+
+	if empty {
+		return errors.New("rule has no criteria")
+	}
+
+	return nil
+}
+
+// match reports whether the given object matches the rule.
+func (r *FilterRule) match(o *filterObject) bool {
+	if !o.isService && (r.Service != "" || r.Servicegroup != "" || len(r.ServiceVars) > 0) {
+		return false
+	}
+
+	return matchPattern(r.Host, o.Host) &&
+		matchPattern(r.Service, o.Service) &&
+		matchPattern(r.Zone, o.Zone) &&
+		matchAnyName(r.Hostgroup, o.Hostgroups) &&
+		matchAnyName(r.Servicegroup, o.Servicegroups) &&
+		matchVars(r.HostVars, o.HostVars) &&
+		matchVars(r.ServiceVars, o.ServiceVars)
+}
+
+// matchPattern reports whether name matches the pattern, which matches everything if empty.
+func matchPattern(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, name)
+
+	return ok
+}
+
+// matchAnyName reports whether any of the names matches the pattern, which matches everything if empty.
+func matchAnyName(pattern string, names []string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchVars reports whether all custom variables match their patterns.
+func matchVars(patterns map[string]string, vars map[string]any) bool {
+	for name, pattern := range patterns {
+		value, ok := vars[name]
+		if !ok {
+			return false
+		}
+
+		s, ok := value.(string)
+		if !ok {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return false
+			}
+
+			s = string(encoded)
+		}
+
+		if !matchPattern(pattern, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterObject holds the attributes of a host or service a Filter is evaluated against.
+type filterObject struct {
+	isService bool
+
+	Host          string
+	Service       string
+	Zone          string
+	Hostgroups    []string
+	Servicegroups []string
+	HostVars      map[string]any
+	ServiceVars   map[string]any
+}
+
+// objectFilterTTL is the time after which the attributes of an object are looked up again to pick up config changes.
+// Expired entries are evicted at the same interval.
+const objectFilterTTL = 5 * time.Minute
+
+// objectFilterBulkSize is the maximum number of objects whose attributes are loaded at once by objectFilter.preload.
+const objectFilterBulkSize = 1 << 10
+
+// objectFilter evaluates a Filter against hosts and services, whose attributes are fetched by the Client and cached.
+//
+// objectFilter is safe for concurrent use.
+type objectFilter struct {
+	filter Filter
+	client *Client
+
+	mu           sync.Mutex
+	entries      map[string]objectFilterEntry
+	nextEviction time.Time
+}
+
+type objectFilterEntry struct {
+	selected bool
+	expires  time.Time
+}
+
+// objectFilterKey identifies a host, or a service if serviceId is not nil.
+type objectFilterKey struct {
+	hostId    types.Binary
+	serviceId types.Binary
+}
+
+// String returns the key of the object in objectFilter.entries.
+func (k objectFilterKey) String() string {
+	return k.hostId.String() + k.serviceId.String()
+}
+
+// objectFilterKeyOf returns the objectFilterKey of the host or service of the given state.
+func objectFilterKeyOf(entity database.Entity) objectFilterKey {
+	switch s := entity.(type) {
+	case *v1.HostState:
+		return objectFilterKey{hostId: s.HostId}
+	case *v1.ServiceState:
+		return objectFilterKey{hostId: s.HostId, serviceId: s.ServiceId}
+	default:
+		return objectFilterKey{}
+	}
+}
+
+// newObjectFilter returns a new objectFilter for the given Filter.
+func newObjectFilter(filter Filter, client *Client) *objectFilter {
+	return &objectFilter{filter: filter, client: client, entries: make(map[string]objectFilterEntry)}
+}
+
+// selected reports whether the host, or the service if serviceId is not nil, is selected by the filter.
+// Objects which do not exist (anymore) are considered selected, as there is nothing to filter.
+func (f *objectFilter) selected(ctx context.Context, hostId, serviceId types.Binary) (bool, error) {
+	if f == nil || f.filter.IsEmpty() {
+		return true, nil
+	}
+
+	key := objectFilterKey{hostId: hostId, serviceId: serviceId}
+
+	if selected, ok := f.cached(key, time.Now()); ok {
+		return selected, nil
+	}
+
+	if err := f.preload(ctx, []objectFilterKey{key}); err != nil {
+		return false, err
+	}
+
+	selected, _ := f.cached(key, time.Now())
+
+	return selected, nil
+}
+
+// preload evaluates the filter for all given objects not cached yet, loading their attributes in bulks,
+// so that subsequent calls to selected for these objects don't have to look them up one by one.
+func (f *objectFilter) preload(ctx context.Context, keys []objectFilterKey) error {
+	if f == nil || f.filter.IsEmpty() {
+		return nil
+	}
+
+	now := time.Now()
+
+	var missing []objectFilterKey
+	for _, key := range keys {
+		if _, ok := f.cached(key, now); !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	for bulk := range slices.Chunk(missing, objectFilterBulkSize) {
+		objects, err := f.load(ctx, bulk)
+		if err != nil {
+			return err
+		}
+
+		f.mu.Lock()
+		f.evictExpired(now)
+		for _, key := range bulk {
+			o := objects[key.String()]
+			f.entries[key.String()] = objectFilterEntry{
+				selected: o == nil || f.filter.match(o),
+				expires:  now.Add(objectFilterTTL),
+			}
+		}
+		f.mu.Unlock()
+	}
+
+	return nil
+}
+
+// cached returns the cached filter result of the given object, if any and not yet expired.
+func (f *objectFilter) cached(key objectFilterKey, now time.Time) (selected, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.entries[key.String()]
+	if !ok || !now.Before(e.expires) {
+		return false, false
+	}
+
+	return e.selected, true
+}
+
+// evictExpired removes the expired entries, but at most once per objectFilterTTL,
+// so that the entries don't have to be iterated on every cache miss. f.mu must be held.
+func (f *objectFilter) evictExpired(now time.Time) {
+	if now.Before(f.nextEviction) {
+		return
+	}
+
+	for k, e := range f.entries {
+		if !now.Before(e.expires) {
+			delete(f.entries, k)
+		}
+	}
+
+	f.nextEviction = now.Add(objectFilterTTL)
+}
+
+// load fetches the attributes of the given hosts and services in bulk, by their objectFilterKey string.
+// Objects which do not exist are missing from the returned map.
+func (f *objectFilter) load(ctx context.Context, keys []objectFilterKey) (map[string]*filterObject, error) {
+	type checkable struct {
+		Name     string `json:"name"`
+		ZoneName string `json:"zone_name"`
+	}
+
+	var hostIds, serviceIds []types.Binary
+	seenHosts := make(map[string]struct{})
+	for _, key := range keys {
+		if _, ok := seenHosts[key.hostId.String()]; !ok {
+			seenHosts[key.hostId.String()] = struct{}{}
+			hostIds = append(hostIds, key.hostId)
+		}
+		if key.serviceId != nil {
+			serviceIds = append(serviceIds, key.serviceId)
+		}
+	}
+
+	fetch := func(ctx context.Context, typ string, ids []types.Binary) (map[string]*checkable, error) {
+		objs := make(map[string]*checkable)
+		if len(ids) == 0 {
+			return objs, nil
+		}
+
+		idStrings := make([]string, 0, len(ids))
+		for _, id := range ids {
+			idStrings = append(idStrings, id.String())
+		}
+
+		err := streamRedisHashObjects(ctx, f.client.redisClient, "icinga:"+typ, func(c *checkable, id string) error {
+			objs[id] = c
+			return nil
+		}, idStrings...)
+
+		return objs, err
+	}
+
+	var (
+		hosts, services           map[string]*checkable
+		hostgroups, servicegroups map[string][]string
+		hostVars, serviceVars     map[string]map[string]any
+	)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() (err error) {
+		hosts, err = fetch(ctx, "host", hostIds)
+		return
+	})
+
+	g.Go(func() (err error) {
+		services, err = fetch(ctx, "service", serviceIds)
+		return
+	})
+
+	g.Go(func() (err error) {
+		hostgroups, err = f.fetchGroupNames(ctx, "hostgroup", hostIds)
+		return
+	})
+
+	g.Go(func() (err error) {
+		servicegroups, err = f.fetchGroupNames(ctx, "servicegroup", serviceIds)
+		return
+	})
+
+	g.Go(func() (err error) {
+		hostVars, err = f.client.fetchMultiCustomVarsFromSql(ctx, "host", hostIds...)
+		return
+	})
+
+	g.Go(func() (err error) {
+		serviceVars, err = f.client.fetchMultiCustomVarsFromSql(ctx, "service", serviceIds...)
+		return
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, errors.Wrap(err, "cannot fetch object attributes for filter")
+	}
+
+	objects := make(map[string]*filterObject, len(keys))
+	for _, key := range keys {
+		host, ok := hosts[key.hostId.String()]
+		if !ok {
+			continue
+		}
+
+		o := &filterObject{
+			Host:       host.Name,
+			Zone:       host.ZoneName,
+			Hostgroups: hostgroups[key.hostId.String()],
+			HostVars:   hostVars[key.hostId.String()],
+		}
+
+		if key.serviceId != nil {
+			service, ok := services[key.serviceId.String()]
+			if !ok {
+				continue
+			}
+
+			o.isService = true
+			// The zone of a service may differ from the one of its host.
+			o.Service, o.Zone = service.Name, service.ZoneName
+			o.Servicegroups = servicegroups[key.serviceId.String()]
+			o.ServiceVars = serviceVars[key.serviceId.String()]
+		}
+
+		objects[key.String()] = o
+	}
+
+	return objects, nil
+}
+
+// fetchGroupNames returns the names of the host or service groups, as specified by typ, by member ID.
+func (f *objectFilter) fetchGroupNames(ctx context.Context, typ string, ids []types.Binary) (map[string][]string, error) {
+	panicForInvalidType([]string{"hostgroup", "servicegroup"}, typ)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	member := strings.TrimSuffix(typ, "group") + "_id"
+
+	query, args, err := sqlx.In(
+		`SELECT `+typ+`_member.`+member+` AS member_id, `+typ+`.name AS name
+		FROM `+typ+`
+		JOIN `+typ+`_member ON `+typ+`.id = `+typ+`_member.`+typ+`_id
+		WHERE `+typ+`_member.`+member+` IN (?)`,
+		ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create IN query")
+	}
+
+	var rows []struct {
+		MemberId types.Binary `db:"member_id"`
+		Name     string       `db:"name"`
+	}
+	if err := f.client.fetchObjectFromSql(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, row := range rows {
+		groups[row.MemberId.String()] = append(groups[row.MemberId.String()], row.Name)
+	}
+
+	return groups, nil
+}
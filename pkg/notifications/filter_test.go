@@ -0,0 +1,101 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_match(t *testing.T) {
+	host := &filterObject{
+		Host:       "web1",
+		Zone:       "dmz",
+		Hostgroups: []string{"linux", "web"},
+		HostVars:   map[string]any{"env": "staging", "tier": float64(2)},
+	}
+
+	service := &filterObject{
+		isService:     true,
+		Host:          "web1",
+		Service:       "http",
+		Zone:          "master",
+		Hostgroups:    []string{"linux"},
+		Servicegroups: []string{"http-checks"},
+		HostVars:      map[string]any{"env": "production"},
+		ServiceVars:   map[string]any{"team": "web"},
+	}
+
+	subtests := []struct {
+		name     string
+		filter   Filter
+		input    *filterObject
+		selected bool
+	}{
+		{name: "empty", input: host, selected: true},
+		{name: "exclude-host", filter: Filter{Exclude: []FilterRule{{Host: "web*"}}}, input: host, selected: false},
+		{name: "exclude-other-host", filter: Filter{Exclude: []FilterRule{{Host: "db*"}}}, input: host, selected: true},
+		{name: "exclude-var", filter: Filter{Exclude: []FilterRule{{HostVars: map[string]string{"env": "staging"}}}}, input: host, selected: false},
+		{name: "exclude-var-json", filter: Filter{Exclude: []FilterRule{{HostVars: map[string]string{"tier": "2"}}}}, input: host, selected: false},
+		{name: "exclude-missing-var", filter: Filter{Exclude: []FilterRule{{HostVars: map[string]string{"os": "*"}}}}, input: host, selected: true},
+		{name: "exclude-hostgroup", filter: Filter{Exclude: []FilterRule{{Hostgroup: "web"}}}, input: host, selected: false},
+		{name: "exclude-service-rule-host", filter: Filter{Exclude: []FilterRule{{Host: "web1", Service: "*"}}}, input: host, selected: true},
+		{name: "exclude-service", filter: Filter{Exclude: []FilterRule{{Host: "web1", Service: "http"}}}, input: service, selected: false},
+		{name: "exclude-partial", filter: Filter{Exclude: []FilterRule{{Service: "http", Zone: "dmz"}}}, input: service, selected: true},
+		{name: "include-zone", filter: Filter{Include: []FilterRule{{Zone: "master"}}}, input: service, selected: true},
+		{name: "include-other-zone", filter: Filter{Include: []FilterRule{{Zone: "master"}}}, input: host, selected: false},
+		{name: "include-any", filter: Filter{Include: []FilterRule{{Zone: "master"}, {Hostgroup: "linux"}}}, input: host, selected: true},
+		{name: "include-servicegroup", filter: Filter{Include: []FilterRule{{Servicegroup: "http-*", ServiceVars: map[string]string{"team": "web"}}}}, input: service, selected: true},
+		{
+			name: "include-exclude",
+			filter: Filter{
+				Include: []FilterRule{{Hostgroup: "linux"}},
+				Exclude: []FilterRule{{HostVars: map[string]string{"env": "production"}}},
+			},
+			input:    service,
+			selected: false,
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.NoError(t, st.filter.Validate())
+			require.Equal(t, st.selected, st.filter.match(st.input))
+		})
+	}
+}
+
+func TestFilterRule_Validate(t *testing.T) {
+	require.NoError(t, (&FilterRule{Host: "web*"}).Validate())
+	require.ErrorContains(t, (&FilterRule{}).Validate(), "rule has no criteria")
+	require.ErrorContains(t, (&FilterRule{Service: "[web"}).Validate(), "invalid pattern")
+}
+
+func TestObjectFilter_evictExpired(t *testing.T) {
+	now := time.Now()
+	valid := objectFilterKey{hostId: types.Binary{1}}
+	expired := objectFilterKey{hostId: types.Binary{1}, serviceId: types.Binary{2}}
+
+	f := newObjectFilter(Filter{}, nil)
+	f.entries[valid.String()] = objectFilterEntry{selected: true, expires: now.Add(time.Minute)}
+	f.entries[expired.String()] = objectFilterEntry{selected: true, expires: now}
+
+	selected, ok := f.cached(valid, now)
+	require.True(t, ok)
+	require.True(t, selected)
+
+	_, ok = f.cached(expired, now)
+	require.False(t, ok, "expired entries must not be returned")
+
+	f.evictExpired(now)
+	require.Len(t, f.entries, 1)
+	require.Contains(t, f.entries, valid.String())
+
+	f.entries[expired.String()] = objectFilterEntry{expires: now}
+	f.evictExpired(now.Add(objectFilterTTL / 2))
+	require.Len(t, f.entries, 2, "entries must be evicted at most once per TTL")
+
+	f.evictExpired(now.Add(objectFilterTTL))
+	require.Empty(t, f.entries)
+}
@@ -1,6 +1,7 @@
 package notifications
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1" // #nosec G505 -- Blocklisted import crypto/sha1
 	"encoding/hex"
@@ -10,6 +11,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/icinga/icinga-go-library/backoff"
@@ -19,6 +21,7 @@ import (
 	"github.com/icinga/icinga-go-library/notifications/event"
 	"github.com/icinga/icinga-go-library/notifications/source"
 	"github.com/icinga/icinga-go-library/objectpacker"
+	"github.com/icinga/icinga-go-library/periodic"
 	"github.com/icinga/icinga-go-library/redis"
 	"github.com/icinga/icinga-go-library/retry"
 	"github.com/icinga/icinga-go-library/strcase"
@@ -64,6 +67,60 @@ func (ev *fetchableEvent) completeAndUpdate(ctx context.Context, attributes []st
 	return nil
 }
 
+// Unreachable* are the supported ways of handling state changes of unreachable checkables, i.e. checkables with a
+// failed parent in the dependency graph. If such state changes are muted or suppressed, the state events of the
+// failed parents carry the affected children within their "children" relation.
+const (
+	UnreachableSubmit   = "submit"   // Submit state changes like those of reachable checkables.
+	UnreachableMute     = "mute"     // Submit state changes, but mute them.
+	UnreachableSuppress = "suppress" // Do not submit problem state changes.
+)
+
+// CheckOutputSync* are the supported ways of keeping the messages of open incidents in sync with the check outputs.
+const (
+	CheckOutputSyncPoll   = "poll"   // Periodically compare the check outputs of all objects with open incidents.
+	CheckOutputSyncEvents = "events" // Update the message of an incident when the check output of its object changes.
+)
+
+// Options configure the Client in addition to the connection to Icinga Notifications defined by [source.Config].
+type Options struct {
+	// Unreachable is one of the Unreachable* constants.
+	Unreachable string `yaml:"unreachable" env:"UNREACHABLE" default:"submit"`
+
+	// CheckOutputSync is one of the CheckOutputSync* constants.
+	CheckOutputSync string `yaml:"check_output_sync" env:"CHECK_OUTPUT_SYNC" default:"poll"`
+
+	// CheckOutputSyncInterval is the polling interval, or the minimum interval between two updates of the same
+	// incident if driven by events.
+	CheckOutputSyncInterval time.Duration `yaml:"check_output_sync_interval" env:"CHECK_OUTPUT_SYNC_INTERVAL" default:"5m"`
+
+	// Filter can only be configured via YAML.
+	Filter Filter `yaml:"filter"`
+}
+
+// Validate checks constraints in the supplied options and returns an error if they are violated.
+func (o *Options) Validate() error {
+	switch o.Unreachable {
+	case UnreachableSubmit, UnreachableMute, UnreachableSuppress:
+	default:
+		return errors.Errorf("invalid unreachable mode %q, must be one of %q, %q or %q",
+			o.Unreachable, UnreachableSubmit, UnreachableMute, UnreachableSuppress)
+	}
+
+	switch o.CheckOutputSync {
+	case CheckOutputSyncPoll, CheckOutputSyncEvents:
+	default:
+		return errors.Errorf("invalid check output sync mode %q, must be either %q or %q",
+			o.CheckOutputSync, CheckOutputSyncPoll, CheckOutputSyncEvents)
+	}
+
+	if o.CheckOutputSyncInterval <= 0 {
+		return errors.New("check_output_sync_interval must be positive")
+	}
+
+	return errors.Wrap(o.Filter.Validate(), "invalid filter")
+}
+
 // Client is an Icinga Notifications compatible client implementation to push events to Icinga Notifications.
 //
 // A new Client should be created by the NewNotificationsClient function. New history entries can be submitted by
@@ -83,6 +140,21 @@ type Client struct {
 
 	// heartbeatOutCh is a channel used to send heartbeat signals to the HA controller.
 	heartbeatOutCh chan<- bool
+
+	options Options
+
+	// filter selects the objects whose events are submitted.
+	filter *objectFilter
+
+	// outputs updates the messages of open incidents if Options.CheckOutputSync is CheckOutputSyncEvents.
+	outputs *checkOutputUpdater
+
+	// outbox queues state changes until they are submitted by Client.SendOutbox.
+	outbox *outbox
+	// outboxWakeup signals Client.SendOutbox that new state changes have been queued.
+	outboxWakeup chan struct{}
+	// submittedMilli is the time in Unix milliseconds of the last successful Client.Submit.
+	submittedMilli atomic.Int64
 }
 
 // NewNotificationsClient creates a new Client connected to an existing database and logger.
@@ -91,6 +163,7 @@ func NewNotificationsClient(
 	rc *redis.Client,
 	logger *logging.Logger,
 	cfg source.Config,
+	options Options,
 	heartbeatOutCh chan<- bool,
 ) (*Client, error) {
 	notificationsClient, err := source.NewClient(cfg, "Icinga DB "+internal.Version.Version)
@@ -98,7 +171,7 @@ func NewNotificationsClient(
 		return nil, err
 	}
 
-	return &Client{
+	client := &Client{
 		Config: cfg,
 
 		db:     db,
@@ -108,7 +181,16 @@ func NewNotificationsClient(
 		redisClient:         rc,
 
 		heartbeatOutCh: heartbeatOutCh,
-	}, nil
+
+		options: options,
+
+		outbox:       &outbox{db: db},
+		outboxWakeup: make(chan struct{}, 1),
+	}
+	client.filter = newObjectFilter(options.Filter, client)
+	client.outputs = newCheckOutputUpdater(client)
+
+	return client, nil
 }
 
 // ClearIncidents clears the cached incidents previously populated by the [Client.ApplyDelta].
@@ -138,8 +220,12 @@ func (client *Client) ClearIncidents() {
 // errors encountered during the submission of events to the Icinga Notifications API and continue processing the
 // remaining events, but never returns an error for those.
 func (client *Client) ApplyDelta(ctx context.Context, delta *icingadb.Delta) error {
+	var objectType string
 	switch delta.Subject.Entity().(type) {
-	case *v1.HostState, *v1.ServiceState:
+	case *v1.HostState:
+		objectType = "host"
+	case *v1.ServiceState:
+		objectType = "service"
 	default:
 		return nil
 	}
@@ -159,6 +245,17 @@ func (client *Client) ApplyDelta(ctx context.Context, delta *icingadb.Delta) err
 		return nil
 	}
 
+	environment, ok := v1.EnvironmentFromContext(ctx)
+	if !ok {
+		panic("cannot get environment from context")
+	}
+
+	// States still queued in the outbox have not been submitted yet, so the corresponding incidents are outdated.
+	queued, err := client.outbox.latest(ctx, environment.Id, objectType)
+	if err != nil {
+		return errors.Wrap(err, "can't fetch queued states from Icinga Notifications outbox")
+	}
+
 	client.logger.Infof("Fetching %d entities of type %s from Redis for submission to Icinga Notifications",
 		len(delta.RedisSnapshot),
 		delta.Subject.Name())
@@ -174,18 +271,75 @@ func (client *Client) ApplyDelta(ctx context.Context, delta *icingadb.Delta) err
 	entities, rErrs := icingaredis.CreateEntities(ctx, delta.Subject.Factory(), pairs, 1)
 	com.ErrgroupReceive(g, rErrs)
 
+	// Evaluate the filter for bulks of entities, as looking up the attributes of each object one by one is slow.
+	preloaded := make(chan database.Entity)
+	g.Go(func() error {
+		defer close(preloaded)
+
+		for bulk := range com.Bulk(ctx, entities, objectFilterBulkSize, com.NeverSplit[database.Entity]) {
+			keys := make([]objectFilterKey, 0, len(bulk))
+			for _, entity := range bulk {
+				keys = append(keys, objectFilterKeyOf(entity))
+			}
+
+			if err := client.filter.preload(ctx, keys); err != nil {
+				return err
+			}
+
+			for _, entity := range bulk {
+				select {
+				case preloaded <- entity:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		return nil
+	})
+
 	g.Go(func() error {
+		// unselected contains the incidents of objects which are not selected by the filter (anymore).
+		var unselected []any
+
 		for {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case entity, ok := <-entities:
+			case entity, ok := <-preloaded:
 				if !ok {
+					if len(unselected) > 0 {
+						client.logger.Infof("Bulk closing %d incidents for objects of type %s not matching the filter",
+							len(unselected),
+							delta.Subject.Name())
+
+						client.closeIncidents(ctx, delta.Subject.Name(), unselected)
+					}
+
 					return nil
 				}
 
-				if incident, exists := client.incidentsByObjId[entity.ID().String()]; exists {
-					if same, err := HaveSameState(incident, entity); err != nil {
+				key := objectFilterKeyOf(entity)
+				if selected, err := client.filter.selected(ctx, key.hostId, key.serviceId); err != nil {
+					return err
+				} else if !selected {
+					if incident, exists := client.incidentsByObjId[entity.ID().String()]; exists {
+						unselected = append(unselected, incident.ObjectTags)
+					}
+
+					continue
+				}
+
+				if queuedState, exists := queued[entity.ID().String()]; exists {
+					// The queued state is yet to be submitted by Client.SendOutbox. If it is the same as the
+					// current one, queueing the current state as well would only submit it twice.
+					if same, err := haveSameQueuedState(queuedState, entity); err != nil {
+						return err
+					} else if same {
+						continue
+					}
+				} else if incident, exists := client.incidentsByObjId[entity.ID().String()]; exists {
+					if same, err := client.haveSameState(incident, entity); err != nil {
 						return err
 					} else if same {
 						// Same state, but not necessarily the same output/message, and since we have a separate
@@ -194,14 +348,11 @@ func (client *Client) ApplyDelta(ctx context.Context, delta *icingadb.Delta) err
 					}
 				}
 
-				// If the entity is new or has a different state than the existing incident, submit it to Icinga
-				// Notifications via the regular /process-event endpoint. Though, in case the API isn't healthy,
-				// it will retry it endlessly, so set a timeout to avoid blocking the entire config dump.
-				func() {
-					ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-					defer cancel()
-					_ = client.Submit(ctx, entity)
-				}()
+				// If the entity is new or has a different state than the existing incident, queue it for submission
+				// to Icinga Notifications via the regular /process-event endpoint by Client.SendOutbox.
+				if err := client.Enqueue(ctx, entity); err != nil {
+					return err
+				}
 			}
 		}
 	})
@@ -232,25 +383,37 @@ func (client *Client) ApplyDelta(ctx context.Context, delta *icingadb.Delta) err
 			len(filter),
 			delta.Subject.Name())
 
-		attrs := source.ModifiableIncidentAttrs{Close: types.MakeBool(true)}
-		if err := client.notificationsClient.ModifyIncidents(ctx, attrs, filter); err != nil {
-			client.logger.Errorw("Failed to bulk close obsolete incidents for deleted objects",
-				zap.String("entity", delta.Subject.Name()),
-				zap.Int("count", len(filter)),
-				zap.String("error", err.Error()))
-		}
+		client.closeIncidents(ctx, delta.Subject.Name(), filter)
+
 		return nil
 	})
 
 	return g.Wait()
 }
 
-// SyncCheckOutputs periodically syncs the check outputs of all hosts and services to the Icinga Notifications API.
+// closeIncidents closes the incidents matching any of the given object tags. Errors are logged only.
+func (client *Client) closeIncidents(ctx context.Context, entity string, filter []any) {
+	attrs := source.ModifiableIncidentAttrs{Close: types.MakeBool(true)}
+	if err := client.notificationsClient.ModifyIncidents(ctx, attrs, filter); err != nil {
+		client.logger.Errorw("Failed to bulk close incidents",
+			zap.String("entity", entity),
+			zap.Int("count", len(filter)),
+			zap.String("error", err.Error()))
+	}
+}
+
+// SyncCheckOutputs keeps the messages of the open incidents in Icinga Notifications in sync with the check outputs
+// of their hosts and services until the context is canceled.
 //
-// This function fetches the current incidents from the Icinga Notifications API, computes the corresponding
-// object IDs, retrieves all host and service states matching those IDs from Redis, and updates the check
-// outputs in Icinga Notifications if they have changed since the last sync.
+// By default, this function periodically fetches the current incidents from the Icinga Notifications API, computes
+// the corresponding object IDs, retrieves all host and service states matching those IDs from Redis, and updates the
+// check outputs in Icinga Notifications if they have changed since the last sync. If Options.CheckOutputSync is
+// CheckOutputSyncEvents, the check outputs are instead updated as they change, see [Client.UpdateCheckOutput].
 func (client *Client) SyncCheckOutputs(ctx context.Context) error {
+	if client.options.CheckOutputSync == CheckOutputSyncEvents {
+		return client.outputs.run(ctx)
+	}
+
 	lastSync := time.Now()
 
 	// modify is a helper function to update the check output of a given state in Icinga Notifications.
@@ -259,54 +422,10 @@ func (client *Client) SyncCheckOutputs(ctx context.Context) error {
 			return nil // Skip state updates that haven't changed since the last sync, or that don't have a valid output.
 		}
 
-		var sb strings.Builder
-		sb.Grow(len(s.Output.String) + len(s.LongOutput.String) + 1)
-		sb.WriteString(s.Output.String)
-		if !s.LongOutput.IsZero() {
-			sb.WriteRune('\n')
-			sb.WriteString(s.LongOutput.String)
-		}
-
-		filter := make(map[string]any)
-		for k, v := range idTags {
-			filter[k] = v
-		}
-		if _, exists := idTags["service"]; !exists {
-			// Only match host incidents, not service incidents that have the same host name.
-			filter["service"] = nil
-		}
-
-		attrs := source.ModifiableIncidentAttrs{Message: types.MakeString(sb.String())}
-		return retry.WithBackoff(
-			ctx,
-			func(ctx context.Context) error { return client.notificationsClient.ModifyIncidents(ctx, attrs, filter) },
-			func(err error) bool { return true },
-			backoff.DefaultBackoff,
-			retry.Settings{
-				OnSuccess: func(elapsed time.Duration, attempt uint64, err error) {
-					client.sendHeartbeat(true)
-					if attempt > 1 {
-						client.logger.Debugw("Successfully updated incident status after retries",
-							zap.String("object_type", types.Name(s)),
-							zap.Duration("elapsed", elapsed),
-							zap.Uint64("attempt", attempt),
-							zap.String("error", err.Error()))
-					}
-				},
-				OnRetryableError: func(elapsed time.Duration, attempt uint64, err, lastErr error) {
-					client.sendHeartbeat(false)
-					if lastErr == nil || err.Error() != lastErr.Error() {
-						client.logger.Errorw("Failed to update incident status",
-							zap.String("object_type", types.Name(s)),
-							zap.Duration("elapsed", elapsed),
-							zap.Error(lastErr))
-					}
-				},
-			},
-		)
+		return client.modifyIncidentMessage(ctx, checkOutputMessage(s), idTags, types.Name(s))
 	}
 
-	const interval = 5 * time.Minute
+	interval := client.options.CheckOutputSyncInterval
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -357,6 +476,61 @@ func (client *Client) SyncCheckOutputs(ctx context.Context) error {
 	}
 }
 
+// checkOutputMessage returns the incident message for the check output of the given state.
+func checkOutputMessage(s *v1.State) string {
+	var sb strings.Builder
+	sb.Grow(len(s.Output.String) + len(s.LongOutput.String) + 1)
+	sb.WriteString(s.Output.String)
+	if !s.LongOutput.IsZero() {
+		sb.WriteRune('\n')
+		sb.WriteString(s.LongOutput.String)
+	}
+
+	return sb.String()
+}
+
+// modifyIncidentMessage updates the message of the open incident of the object identified by the given tags in
+// Icinga Notifications, retrying until the context is canceled. The objectType is only used for logging.
+func (client *Client) modifyIncidentMessage(ctx context.Context, message string, idTags map[string]string, objectType string) error {
+	filter := make(map[string]any)
+	for k, v := range idTags {
+		filter[k] = v
+	}
+	if _, exists := idTags["service"]; !exists {
+		// Only match host incidents, not service incidents that have the same host name.
+		filter["service"] = nil
+	}
+
+	attrs := source.ModifiableIncidentAttrs{Message: types.MakeString(message)}
+	return retry.WithBackoff(
+		ctx,
+		func(ctx context.Context) error { return client.notificationsClient.ModifyIncidents(ctx, attrs, filter) },
+		func(err error) bool { return true },
+		backoff.DefaultBackoff,
+		retry.Settings{
+			OnSuccess: func(elapsed time.Duration, attempt uint64, err error) {
+				client.sendHeartbeat(true)
+				if attempt > 1 {
+					client.logger.Debugw("Successfully updated incident status after retries",
+						zap.String("object_type", objectType),
+						zap.Duration("elapsed", elapsed),
+						zap.Uint64("attempt", attempt),
+						zap.String("error", err.Error()))
+				}
+			},
+			OnRetryableError: func(elapsed time.Duration, attempt uint64, err, lastErr error) {
+				client.sendHeartbeat(false)
+				if lastErr == nil || err.Error() != lastErr.Error() {
+					client.logger.Errorw("Failed to update incident status",
+						zap.String("object_type", objectType),
+						zap.Duration("elapsed", elapsed),
+						zap.Error(lastErr))
+				}
+			},
+		},
+	)
+}
+
 // retrieveEnvironmentIncidents fetches all incidents for the current environment from the Icinga Notifications API.
 //
 // The incidents are returned as a map of object IDs to incidents. The object IDs are generated based on the
@@ -469,6 +643,9 @@ func (client *Client) buildCommonEvent(
 	}, nil
 }
 
+// errSkippedHistoryEntry is returned when a history entry is deliberately not submitted.
+var errSkippedHistoryEntry = errors.New("history entry is skipped")
+
 // errNonVolatileNonHardState is returned when a non-hard state change is attempted to be submitted for a non-volatile checkable.
 var errNonVolatileNonHardState = errors.New("non-hard state change for non-volatile checkable")
 
@@ -512,7 +689,8 @@ func (client *Client) buildStateEvent(ctx context.Context, s *v1.State, hostId,
 	inDowntime := s.InDowntime.Valid && s.InDowntime.Bool
 	isAcked := s.IsAcknowledged.Valid && s.IsAcknowledged.Bool
 	isFlapping := s.IsFlapping.Valid && s.IsFlapping.Bool
-	ev.Muted = types.MakeBool(inDowntime || isAcked || isFlapping)
+	isUnreachable := client.mutesUnreachable(s)
+	ev.Muted = types.MakeBool(inDowntime || isAcked || isFlapping || isUnreachable)
 	if ev.IsMuted() {
 		ev.MutedReason = "Checkable is muted due to"
 		if inDowntime {
@@ -528,6 +706,11 @@ func (client *Client) buildStateEvent(ctx context.Context, s *v1.State, hostId,
 		} else if isFlapping {
 			ev.MutedReason += " flapping state"
 		}
+		if isUnreachable && (inDowntime || isAcked || isFlapping) {
+			ev.MutedReason += ", and being unreachable"
+		} else if isUnreachable {
+			ev.MutedReason += " being unreachable due to a failed parent"
+		}
 		ev.MutedReason += "."
 	} else {
 		ev.MutedReason = "Checkable is not muted (no active downtime, no acknowledgement, and not flapping)"
@@ -543,29 +726,44 @@ func (client *Client) buildStateEvent(ctx context.Context, s *v1.State, hostId,
 		ev.Notify = types.MakeBool(true)
 	}
 
+	if client.options.Unreachable == UnreachableMute || client.options.Unreachable == UnreachableSuppress {
+		if ev.Severity != event.SeverityOK && !isUnreachableState(s) {
+			// This checkable may be the root cause of its children becoming unreachable, whose own events are muted
+			// or suppressed. Attach them, so that the affected children are visible from this single event.
+			if err := ev.relations.complete(ctx, "$.children[*].name"); err != nil {
+				return nil, errors.Wrapf(err, "cannot fetch children of %q,%q", hostId, serviceId)
+			}
+		}
+	}
+
 	return ev, nil
 }
 
 // buildDowntimeHistoryMetaEvent from a downtime history entry.
 func (client *Client) buildDowntimeHistoryMetaEvent(ctx context.Context, h *v1history.DowntimeHistoryMeta) (*fetchableEvent, error) {
-	defer func() { panic("downtime history event generation is incomplete and not yet implemented") }()
-
 	ev, err := client.buildCommonEvent(ctx, h.HostId, h.ServiceId)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot build event for %q,%q", h.HostId, h.ServiceId)
 	}
 
+	ev.Tags["environment"] = h.EnvironmentId.String()
+
 	switch h.EventType {
 	case "downtime_start":
+		ev.Type = event.TypeDowntimeStart
+		ev.Username = h.Author
 		ev.Message = h.Comment
 
 	case "downtime_end":
 		if h.HasBeenCancelled.Valid && h.HasBeenCancelled.Bool {
+			ev.Type = event.TypeDowntimeRemoved
 			ev.Message = "Downtime was cancelled"
 			if h.CancelledBy.Valid {
+				ev.Username = h.CancelledBy.String
 				ev.Message += " (cancelled by " + h.CancelledBy.String + ")"
 			}
 		} else {
+			ev.Type = event.TypeDowntimeEnd
 			ev.Message = "Downtime expired"
 		}
 
@@ -578,18 +776,20 @@ func (client *Client) buildDowntimeHistoryMetaEvent(ctx context.Context, h *v1hi
 
 // buildFlappingHistoryEvent from a flapping history entry.
 func (client *Client) buildFlappingHistoryEvent(ctx context.Context, h *v1history.FlappingHistory) (*fetchableEvent, error) {
-	defer func() { panic("flapping history event generation is incomplete and not yet implemented") }()
-
 	ev, err := client.buildCommonEvent(ctx, h.HostId, h.ServiceId)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot build event for %q,%q", h.HostId, h.ServiceId)
 	}
 
+	ev.Tags["environment"] = h.EnvironmentId.String()
+
 	if h.PercentStateChangeEnd.Valid {
+		ev.Type = event.TypeFlappingEnd
 		ev.Message = fmt.Sprintf(
 			"Checkable stopped flapping (Current flapping value %.2f%% < low threshold %.2f%%)",
 			h.PercentStateChangeEnd.Float64, h.FlappingThresholdLow)
 	} else if h.PercentStateChangeStart.Valid {
+		ev.Type = event.TypeFlappingStart
 		ev.Message = fmt.Sprintf(
 			"Checkable started flapping (Current flapping value %.2f%% > high threshold %.2f%%)",
 			h.PercentStateChangeStart.Float64, h.FlappingThresholdHigh)
@@ -602,19 +802,25 @@ func (client *Client) buildFlappingHistoryEvent(ctx context.Context, h *v1histor
 
 // buildAcknowledgementHistoryEvent from an acknowledgment history entry.
 func (client *Client) buildAcknowledgementHistoryEvent(ctx context.Context, h *v1history.AcknowledgementHistory) (*fetchableEvent, error) {
-	defer func() { panic("acknowledgement history event generation is incomplete and not yet implemented") }()
-
 	ev, err := client.buildCommonEvent(ctx, h.HostId, h.ServiceId)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot build event for %q,%q", h.HostId, h.ServiceId)
 	}
 
+	ev.Tags["environment"] = h.EnvironmentId.String()
+
 	if !h.ClearTime.Time().IsZero() {
+		ev.Type = event.TypeAcknowledgementCleared
 		ev.Message = "Acknowledgement was cleared"
 		if h.ClearedBy.Valid {
+			ev.Username = h.ClearedBy.String
 			ev.Message += " (cleared by " + h.ClearedBy.String + ")"
 		}
 	} else if !h.SetTime.Time().IsZero() {
+		ev.Type = event.TypeAcknowledgementSet
+		if h.Author.Valid {
+			ev.Username = h.Author.String
+		}
 		if h.Comment.Valid {
 			ev.Message = h.Comment.String
 		} else {
@@ -627,28 +833,103 @@ func (client *Client) buildAcknowledgementHistoryEvent(ctx context.Context, h *v
 	return ev, nil
 }
 
-// Submit this [database.Entity] to the Icinga Notifications API.
+// buildCommentHistoryEvent from a comment history entry.
 //
-// Based on the entity's type, a different kind of event will be constructed. The event will be sent to the API in a
-// blocking fashion and will be retried with an exponential backoff in case of retryable errors until a non-retryable
-// error occurs (like ctx cancellation) or the deadline is exceeded. In other words, when this method returns an error,
-// then it usually means that there's nothing it can do anymore to successfully submit the event, thus it should be
-// treated as a fatal error.
+// Comments of acknowledgements are skipped, as they are already part of the acknowledgement events.
+func (client *Client) buildCommentHistoryEvent(ctx context.Context, h *v1history.CommentHistory) (*fetchableEvent, error) {
+	if h.EntryType == "ack" {
+		return nil, errSkippedHistoryEntry
+	}
+
+	ev, err := client.buildCommonEvent(ctx, h.HostId, h.ServiceId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build event for %q,%q", h.HostId, h.ServiceId)
+	}
+
+	ev.Tags["environment"] = h.EnvironmentId.String()
+	ev.Type = event.TypeCustom
+
+	comment := &commentRelation{Author: h.Author, Text: h.Comment}
+	if h.HasBeenRemoved.Valid && h.HasBeenRemoved.Bool {
+		ev.Message = "Comment was removed"
+		if h.RemovedBy.Valid {
+			comment.RemovedBy = h.RemovedBy.String
+			ev.Username = h.RemovedBy.String
+			ev.Message += " (removed by " + h.RemovedBy.String + ")"
+		}
+	} else {
+		ev.Username = h.Author
+		ev.Message = h.Comment
+	}
+	ev.setComment(comment)
+
+	return ev, nil
+}
+
+// notificationHistory is a notification history entry including the IDs of the notified users, which are only part
+// of the history stream message and written to a separate table by the history sync.
+type notificationHistory struct {
+	v1history.NotificationHistory `json:",inline"`
+	UsersNotifiedIds              types.String `json:"users_notified_ids"`
+}
+
+// buildNotificationHistoryEvent from a notification history entry, i.e. a notification sent by Icinga 2 itself.
+func (client *Client) buildNotificationHistoryEvent(ctx context.Context, h *notificationHistory) (*fetchableEvent, error) {
+	ev, err := client.buildCommonEvent(ctx, h.HostId, h.ServiceId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build event for %q,%q", h.HostId, h.ServiceId)
+	}
+
+	ev.Tags["environment"] = h.EnvironmentId.String()
+	ev.Type = event.TypeCustom
+	ev.Username = h.Author
+
+	notification := &notificationRelation{Type: string(h.Type), Author: h.Author, Text: h.Text.String}
+
+	if h.UsersNotifiedIds.Valid {
+		var userIds []types.Binary
+		if err := types.UnmarshalJSON([]byte(h.UsersNotifiedIds.String), &userIds); err != nil {
+			return nil, errors.Wrap(err, "cannot parse notified users")
+		}
+
+		users, err := client.fetchObjectsFromRedis(ctx, "user", userIds...)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot fetch notified users")
+		}
+
+		for _, user := range users {
+			notification.Users = append(notification.Users, user)
+		}
+		slices.SortFunc(notification.Users, func(a, b *icingaObject) int { return strings.Compare(a.Name, b.Name) })
+	}
+
+	ev.Message = fmt.Sprintf("Icinga 2 sent a %s notification to %d user(s)",
+		strings.ReplaceAll(string(h.Type), "_", " "), h.UsersNotified)
+	if h.Text.Valid && h.Text.String != "" {
+		ev.Message += ": " + h.Text.String
+	}
+	ev.setNotification(notification)
+
+	return ev, nil
+}
+
+// canIgnoreStateUpdate reports whether the given state is irrelevant for Icinga Notifications.
+func canIgnoreStateUpdate(s *v1.State) bool {
+	// Ignore PENDING -> OK, otherwise we'll have a bunch of incidents that are be closed immediately.
+	// Also ignore any Pending states (99), as these are not relevant for notifications.
+	return s.HardState == 99 || (s.HardState == 0 && s.PreviousHardState == 99)
+}
+
+// buildEvent builds the event for the given [database.Entity] based on its type.
 //
-// Note that this function is used as [icingadb.RUUpsertFunc] for the runtime updates pipeline, so its signature must
-// match the [icingadb.RUUpsertFunc] type.
-func (client *Client) Submit(ctx context.Context, entity database.Entity) error {
+// Returns nil if there is nothing to submit for the entity, e.g. because it is of an unrelated type or the event
+// can't be built. The latter is logged, as retrying won't help.
+func (client *Client) buildEvent(ctx context.Context, entity database.Entity) *fetchableEvent {
 	var (
 		ev       *fetchableEvent
 		eventErr error
 	)
 
-	canIgnoreStateUpdate := func(s *v1.State) bool {
-		// Ignore PENDING -> OK, otherwise we'll have a bunch of incidents that are be closed immediately.
-		// Also ignore any Pending states (99), as these are not relevant for notifications.
-		return s.HardState == 99 || (s.HardState == 0 && s.PreviousHardState == 99)
-	}
-
 	switch h := entity.(type) {
 	case *v1history.AcknowledgementHistory:
 		ev, eventErr = client.buildAcknowledgementHistoryEvent(ctx, h)
@@ -659,6 +940,12 @@ func (client *Client) Submit(ctx context.Context, entity database.Entity) error
 	case *v1history.FlappingHistory:
 		ev, eventErr = client.buildFlappingHistoryEvent(ctx, h)
 
+	case *v1history.CommentHistory:
+		ev, eventErr = client.buildCommentHistoryEvent(ctx, h)
+
+	case *notificationHistory:
+		ev, eventErr = client.buildNotificationHistoryEvent(ctx, h)
+
 	case *v1.HostState:
 		if canIgnoreStateUpdate(&h.State) {
 			return nil
@@ -681,7 +968,7 @@ func (client *Client) Submit(ctx context.Context, entity database.Entity) error
 	}
 
 	if eventErr != nil {
-		if !errors.Is(eventErr, errNonVolatileNonHardState) {
+		if !errors.Is(eventErr, errNonVolatileNonHardState) && !errors.Is(eventErr, errSkippedHistoryEntry) {
 			client.logger.Errorw("Cannot build event for entity, skipping submission",
 				zap.String("type", fmt.Sprintf("%T", entity)),
 				zap.Error(eventErr))
@@ -702,6 +989,24 @@ func (client *Client) Submit(ctx context.Context, entity database.Entity) error
 		return nil
 	}
 
+	return ev
+}
+
+// Submit this [database.Entity] to the Icinga Notifications API.
+//
+// Based on the entity's type, a different kind of event will be constructed. The event will be sent to the API in a
+// blocking fashion and will be retried with an exponential backoff in case of retryable errors until a non-retryable
+// error occurs (like ctx cancellation) or the deadline is exceeded. In other words, when this method returns an error,
+// then it usually means that there's nothing it can do anymore to successfully submit the event, thus it should be
+// treated as a fatal error.
+//
+// State changes of the runtime updates pipeline are not submitted directly, but queued by [Client.Enqueue] first.
+func (client *Client) Submit(ctx context.Context, entity database.Entity) error {
+	ev := client.buildEvent(ctx, entity)
+	if ev == nil {
+		return nil
+	}
+
 	attributes := client.DefaultRelations
 	return retry.WithBackoff(
 		ctx,
@@ -730,7 +1035,9 @@ func (client *Client) Submit(ctx context.Context, entity database.Entity) error
 		retry.Settings{
 			OnSuccess: func(elapsed time.Duration, attempt uint64, lastErr error) {
 				client.sendHeartbeat(true)
-				telemetry.Stats.NotificationSync.Add(1)
+				client.submittedMilli.Store(time.Now().UnixMilli())
+				telemetry.StatsFromContext(ctx).NotificationSync.Add(1)
+				client.outputs.observe(entity, ev)
 
 				client.logger.Debugw("Successfully submitted event to Icinga Notifications",
 					zap.String("event", ev.Name),
@@ -753,42 +1060,303 @@ func (client *Client) Submit(ctx context.Context, entity database.Entity) error
 	)
 }
 
-// SyncExtraStages returns a map of history sync keys to [history.StageFunc] to be used for [history.Sync].
+// Enqueue queues the given host or service state in the outbox to be submitted to Icinga Notifications by
+// [Client.SendOutbox]. All other entities, states irrelevant for Icinga Notifications and states of objects not
+// selected by the filter are ignored.
 //
-// Passing the return value of this method as the extraStages parameter to [history.Sync] results in forwarding events
-// from the Icinga DB history stream to Icinga Notifications after being resorted via the StreamSorter.
-func (client *Client) SyncExtraStages(ctx context.Context) map[string]history.StageFunc {
-	var syncKeyStructPtrs = map[string]any{
-		history.SyncPipelineAcknowledgement: (*v1history.AcknowledgementHistory)(nil),
-		history.SyncPipelineDowntime:        (*v1history.DowntimeHistoryMeta)(nil),
-		history.SyncPipelineFlapping:        (*v1history.FlappingHistory)(nil),
+// Temporary database and Redis errors are retried. As a state that can't be queued must not stop the runtime updates,
+// it is logged and skipped if that fails permanently or the retries time out. Only canceling ctx results in an error.
+//
+// Note that this function is used as [icingadb.RUUpsertFunc] for the runtime updates pipeline, so its signature must
+// match the [icingadb.RUUpsertFunc] type.
+func (client *Client) Enqueue(ctx context.Context, entity database.Entity) error {
+	var hostId, serviceId types.Binary
+	switch s := entity.(type) {
+	case *v1.HostState:
+		if canIgnoreStateUpdate(&s.State) {
+			return nil
+		}
+		hostId = s.HostId
+	case *v1.ServiceState:
+		if canIgnoreStateUpdate(&s.State) {
+			return nil
+		}
+		hostId, serviceId = s.HostId, s.ServiceId
+	default:
+		return nil
 	}
 
-	sorterCallbackFn := func(msg redis.XMessage, key string) bool {
-		makeEntity := func(key string, values map[string]any) (database.Entity, error) {
-			structPtr, ok := syncKeyStructPtrs[key]
-			if !ok {
-				return nil, fmt.Errorf("key is not part of keyStructPtrs")
+	var queued bool
+	err := retry.WithBackoff(
+		ctx,
+		func(ctx context.Context) error {
+			if selected, err := client.filter.selected(ctx, hostId, serviceId); err != nil {
+				return errors.Wrap(err, "can't evaluate Icinga Notifications filter")
+			} else if !selected {
+				return nil
 			}
 
-			structifier := structify.MakeMapStructifier(
-				reflect.TypeOf(structPtr).Elem(),
-				"json",
-				contracts.SafeInit)
-			val, err := structifier(values)
+			if suppressed, err := client.suppressesUnreachable(ctx, entity); err != nil {
+				return err
+			} else if suppressed {
+				return nil
+			}
+
+			if err := client.outbox.enqueue(ctx, entity); err != nil {
+				return errors.Wrap(err, "can't queue state in Icinga Notifications outbox")
+			}
+
+			queued = true
+
+			return nil
+		},
+		retry.Retryable,
+		backoff.DefaultBackoff,
+		retry.Settings{
+			Timeout: retry.DefaultTimeout,
+			OnRetryableError: func(elapsed time.Duration, attempt uint64, err, lastErr error) {
+				if lastErr == nil || err.Error() != lastErr.Error() {
+					client.logger.Warnw("Can't queue state in Icinga Notifications outbox. Retrying",
+						zap.String("object_id", entity.ID().String()),
+						zap.Duration("elapsed", elapsed),
+						zap.Uint64("attempt", attempt),
+						zap.Error(err))
+				}
+			},
+		},
+	)
+	if err != nil {
+		if utils.IsContextCanceled(err) {
+			return err
+		}
+
+		client.logger.Errorw("Skipping state which can't be queued in Icinga Notifications outbox",
+			zap.String("object_id", entity.ID().String()),
+			zap.Error(err))
+
+		return nil
+	}
+
+	if !queued {
+		return nil
+	}
+
+	select {
+	case client.outboxWakeup <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+const (
+	// outboxBatchSize is the number of outbox entries fetched at once by Client.SendOutbox.
+	outboxBatchSize = 1 << 10
+
+	// outboxSubmitTimeout limits the time for submitting a single queued state.
+	outboxSubmitTimeout = 10 * time.Second
+
+	// outboxMaxFailures is the number of consecutive failed submissions after which Client.SendOutbox assumes
+	// Icinga Notifications to be unavailable and waits before trying again.
+	outboxMaxFailures = 3
+
+	// outboxMaxAttempts is the number of failed submissions of a queued state after which Client.SendOutbox drops it,
+	// provided that Icinga Notifications accepted other states in the meantime.
+	outboxMaxAttempts = 10
+
+	// outboxRetryInterval is the interval in which Client.SendOutbox retries failed submissions.
+	outboxRetryInterval = 10 * time.Second
+)
+
+// SendOutbox submits the states queued in the outbox by [Client.Enqueue] to Icinga Notifications until the context
+// is canceled.
+//
+// The states of each object are submitted in the order they were queued. If a submission fails, the number of
+// attempts and the error are recorded in the outbox and the remaining states of the same object are held back
+// until it is retried. Successfully submitted states are removed from the outbox. A state which failed
+// outboxMaxAttempts times, although Icinga Notifications accepted other states since its previous attempt,
+// is logged and dropped, so that it doesn't hold back its object forever. As the outbox is stored in the
+// database, the states queued by an Icinga DB instance are submitted by the other one after an HA takeover.
+func (client *Client) SendOutbox(ctx context.Context) error {
+	environment, ok := v1.EnvironmentFromContext(ctx)
+	if !ok {
+		panic("cannot get environment from context")
+	}
+
+	ticker := time.NewTicker(outboxRetryInterval)
+	defer ticker.Stop()
+
+	var pass outboxPass
+	for {
+		if err := client.drainOutbox(ctx, environment.Id, &pass); err != nil {
+			return err
+		}
+
+		select {
+		case <-client.outboxWakeup:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// outboxPass is the progress of a pass through the outbox, which spans multiple calls of Client.drainOutbox
+// if it stops after outboxMaxFailures. Continuing where it stopped ensures that failing states at the beginning
+// of the outbox don't prevent the submission of the following ones.
+type outboxPass struct {
+	// lastId is the ID of the last processed entry.
+	lastId uint64
+
+	// blocked contains the objects whose states are held back due to a failed submission.
+	blocked map[string]struct{}
+}
+
+// drainOutbox submits the states queued in the outbox for the given environment, continuing the given pass.
+func (client *Client) drainOutbox(ctx context.Context, environmentId types.Binary, pass *outboxPass) error {
+	if pass.blocked == nil {
+		pass.blocked = make(map[string]struct{})
+	}
+
+	var failures int
+
+	for {
+		entries, err := client.outbox.pending(ctx, environmentId, pass.lastId, outboxBatchSize)
+		if err != nil {
+			return errors.Wrap(err, "can't fetch queued states from Icinga Notifications outbox")
+		}
+
+		for _, e := range entries {
+			pass.lastId = e.Id
+
+			if _, ok := pass.blocked[e.ObjectId.String()]; ok {
+				continue
+			}
+
+			entity, err := e.entity()
 			if err != nil {
-				return nil, errors.Wrapf(err, "can't structify values %#v for %q", values, key)
+				// This can't succeed on retry either.
+				client.logger.Errorw("Dropping malformed state from Icinga Notifications outbox",
+					zap.Uint64("id", e.Id),
+					zap.Error(err))
+			} else {
+				submitCtx, cancelSubmitCtx := context.WithTimeout(ctx, outboxSubmitTimeout)
+				err = client.Submit(submitCtx, entity)
+				cancelSubmitCtx()
+
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+
+					if e.droppable(client.submittedMilli.Load()) {
+						client.logger.Errorw("Dropping state from Icinga Notifications outbox after failed attempts",
+							zap.Uint64("id", e.Id),
+							zap.String("object_id", e.ObjectId.String()),
+							zap.Uint32("attempts", e.Attempts+1),
+							zap.String("state", e.State),
+							zap.Error(err))
+					} else {
+						pass.blocked[e.ObjectId.String()] = struct{}{}
+
+						if err := client.outbox.recordFailure(ctx, e.Id, err); err != nil {
+							return errors.Wrap(err, "can't record failed submission in Icinga Notifications outbox")
+						}
+
+						client.logger.Debugw("Holding back states of object in Icinga Notifications outbox",
+							zap.String("object_id", e.ObjectId.String()),
+							zap.Uint32("attempts", e.Attempts+1),
+							zap.Error(err))
+
+						if failures++; failures >= outboxMaxFailures {
+							return nil
+						}
+
+						continue
+					}
+				}
 			}
 
-			entity, ok := val.(database.Entity)
-			if !ok {
-				return nil, fmt.Errorf("structifier returned %T which does not implement database.Entity", val)
+			failures = 0
+
+			if err := client.outbox.remove(ctx, e.Id); err != nil {
+				return errors.Wrap(err, "can't remove submitted state from Icinga Notifications outbox")
 			}
+		}
 
-			return entity, nil
+		if len(entries) < outboxBatchSize {
+			// The pass is complete, so the next one starts at the beginning of the outbox again.
+			*pass = outboxPass{}
+
+			return nil
 		}
+	}
+}
+
+// historyStructPtrs maps the keys of the history sync pipelines relevant for events to their entity types.
+var historyStructPtrs = map[string]any{
+	history.SyncPipelineAcknowledgement: (*v1history.AcknowledgementHistory)(nil),
+	history.SyncPipelineDowntime:        (*v1history.DowntimeHistoryMeta)(nil),
+	history.SyncPipelineFlapping:        (*v1history.FlappingHistory)(nil),
+	history.SyncPipelineComment:         (*v1history.CommentHistory)(nil),
+	history.SyncPipelineNotification:    (*notificationHistory)(nil),
+}
 
-		entity, err := makeEntity(key, msg.Values)
+// makeHistoryEntity creates the [database.Entity] of the history sync pipeline key out of a history stream message.
+func makeHistoryEntity(key string, values map[string]any) (database.Entity, error) {
+	structPtr, ok := historyStructPtrs[key]
+	if !ok {
+		return nil, fmt.Errorf("key is not part of historyStructPtrs")
+	}
+
+	structifier := structify.MakeMapStructifier(
+		reflect.TypeOf(structPtr).Elem(),
+		"json",
+		contracts.SafeInit)
+	val, err := structifier(values)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't structify values %#v for %q", values, key)
+	}
+
+	entity, ok := val.(database.Entity)
+	if !ok {
+		return nil, fmt.Errorf("structifier returned %T which does not implement database.Entity", val)
+	}
+
+	return entity, nil
+}
+
+// historySubmitTimeout limits the time for submitting a single history event, so that the responsibility is checked
+// again regularly while the Icinga Notifications API is unavailable.
+const historySubmitTimeout = time.Minute
+
+// SyncExtraStages returns a map of history sync keys to [history.StageFunc] to be used for [history.Sync].
+//
+// Passing the return value of this method as the extraStages parameter to [history.Sync] results in forwarding events
+// from the Icinga DB history stream to Icinga Notifications after being resorted via the StreamSorter.
+//
+// Only the responsible Icinga DB instance, as reported by isResponsible, submits history events. The history stream
+// entries are only forwarded to the next stage, and thus deleted from Redis, after they have been submitted, either by
+// this instance or, as recorded by the checkpoint in the database, by another one. So if this instance is not
+// responsible, it retains the entries until the responsible instance has submitted them or until it takes over itself.
+func (client *Client) SyncExtraStages(ctx context.Context, isResponsible func() bool) map[string]history.StageFunc {
+	checkpoint := &historyCheckpoint{db: client.db}
+
+	type historyEvent struct {
+		EventId       types.Binary `json:"event_id"`
+		EnvironmentId types.Binary `json:"environment_id"`
+		HostId        types.Binary `json:"host_id"`
+		ServiceId     types.Binary `json:"service_id"`
+	}
+
+	eventStructifier := structify.MakeMapStructifier(reflect.TypeFor[historyEvent](), "json", contracts.SafeInit)
+
+	// uncommitted is the ID of the history event which has been submitted, but not yet recorded in the checkpoint.
+	// It is only accessed by the callback, which the StreamSorter never executes concurrently.
+	var uncommitted types.Binary
+
+	sorterCallbackFn := func(msg redis.XMessage, key string) bool {
+		entity, err := makeHistoryEntity(key, msg.Values)
 		if err != nil {
 			client.logger.Errorw("Failed to create database.Entity out of Redis stream message",
 				zap.Error(err),
@@ -797,16 +1365,99 @@ func (client *Client) SyncExtraStages(ctx context.Context) map[string]history.St
 			return false
 		}
 
-		if err := client.Submit(ctx, entity); err == nil {
+		rawEvent, err := eventStructifier(msg.Values)
+		if err != nil {
+			client.logger.Errorw("Failed to read history event ID out of Redis stream message",
+				zap.Error(err),
+				zap.String("key", key),
+				zap.String("id", msg.ID))
+			return false
+		}
+		ev, ok := rawEvent.(*historyEvent)
+		if !ok {
+			client.logger.Errorw("Structifier returned unexpected type for Redis stream message",
+				zap.String("type", fmt.Sprintf("%T", rawEvent)),
+				zap.String("key", key),
+				zap.String("id", msg.ID))
+			return false
+		}
+
+		if selected, err := client.filter.selected(ctx, ev.HostId, ev.ServiceId); err != nil {
+			client.logger.Errorw("Cannot evaluate filter for history event",
+				zap.String("key", key),
+				zap.String("event_id", ev.EventId.String()),
+				zap.Error(err))
+			return false
+		} else if !selected {
 			return true
 		}
-		return false
+
+		if !bytes.Equal(uncommitted, ev.EventId) {
+			submitted, err := checkpoint.isSubmitted(ctx, ev.EventId)
+			if err != nil {
+				client.logger.Errorw("Cannot check whether history event was already submitted",
+					zap.String("key", key),
+					zap.String("event_id", ev.EventId.String()),
+					zap.Error(err))
+				return false
+			}
+
+			if submitted {
+				client.logger.Debugw("Skipping history event already submitted to Icinga Notifications",
+					zap.String("key", key),
+					zap.String("event_id", ev.EventId.String()))
+				return true
+			}
+
+			if !isResponsible() {
+				// Retain the entry until the responsible instance has submitted it or this instance takes over.
+				return false
+			}
+
+			submitCtx, cancelSubmitCtx := context.WithTimeout(ctx, historySubmitTimeout)
+			err = client.Submit(submitCtx, entity)
+			cancelSubmitCtx()
+			if err != nil {
+				return false
+			}
+
+			uncommitted = ev.EventId
+		}
+
+		err = checkpoint.commit(ctx, &checkpointEntry{
+			EntityWithoutChecksum: v1.EntityWithoutChecksum{IdMeta: v1.IdMeta{Id: ev.EventId}},
+			EnvironmentMeta:       v1.EnvironmentMeta{EnvironmentId: ev.EnvironmentId},
+			HistoryType:           key,
+			SubmitTime:            types.UnixMilli(time.Now()),
+		})
+		if err != nil {
+			// Retry only the commit, not the submission, as the event would be submitted twice otherwise.
+			client.logger.Errorw("Cannot record submitted history event in checkpoint",
+				zap.String("key", key),
+				zap.String("event_id", ev.EventId.String()),
+				zap.Error(err))
+			return false
+		}
+
+		uncommitted = nil
+
+		return true
 	}
 
+	periodic.Start(ctx, time.Hour, func(tick periodic.Tick) {
+		deleted, err := checkpoint.cleanup(ctx, tick.Time.Add(-checkpointRetention))
+		if err != nil {
+			client.logger.Errorw("Cannot clean up Icinga Notifications history checkpoint", zap.Error(err))
+			return
+		}
+
+		client.logger.Debugf("Removed %d old history events from Icinga Notifications checkpoint", deleted)
+	}, periodic.Immediate())
+
 	pipelineFn := NewStreamSorter(ctx, client.logger, sorterCallbackFn).PipelineFunc
 
 	extraStages := make(map[string]history.StageFunc)
-	for k := range syncKeyStructPtrs {
+	for k := range historyStructPtrs {
 		extraStages[k] = pipelineFn
 	}
 
@@ -846,16 +1497,9 @@ func StateToSeverity(s *v1.State, isService bool) (event.Severity, error) {
 // of a checkable in Icinga DB. It compares the severity and muted status of the incident with the state of the
 // entity and returns true if they match, false otherwise. If the entity type is unsupported, an error is returned.
 func HaveSameState(incident source.Incident, entity database.Entity) (bool, error) {
-	var s *v1.State
-	var isService bool
-	switch e := entity.(type) {
-	case *v1.HostState:
-		s = &e.State
-	case *v1.ServiceState:
-		s = &e.State
-		isService = true
-	default:
-		return false, fmt.Errorf("unsupported entity type %T", entity)
+	s, isService, err := checkableStateOf(entity)
+	if err != nil {
+		return false, err
 	}
 
 	severity, err := StateToSeverity(s, isService)
@@ -867,11 +1511,111 @@ func HaveSameState(incident source.Incident, entity database.Entity) (bool, erro
 		return false, nil
 	}
 
+	if incident.IsMuted != isMuted(s) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// haveSameState is like HaveSameState, but additionally considers checkables muted for being unreachable.
+func (client *Client) haveSameState(incident source.Incident, entity database.Entity) (bool, error) {
+	s, isService, err := checkableStateOf(entity)
+	if err != nil {
+		return false, err
+	}
+
+	if !client.mutesUnreachable(s) {
+		return HaveSameState(incident, entity)
+	}
+
+	severity, err := StateToSeverity(s, isService)
+	if err != nil {
+		return false, err
+	}
+
+	return incident.Severity == severity && incident.IsMuted, nil
+}
+
+// haveSameQueuedState checks if the given state queued in the outbox and the current state of the same checkable
+// result in the same incident state, i.e. have the same severity and muted status.
+//
+// As being unreachable may mute a checkable as well, depending on Options.Unreachable, the reachability is compared too.
+func haveSameQueuedState(queued, entity database.Entity) (bool, error) {
+	q, _, err := checkableStateOf(queued)
+	if err != nil {
+		return false, err
+	}
+
+	s, _, err := checkableStateOf(entity)
+	if err != nil {
+		return false, err
+	}
+
+	return q.HardState == s.HardState && isMuted(q) == isMuted(s) && isUnreachableState(q) == isUnreachableState(s), nil
+}
+
+// checkableStateOf returns the state of the given host or service state entity.
+func checkableStateOf(entity database.Entity) (s *v1.State, isService bool, err error) {
+	switch e := entity.(type) {
+	case *v1.HostState:
+		return &e.State, false, nil
+	case *v1.ServiceState:
+		return &e.State, true, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported entity type %T", entity)
+	}
+}
+
+// isMuted reports whether the checkable of the given state is in downtime, acknowledged or flapping.
+func isMuted(s *v1.State) bool {
 	inDowntime := s.InDowntime.Valid && s.InDowntime.Bool
 	isAcked := s.IsAcknowledged.Valid && s.IsAcknowledged.Bool
 	isFlapping := s.IsFlapping.Valid && s.IsFlapping.Bool
-	if incident.IsMuted != (inDowntime || isAcked || isFlapping) {
+
+	return inDowntime || isAcked || isFlapping
+}
+
+// isUnreachableState reports whether the checkable of the given state is unreachable due to a failed parent.
+func isUnreachableState(s *v1.State) bool {
+	return s.IsReachable.Valid && !s.IsReachable.Bool
+}
+
+// mutesUnreachable reports whether the checkable of the given state is muted for being unreachable.
+func (client *Client) mutesUnreachable(s *v1.State) bool {
+	return client.options.Unreachable == UnreachableMute && isUnreachableState(s)
+}
+
+// suppressesUnreachable reports whether the given state is held back for its checkable being unreachable.
+//
+// Only problem states are held back. Recoveries are still submitted to close incidents opened before the checkable
+// became unreachable. Besides the checkable being unreachable according to Icinga 2, the dependency graph must show
+// a failed parent. Otherwise, e.g. if the dependency states are not yet synced, the state is rather submitted than
+// possibly losing the only event about a problem.
+func (client *Client) suppressesUnreachable(ctx context.Context, entity database.Entity) (bool, error) {
+	if client.options.Unreachable != UnreachableSuppress {
 		return false, nil
 	}
-	return true, nil
+
+	s, isService, err := checkableStateOf(entity)
+	if err != nil || !isUnreachableState(s) {
+		return false, err
+	}
+
+	severity, err := StateToSeverity(s, isService)
+	if err != nil || severity == event.SeverityOK {
+		return false, err
+	}
+
+	key := objectFilterKeyOf(entity)
+	failed, err := client.fetchParentFailedFromSql(ctx, key.hostId, key.serviceId)
+	if err != nil {
+		return false, errors.Wrap(err, "can't fetch dependency states")
+	}
+
+	if !failed {
+		client.logger.Debugw("Submitting state of unreachable object without a failed parent in the dependency graph",
+			zap.String("object_id", entity.ID().String()))
+	}
+
+	return failed, nil
 }
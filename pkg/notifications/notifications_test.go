@@ -0,0 +1,51 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icingadb/pkg/icingadb/history"
+	v1history "github.com/icinga/icingadb/pkg/icingadb/v1/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeHistoryEntity(t *testing.T) {
+	entity, err := makeHistoryEntity(history.SyncPipelineNotification, map[string]any{
+		"id":                 "0102",
+		"host_id":            "0304",
+		"type":               "problem",
+		"author":             "icingaadmin",
+		"text":               "Disk is full",
+		"users_notified":     "2",
+		"users_notified_ids": `["0506","0708"]`,
+	})
+	require.NoError(t, err)
+
+	h, ok := entity.(*notificationHistory)
+	require.True(t, ok)
+	require.Equal(t, types.Binary{3, 4}, h.HostId)
+	require.Equal(t, v1history.NotificationType("problem"), h.Type)
+	require.Equal(t, "icingaadmin", h.Author)
+	require.Equal(t, uint16(2), h.UsersNotified)
+	require.Equal(t, types.MakeString(`["0506","0708"]`), h.UsersNotifiedIds)
+
+	entity, err = makeHistoryEntity(history.SyncPipelineComment, map[string]any{
+		"comment_id":       "0102",
+		"entry_type":       "ack",
+		"author":           "icingaadmin",
+		"comment":          "Working on it",
+		"has_been_removed": "1",
+		"removed_by":       "operator",
+	})
+	require.NoError(t, err)
+
+	c, ok := entity.(*v1history.CommentHistory)
+	require.True(t, ok)
+	require.Equal(t, v1history.CommentEntryType("ack"), c.EntryType)
+	require.Equal(t, "Working on it", c.Comment)
+	require.Equal(t, types.MakeBool(true), c.HasBeenRemoved)
+	require.Equal(t, types.MakeString("operator"), c.RemovedBy)
+
+	_, err = makeHistoryEntity(history.SyncPipelineState, nil)
+	require.Error(t, err)
+}
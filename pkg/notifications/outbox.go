@@ -0,0 +1,146 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/pkg/errors"
+)
+
+// outboxEntry is a host or service state queued in the outbox for submission to Icinga Notifications.
+type outboxEntry struct {
+	Id         uint64       `db:"id"`
+	ObjectId   types.Binary `db:"object_id"`
+	ObjectType string       `db:"object_type"`
+	State      string       `db:"state"` // JSON-encoded [v1.HostState] or [v1.ServiceState].
+	Attempts   uint32       `db:"attempts"`
+
+	LastAttemptTime types.Int `db:"last_attempt_time"` // Unix milliseconds, NULL if not attempted yet.
+}
+
+// entity decodes the queued state.
+func (e *outboxEntry) entity() (database.Entity, error) {
+	var entity database.Entity
+	switch e.ObjectType {
+	case "host":
+		entity = &v1.HostState{}
+	case "service":
+		entity = &v1.ServiceState{}
+	default:
+		return nil, errors.Errorf("unknown object type %q", e.ObjectType)
+	}
+
+	if err := json.Unmarshal([]byte(e.State), entity); err != nil {
+		return nil, errors.Wrapf(err, "can't decode queued %s state", e.ObjectType)
+	}
+
+	return entity, nil
+}
+
+// droppable returns whether the entry should be dropped after another failed submission, i.e. if it failed
+// outboxMaxAttempts times and Icinga Notifications accepted a state at submittedMilli after its previous attempt.
+// As Icinga Notifications is available then, the state itself can't be submitted, e.g. due to a deleted object.
+func (e *outboxEntry) droppable(submittedMilli int64) bool {
+	return e.Attempts+1 >= outboxMaxAttempts && e.LastAttemptTime.Valid && submittedMilli > e.LastAttemptTime.Int64
+}
+
+// outbox durably queues state changes in the database until they have been submitted to Icinga Notifications.
+//
+// Unlike retrying the submission in memory, this ensures that no state change is lost if Icinga DB is restarted
+// or hands over while Icinga Notifications is unavailable, as the taking over instance continues with the outbox.
+type outbox struct {
+	db *database.DB
+}
+
+// enqueue queues the given host or service state.
+func (o *outbox) enqueue(ctx context.Context, entity database.Entity) error {
+	var environmentId types.Binary
+	var objectType string
+	switch s := entity.(type) {
+	case *v1.HostState:
+		environmentId, objectType = s.EnvironmentId, "host"
+	case *v1.ServiceState:
+		environmentId, objectType = s.EnvironmentId, "service"
+	default:
+		return errors.Errorf("unsupported entity type %T", entity)
+	}
+
+	state, err := json.Marshal(entity)
+	if err != nil {
+		return errors.Wrapf(err, "can't encode %s state", objectType)
+	}
+
+	stmt := o.db.Rebind(`INSERT INTO notifications_outbox
+		(environment_id, object_id, object_type, state, enqueue_time, attempts) VALUES (?, ?, ?, ?, ?, 0)`)
+
+	_, err = o.db.ExecContext(ctx, stmt, environmentId, entity.ID(), objectType, string(state), time.Now().UnixMilli())
+	if err != nil {
+		return database.CantPerformQuery(err, stmt)
+	}
+
+	return nil
+}
+
+// pending returns up to limit queued entries of the given environment after the entry with the ID afterId,
+// in the order they were queued.
+func (o *outbox) pending(ctx context.Context, environmentId types.Binary, afterId uint64, limit int) ([]outboxEntry, error) {
+	query := o.db.Rebind(`SELECT id, object_id, object_type, state, attempts, last_attempt_time
+		FROM notifications_outbox WHERE environment_id = ? AND id > ? ORDER BY id LIMIT ?`)
+
+	var entries []outboxEntry
+	if err := o.db.SelectContext(ctx, &entries, query, environmentId, afterId, limit); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	return entries, nil
+}
+
+// latest returns the most recently queued state of each object of the given environment and type,
+// keyed by the object ID.
+func (o *outbox) latest(ctx context.Context, environmentId types.Binary, objectType string) (map[string]database.Entity, error) {
+	query := o.db.Rebind(`SELECT id, object_id, object_type, state, attempts FROM notifications_outbox
+		WHERE environment_id = ? AND object_type = ? ORDER BY id`)
+
+	var entries []outboxEntry
+	if err := o.db.SelectContext(ctx, &entries, query, environmentId, objectType); err != nil {
+		return nil, database.CantPerformQuery(err, query)
+	}
+
+	latest := make(map[string]database.Entity, len(entries))
+	for _, e := range entries {
+		entity, err := e.entity()
+		if err != nil {
+			return nil, err
+		}
+
+		latest[e.ObjectId.String()] = entity
+	}
+
+	return latest, nil
+}
+
+// remove deletes the given entry from the outbox.
+func (o *outbox) remove(ctx context.Context, id uint64) error {
+	stmt := o.db.Rebind(`DELETE FROM notifications_outbox WHERE id = ?`)
+	if _, err := o.db.ExecContext(ctx, stmt, id); err != nil {
+		return database.CantPerformQuery(err, stmt)
+	}
+
+	return nil
+}
+
+// recordFailure increments the number of attempts of the given entry and records the error of the last one.
+func (o *outbox) recordFailure(ctx context.Context, id uint64, failure error) error {
+	stmt := o.db.Rebind(`UPDATE notifications_outbox
+		SET attempts = attempts + 1, last_attempt_time = ?, last_error = ? WHERE id = ?`)
+
+	if _, err := o.db.ExecContext(ctx, stmt, time.Now().UnixMilli(), failure.Error(), id); err != nil {
+		return database.CantPerformQuery(err, stmt)
+	}
+
+	return nil
+}
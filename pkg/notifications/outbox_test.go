@@ -0,0 +1,94 @@
+package notifications
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/icinga/icinga-go-library/types"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxEntry_entity(t *testing.T) {
+	state := &v1.ServiceState{
+		State: v1.State{
+			EnvironmentMeta: v1.EnvironmentMeta{EnvironmentId: types.Binary{1, 2, 3}},
+			HardState:       2,
+			InDowntime:      types.MakeBool(true),
+			LastUpdate:      types.UnixMilli(time.UnixMilli(1700000000123)),
+			Output:          types.MakeString("CRITICAL"),
+			StateType:       "hard",
+		},
+		ServiceId: types.Binary{4, 5, 6},
+		HostId:    types.Binary{7, 8, 9},
+	}
+
+	encoded, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	entity, err := (&outboxEntry{ObjectType: "service", State: string(encoded)}).entity()
+	require.NoError(t, err)
+	require.Equal(t, state, entity)
+
+	_, err = (&outboxEntry{ObjectType: "hostgroup", State: string(encoded)}).entity()
+	require.Error(t, err)
+}
+
+func TestHaveSameQueuedState(t *testing.T) {
+	hostState := func(hardState uint8, acked bool) *v1.HostState {
+		return &v1.HostState{State: v1.State{HardState: hardState, IsAcknowledged: types.MakeBool(acked)}}
+	}
+
+	subtests := []struct {
+		name   string
+		queued *v1.HostState
+		state  *v1.HostState
+		same   bool
+	}{
+		{name: "same", queued: hostState(1, false), state: hostState(1, false), same: true},
+		{name: "state", queued: hostState(1, false), state: hostState(0, false), same: false},
+		{name: "muted", queued: hostState(1, false), state: hostState(1, true), same: false},
+		{
+			name:   "unreachable",
+			queued: hostState(1, false),
+			state:  &v1.HostState{State: v1.State{HardState: 1, IsReachable: types.MakeBool(false)}},
+			same:   false,
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			same, err := haveSameQueuedState(st.queued, st.state)
+			require.NoError(t, err)
+			require.Equal(t, st.same, same)
+		})
+	}
+
+	_, err := haveSameQueuedState(hostState(0, false), &v1.Host{})
+	require.Error(t, err)
+}
+
+func TestOutboxEntry_droppable(t *testing.T) {
+	attempted := types.Int{NullInt64: sql.NullInt64{Int64: 1000, Valid: true}}
+
+	subtests := []struct {
+		name      string
+		entry     outboxEntry
+		submitted int64
+		droppable bool
+	}{
+		{"first-attempt", outboxEntry{}, 2000, false},
+		{"below-max-attempts", outboxEntry{Attempts: outboxMaxAttempts - 2, LastAttemptTime: attempted}, 2000, false},
+		{"unavailable", outboxEntry{Attempts: outboxMaxAttempts - 1, LastAttemptTime: attempted}, 500, false},
+		{"never-submitted", outboxEntry{Attempts: outboxMaxAttempts - 1, LastAttemptTime: attempted}, 0, false},
+		{"available", outboxEntry{Attempts: outboxMaxAttempts - 1, LastAttemptTime: attempted}, 2000, true},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.droppable, st.entry.droppable(st.submitted))
+		})
+	}
+}
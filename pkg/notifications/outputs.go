@@ -0,0 +1,211 @@
+package notifications
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"go.uber.org/zap"
+)
+
+// checkOutputUpdater updates the message of an open incident in Icinga Notifications when the check output of its
+// object changes, but at most once per Options.CheckOutputSyncInterval per incident.
+//
+// The open incidents are fetched from Icinga Notifications once when started by checkOutputUpdater.run, and
+// afterward tracked based on the state events submitted by the Client.
+//
+// checkOutputUpdater is safe for concurrent use.
+type checkOutputUpdater struct {
+	client *Client
+
+	// wakeup signals checkOutputUpdater.run that a check output has changed.
+	wakeup chan struct{}
+
+	mu sync.Mutex
+	// incidents maps the object IDs of open incidents to their messages. It is nil if not running.
+	incidents map[string]*incidentMessage
+}
+
+// incidentMessage tracks the message of an open incident.
+type incidentMessage struct {
+	tags    map[string]string // tags identify the object of the incident.
+	message string            // message is the last one known to Icinga Notifications.
+	pending types.String      // pending is the message to update the incident with, if valid.
+	updated time.Time         // updated is the time of the last update.
+}
+
+// newCheckOutputUpdater returns a new checkOutputUpdater for the given Client.
+func newCheckOutputUpdater(client *Client) *checkOutputUpdater {
+	return &checkOutputUpdater{client: client, wakeup: make(chan struct{}, 1)}
+}
+
+// UpdateCheckOutput schedules an update of the message of the open incident of the host or service of the given state
+// if its check output has changed. All other entities and states of objects without open incidents are ignored.
+//
+// Only if Options.CheckOutputSync is CheckOutputSyncEvents, the updates are performed by [Client.SyncCheckOutputs].
+//
+// Note that this function is used as [icingadb.RUUpsertFunc] for the runtime updates pipeline, so its signature must
+// match the [icingadb.RUUpsertFunc] type.
+func (client *Client) UpdateCheckOutput(_ context.Context, entity database.Entity) error {
+	s, _, err := checkableStateOf(entity)
+	if err != nil || !s.Output.Valid {
+		return nil
+	}
+
+	client.outputs.update(entity.ID().String(), checkOutputMessage(s))
+
+	return nil
+}
+
+// update schedules the incident of the given object for an update if the message differs from the known one.
+func (u *checkOutputUpdater) update(objectId, message string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	im, ok := u.incidents[objectId]
+	if !ok {
+		return
+	}
+
+	if message == im.message {
+		im.pending = types.String{}
+		return
+	}
+
+	im.pending = types.MakeString(message)
+
+	select {
+	case u.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// observe tracks incidents opened and closed by the given submitted event of the given state entity.
+func (u *checkOutputUpdater) observe(entity database.Entity, ev *fetchableEvent) {
+	if u == nil {
+		return
+	}
+
+	if _, _, err := checkableStateOf(entity); err != nil {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.incidents == nil {
+		return
+	}
+
+	objectId := entity.ID().String()
+	if ev.Close.Valid && ev.Close.Bool {
+		delete(u.incidents, objectId)
+		return
+	}
+
+	im, ok := u.incidents[objectId]
+	if !ok {
+		im = &incidentMessage{tags: maps.Clone(ev.Tags)}
+		u.incidents[objectId] = im
+	}
+
+	// The event has updated the message already.
+	im.message = ev.Message
+	im.pending = types.String{}
+}
+
+// run fetches the open incidents and performs the updates scheduled by checkOutputUpdater.update until the context
+// is canceled.
+func (u *checkOutputUpdater) run(ctx context.Context) error {
+	incidents := make(map[string]*incidentMessage)
+	for id, incident := range u.client.retrieveEnvironmentIncidents(ctx) {
+		incidents[id] = &incidentMessage{tags: incident.ObjectTags}
+	}
+
+	u.mu.Lock()
+	u.incidents = incidents
+	u.mu.Unlock()
+
+	defer func() {
+		u.mu.Lock()
+		u.incidents = nil
+		u.mu.Unlock()
+	}()
+
+	u.client.logger.Debugw("Updating check outputs of incidents on changes", zap.Int("incidents", len(incidents)))
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-u.wakeup:
+		case <-timer.C:
+		}
+
+		next, err := u.flush(ctx)
+		if err != nil {
+			return err
+		}
+
+		timer.Stop()
+		if next > 0 {
+			timer.Reset(next)
+		}
+	}
+}
+
+// flush updates all incidents with pending messages whose last update was at least Options.CheckOutputSyncInterval
+// ago. It returns the duration until the next pending update is due, or zero if there is none.
+func (u *checkOutputUpdater) flush(ctx context.Context) (time.Duration, error) {
+	for {
+		tags, message, next := u.nextDue(time.Now())
+		if tags == nil {
+			return next, nil
+		}
+
+		objectType := "host"
+		if _, ok := tags["service"]; ok {
+			objectType = "service"
+		}
+
+		if err := u.client.modifyIncidentMessage(ctx, message, tags, objectType); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// nextDue returns the tags and the pending message of an incident whose update is due and marks it as updated.
+// If there is none, it returns nil tags and the duration until the next pending update is due, or zero.
+func (u *checkOutputUpdater) nextDue(now time.Time) (tags map[string]string, message string, next time.Duration) {
+	interval := u.client.options.CheckOutputSyncInterval
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, im := range u.incidents {
+		if !im.pending.Valid {
+			continue
+		}
+
+		if wait := im.updated.Add(interval).Sub(now); wait > 0 {
+			if next == 0 || wait < next {
+				next = wait
+			}
+
+			continue
+		}
+
+		message = im.pending.String
+		im.message, im.pending, im.updated = message, types.String{}, now
+
+		return im.tags, message, 0
+	}
+
+	return nil, "", next
+}
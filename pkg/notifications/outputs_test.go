@@ -0,0 +1,69 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/icinga/icinga-go-library/notifications/event"
+	"github.com/icinga/icinga-go-library/types"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOutputUpdater(t *testing.T) {
+	u := newCheckOutputUpdater(&Client{options: Options{CheckOutputSyncInterval: time.Minute}})
+
+	state := &v1.HostState{HostId: types.Binary{1}}
+	state.Id = types.Binary{1}
+	objectId := state.ID().String()
+
+	submitted := func(message string, closed bool) *fetchableEvent {
+		ev := &fetchableEvent{Event: &event.Event{Tags: map[string]string{"host": "web1"}, Message: message}}
+		if closed {
+			ev.Close = types.MakeBool(true)
+		}
+
+		return ev
+	}
+
+	// Not running, so nothing is tracked.
+	u.observe(state, submitted("CRITICAL", false))
+	u.update(objectId, "CRITICAL - again")
+	tags, _, next := u.nextDue(time.Now())
+	require.Nil(t, tags)
+	require.Zero(t, next)
+
+	u.incidents = make(map[string]*incidentMessage)
+	u.observe(state, submitted("CRITICAL", false))
+
+	// Same message as submitted with the event.
+	u.update(objectId, "CRITICAL")
+	tags, _, _ = u.nextDue(time.Now())
+	require.Nil(t, tags)
+
+	now := time.Now()
+	u.update(objectId, "CRITICAL - 1")
+	tags, message, _ := u.nextDue(now)
+	require.Equal(t, map[string]string{"host": "web1"}, tags)
+	require.Equal(t, "CRITICAL - 1", message)
+
+	// Rate limited until the interval has passed.
+	u.update(objectId, "CRITICAL - 2")
+	tags, _, next = u.nextDue(now.Add(time.Second))
+	require.Nil(t, tags)
+	require.Equal(t, 59*time.Second, next)
+
+	tags, message, _ = u.nextDue(now.Add(time.Minute))
+	require.NotNil(t, tags)
+	require.Equal(t, "CRITICAL - 2", message)
+
+	// Closed incidents are not updated anymore.
+	u.observe(state, submitted("OK", true))
+	u.update(objectId, "OK - 1")
+	tags, _, _ = u.nextDue(now.Add(time.Hour))
+	require.Nil(t, tags)
+
+	// Other entities are ignored.
+	u.observe(&v1.Host{}, submitted("", false))
+	require.Empty(t, u.incidents)
+}
@@ -185,10 +185,11 @@ func (sorter *StreamSorter) verbose(msg string, keysAndValues ...any) {
 	sorter.logger.Debugw(msg, keysAndValues...)
 }
 
-// startCallback initiates the callback in a background goroutine and returns a channel that is closed once the callback
-// has succeeded. It retries the callback with a backoff until it signal success by returning true.
-func (sorter *StreamSorter) startCallback(msg redis.XMessage, key string) <-chan struct{} {
-	callbackCh := make(chan struct{})
+// startCallback initiates the callback in a background goroutine and returns a channel that receives true once the
+// callback has succeeded. It retries the callback with a backoff until it signal success by returning true. If the
+// StreamSorter.ctx is done before, the channel is closed without receiving a value.
+func (sorter *StreamSorter) startCallback(msg redis.XMessage, key string) <-chan bool {
+	callbackCh := make(chan bool, 1)
 
 	go func() {
 		defer close(callbackCh)
@@ -213,6 +214,7 @@ func (sorter *StreamSorter) startCallback(msg redis.XMessage, key string) <-chan
 				zap.Duration("next-delay", callbackDelay))
 
 			if success {
+				callbackCh <- true
 				return
 			} else {
 				callbackDelay = min(max(time.Millisecond, 2*callbackDelay), sorter.callbackMaxDelay)
@@ -249,9 +251,9 @@ func (sorter *StreamSorter) worker() {
 	}()
 
 	// If a submission is currently given to the callback via startCallback, these two variables are not nil. After the
-	// callback has finished, the channel will be closed.
+	// callback has succeeded, the channel will receive true.
 	var runningSubmission *streamSorterSubmission
-	var runningCallbackCh <-chan struct{}
+	var runningCallbackCh <-chan bool
 
 	for {
 		if (runningSubmission == nil) != (runningCallbackCh == nil) {
@@ -307,9 +309,20 @@ func (sorter *StreamSorter) worker() {
 			// Loop start processing of the next submission.
 			continue
 
-		case <-runningCallbackCh:
+		case success := <-runningCallbackCh:
+			if !success {
+				// The callback was aborted as the context is done. The message must not be forwarded, otherwise it
+				// would be deleted from Redis without ever being passed to the callback successfully.
+				return
+			}
+
 			out := runningSubmission.out
-			out <- runningSubmission.msg
+			select {
+			case out <- runningSubmission.msg:
+			case <-sorter.ctx.Done():
+				return
+			}
+
 			state := registeredOutputs[out]
 			state.pending--
 			if state.close && state.pending == 0 {
@@ -377,3 +377,39 @@ func TestStreamSorter(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamSorter_CanceledCallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	called := make(chan struct{}, 1)
+	sorter := NewStreamSorter(
+		ctx,
+		logging.NewLogger(zaptest.NewLogger(t).Sugar(), time.Second),
+		func(redis.XMessage, string) bool {
+			select {
+			case called <- struct{}{}:
+			default:
+			}
+
+			return false
+		})
+	sorter.callbackMaxDelay = 10 * time.Millisecond
+	sorter.submissionMinAge = 0
+
+	in := make(chan redis.XMessage)
+	out := make(chan redis.XMessage)
+	go func() { _ = sorter.PipelineFunc(ctx, history.Sync{}, "", in, out) }()
+
+	in <- redis.XMessage{ID: fmt.Sprintf("%d-0", time.Now().UnixMilli())}
+	<-called
+	cancel()
+
+	// A message whose callback never succeeded must not be forwarded, as it would be deleted from Redis otherwise.
+	select {
+	case msg, ok := <-out:
+		require.False(t, ok, "message %q was forwarded without a successful callback", msg.ID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("output channel was not closed")
+	}
+}
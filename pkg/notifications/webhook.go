@@ -0,0 +1,633 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/icinga/icinga-go-library/backoff"
+	"github.com/icinga/icinga-go-library/com"
+	"github.com/icinga/icinga-go-library/config"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/logging"
+	"github.com/icinga/icinga-go-library/notifications/event"
+	"github.com/icinga/icinga-go-library/periodic"
+	"github.com/icinga/icinga-go-library/redis"
+	"github.com/icinga/icinga-go-library/retry"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icingadb/internal"
+	"github.com/icinga/icingadb/pkg/common"
+	"github.com/icinga/icingadb/pkg/icingadb/history"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/pkg/errors"
+	"github.com/theory/jsonpath"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Webhook payload formats supported by [WebhookConfig.Format].
+const (
+	WebhookFormatJson         = "json"
+	WebhookFormatAlertmanager = "alertmanager"
+)
+
+const (
+	// webhookDefaultTimeout is used if no [WebhookConfig.Timeout] is configured.
+	webhookDefaultTimeout = 10 * time.Second
+
+	// webhookDefaultRetryTimeout is used if no [WebhookConfig.RetryTimeout] is configured.
+	webhookDefaultRetryTimeout = time.Minute
+)
+
+// WebhookConfig defines an HTTP endpoint to which [Webhooks] posts events.
+type WebhookConfig struct {
+	// Name identifies the webhook in the logs.
+	Name string `yaml:"name"`
+	Url  string `yaml:"url"`
+
+	// Format is one of the WebhookFormat* constants, defaulting to WebhookFormatJson. It is ignored if a Template is set.
+	Format string `yaml:"format"`
+
+	// Template is a text/template rendering the request body from a [WebhookEvent].
+	Template string `yaml:"template"`
+
+	// Headers are additional HTTP headers to send, e.g. for authentication.
+	Headers map[string]string `yaml:"headers"`
+
+	// Secret is the key to sign the request body with HMAC-SHA256. The signature is sent in the
+	// X-Icinga-Signature header as "sha256=" followed by its hex encoding.
+	Secret string `yaml:"secret"` // #nosec G117 -- exported secret field
+
+	// Relations are JSONPaths of relations to resolve and include in the events, like the notifications default_relations.
+	Relations []string `yaml:"relations"`
+
+	Filter WebhookFilter `yaml:"filter"`
+
+	// Timeout limits the time of a single request.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// RetryTimeout is the time after which failed requests are not retried anymore and the event is dropped.
+	RetryTimeout time.Duration `yaml:"retry-timeout"`
+
+	TlsOptions config.TLS `yaml:",inline"`
+}
+
+// Validate checks constraints in the supplied webhook configuration and returns an error if they are violated.
+func (c *WebhookConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("name missing")
+	}
+
+	if c.Url == "" {
+		return errors.New("url missing")
+	}
+	if u, err := url.Parse(c.Url); err != nil {
+		return errors.Wrap(err, "invalid url")
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid url scheme %q, must be http or https", u.Scheme)
+	}
+
+	switch c.Format {
+	case "", WebhookFormatJson, WebhookFormatAlertmanager:
+	default:
+		return errors.Errorf("unknown format %q", c.Format)
+	}
+
+	if _, err := c.parseTemplate(); err != nil {
+		return err
+	}
+
+	for _, relation := range c.Relations {
+		if _, err := jsonpath.Parse(relation); err != nil {
+			return errors.Wrapf(err, "relation %q is not a valid JSONPath", relation)
+		}
+	}
+
+	if err := c.Filter.Validate(); err != nil {
+		return errors.Wrap(err, "invalid filter")
+	}
+
+	if c.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	if c.RetryTimeout < 0 {
+		return errors.New("retry-timeout must not be negative")
+	}
+
+	return nil
+}
+
+// parseTemplate parses the configured Template, if any.
+func (c *WebhookConfig) parseTemplate() (*template.Template, error) {
+	if c.Template == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(c.Name).Funcs(template.FuncMap{"json": webhookTemplateJson}).Parse(c.Template)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid template")
+	}
+
+	return tmpl, nil
+}
+
+// webhookTemplateJson is available in webhook templates as the "json" function to JSON-encode values.
+func webhookTemplateJson(v any) (string, error) {
+	encoded, err := json.Marshal(v)
+
+	return string(encoded), err
+}
+
+// WebhookFilter selects the events to post to a webhook. Empty criteria match all events.
+type WebhookFilter struct {
+	// Types are the event types to post, e.g. "state" or "acknowledgement-set".
+	Types []string `yaml:"types"`
+
+	// Hosts are patterns, as understood by [path.Match], of host names to post events for.
+	Hosts []string `yaml:"hosts"`
+
+	// Services are patterns, as understood by [path.Match], of service names to post events for.
+	// If set, events of hosts themselves are not posted.
+	Services []string `yaml:"services"`
+}
+
+// Validate checks constraints in the supplied webhook filter and returns an error if they are violated.
+func (f *WebhookFilter) Validate() error {
+	for _, t := range f.Types {
+		if _, err := event.ParseType(t); err != nil {
+			return err
+		}
+	}
+
+	for _, pattern := range slices.Concat(f.Hosts, f.Services) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// match reports whether the given event is selected by the filter.
+func (f *WebhookFilter) match(ev *WebhookEvent) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, ev.Type) {
+		return false
+	}
+
+	if len(f.Hosts) > 0 && !matchAny(f.Hosts, ev.Tags["host"]) {
+		return false
+	}
+
+	if len(f.Services) > 0 {
+		service, ok := ev.Tags["service"]
+		if !ok || !matchAny(f.Services, service) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchAny reports whether name matches any of the given patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookEvent is posted to webhooks in the json format and passed to webhook templates.
+type WebhookEvent struct {
+	Time        time.Time         `json:"time"`
+	Type        string            `json:"type"`
+	Severity    string            `json:"severity,omitempty"`
+	Name        string            `json:"name"`
+	Url         string            `json:"url"`
+	Tags        map[string]string `json:"tags"`
+	Username    string            `json:"username,omitempty"`
+	Message     string            `json:"message"`
+	Muted       bool              `json:"muted"`
+	MutedReason string            `json:"muted_reason,omitempty"`
+	Relations   map[string]any    `json:"relations,omitempty"`
+}
+
+// newWebhookEvent creates a WebhookEvent from an event built for Icinga Notifications.
+func newWebhookEvent(ev *event.Event, t time.Time) *WebhookEvent {
+	we := &WebhookEvent{
+		Time:        t,
+		Type:        ev.Type.String(),
+		Name:        ev.Name,
+		Url:         ev.URL,
+		Tags:        ev.Tags,
+		Username:    ev.Username,
+		Message:     ev.Message,
+		Muted:       ev.IsMuted(),
+		MutedReason: ev.MutedReason,
+		Relations:   ev.Relations,
+	}
+
+	if ev.Severity != event.SeverityNone {
+		we.Severity = ev.Severity.String()
+	}
+
+	return we
+}
+
+// alertmanagerAlert is an alert as expected by the Alertmanager API.
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt,omitzero"`
+}
+
+// encodeAlertmanager encodes a state event as Alertmanager alert, which is resolved if the state is OK.
+// Returns nil for all other events, as Alertmanager only knows about alerts.
+func encodeAlertmanager(ev *WebhookEvent) ([]byte, error) {
+	if ev.Type != event.TypeState.String() {
+		return nil, nil
+	}
+
+	alert := alertmanagerAlert{
+		Labels: map[string]string{"alertname": "IcingaState"},
+		Annotations: map[string]string{
+			"summary":     ev.Name,
+			"description": ev.Message,
+			"severity":    ev.Severity,
+			"url":         ev.Url,
+		},
+		StartsAt: ev.Time,
+	}
+
+	for k, v := range ev.Tags {
+		alert.Labels[k] = v
+	}
+
+	if ev.Severity == event.SeverityOK.String() {
+		alert.EndsAt = ev.Time
+	}
+
+	return json.Marshal([]alertmanagerAlert{alert})
+}
+
+// errWebhookRejected is returned if a webhook rejects a request, which is therefore not retried.
+var errWebhookRejected = errors.New("request rejected")
+
+// webhook posts events to a single configured endpoint.
+type webhook struct {
+	config   WebhookConfig
+	client   *http.Client
+	template *template.Template
+	queue    chan *WebhookEvent
+	posted   com.Counter
+	dropped  com.Counter
+}
+
+// encode renders the request body for the given event. Returns nil if the event is not to be posted in this format.
+func (wh *webhook) encode(ev *WebhookEvent) ([]byte, error) {
+	if wh.template != nil {
+		var buf bytes.Buffer
+		if err := wh.template.Execute(&buf, ev); err != nil {
+			return nil, errors.Wrap(err, "can't execute template")
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	if wh.config.Format == WebhookFormatAlertmanager {
+		return encodeAlertmanager(ev)
+	}
+
+	return json.Marshal(ev)
+}
+
+// post sends the given request body once.
+func (wh *webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.config.Url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "can't create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Icinga DB "+internal.Version.Version)
+	for k, v := range wh.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if wh.config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(wh.config.Secret))
+		_, _ = mac.Write(body)
+		req.Header.Set("X-Icinga-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := wh.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "can't send request")
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	switch {
+	case res.StatusCode < 300:
+		return nil
+	case res.StatusCode >= 500, res.StatusCode == http.StatusRequestTimeout, res.StatusCode == http.StatusTooManyRequests:
+		return errors.Errorf("unexpected HTTP status %s", res.Status)
+	default:
+		return errors.Wrapf(errWebhookRejected, "unexpected HTTP status %s", res.Status)
+	}
+}
+
+// Webhooks posts state changes, acknowledgements, downtimes and flapping events to HTTP endpoints, using the same
+// events as submitted to Icinga Notifications.
+//
+// Events are posted asynchronously by [Webhooks.Run] in the order they were submitted. Failed requests are retried
+// with an exponential backoff until the configured retry timeout, after which the event is dropped. If a webhook
+// can't keep up, further events are dropped for it, so that it doesn't stall the pipelines submitting them.
+type Webhooks struct {
+	logger *logging.Logger
+
+	// events is only used to build the events, never to submit them to Icinga Notifications.
+	events    *Client
+	relations []string
+	webhooks  []*webhook
+
+	// hardStates are the last seen hard states by state ID, to only post state events on hard state changes.
+	hardStates   map[string]uint8
+	hardStatesMu sync.Mutex
+}
+
+// NewWebhooks returns new Webhooks for the given configurations.
+func NewWebhooks(db *database.DB, rc *redis.Client, logger *logging.Logger, configs []WebhookConfig) (*Webhooks, error) {
+	w := &Webhooks{
+		logger:     logger,
+		events:     &Client{db: db, logger: logger, redisClient: rc},
+		hardStates: make(map[string]uint8),
+	}
+
+	for _, c := range configs {
+		u, err := url.Parse(c.Url)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't parse url of webhook %q", c.Name)
+		}
+
+		tlsConfig, err := c.TlsOptions.MakeConfig(u.Hostname())
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't create TLS config of webhook %q", c.Name)
+		}
+
+		tmpl, err := c.parseTemplate()
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't parse template of webhook %q", c.Name)
+		}
+
+		if c.Format == "" {
+			c.Format = WebhookFormatJson
+		}
+		if c.Timeout == 0 {
+			c.Timeout = webhookDefaultTimeout
+		}
+		if c.RetryTimeout == 0 {
+			c.RetryTimeout = webhookDefaultRetryTimeout
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+
+		w.webhooks = append(w.webhooks, &webhook{
+			config:   c,
+			client:   &http.Client{Transport: transport, Timeout: c.Timeout},
+			template: tmpl,
+			queue:    make(chan *WebhookEvent, 1<<10),
+		})
+
+		for _, relation := range c.Relations {
+			if !slices.Contains(w.relations, relation) {
+				w.relations = append(w.relations, relation)
+			}
+		}
+	}
+
+	return w, nil
+}
+
+// Submit builds the event of this [database.Entity] and queues it for all webhooks whose filter it matches.
+// Host and service states are only posted if their hard state changed. Events are dropped for webhooks whose
+// queue is full.
+//
+// Note that this function is used as [icingadb.RUUpsertFunc] for the runtime updates pipeline, so its signature must
+// match the [icingadb.RUUpsertFunc] type.
+func (w *Webhooks) Submit(ctx context.Context, entity database.Entity) error {
+	switch s := entity.(type) {
+	case *v1.HostState:
+		if !w.hardStateChanged(s.Id, &s.State) {
+			return nil
+		}
+	case *v1.ServiceState:
+		if !w.hardStateChanged(s.Id, &s.State) {
+			return nil
+		}
+	}
+
+	ev := w.events.buildEvent(ctx, entity)
+	if ev == nil {
+		return nil
+	}
+
+	we := newWebhookEvent(ev.Event, time.Now())
+
+	var matching []*webhook
+	for _, wh := range w.webhooks {
+		if wh.config.Filter.match(we) {
+			matching = append(matching, wh)
+		}
+	}
+
+	if len(matching) == 0 {
+		return nil
+	}
+
+	if err := ev.completeAndUpdate(ctx, w.relations); err != nil {
+		w.logger.Errorw("Cannot fetch relations for webhook event, skipping it",
+			zap.String("event", ev.Name),
+			zap.Strings("relations", w.relations),
+			zap.Error(err))
+		return nil
+	}
+
+	we.Relations = ev.Relations
+
+	for _, wh := range matching {
+		select {
+		case wh.queue <- we:
+		default:
+			wh.dropped.Inc()
+		}
+	}
+
+	return nil
+}
+
+// hardStateChanged reports whether the given hard state differs from the last one seen for the state ID.
+//
+// For states not seen since the start, it falls back to the previous hard state reported by Icinga 2, which is
+// the hard state before the last hard state change. Thus, after a restart, the current hard state of an object may
+// be posted once more with its next hard check result, but no hard state change is missed.
+func (w *Webhooks) hardStateChanged(id types.Binary, s *v1.State) bool {
+	if s.StateType != common.HardState {
+		return false
+	}
+
+	w.hardStatesMu.Lock()
+	defer w.hardStatesMu.Unlock()
+
+	last, ok := w.hardStates[id.String()]
+	if !ok {
+		last = s.PreviousHardState
+	}
+
+	w.hardStates[id.String()] = s.HardState
+
+	return last != s.HardState
+}
+
+// SyncExtraStages returns a map of history sync keys to [history.StageFunc] to be used for [history.Sync], which
+// submit acknowledgements, downtimes and flapping events from the history streams.
+//
+// Only the responsible Icinga DB instance, as reported by isResponsible, submits history events.
+// Unlike [Client.SyncExtraStages], the history stream entries are always forwarded, so events of a history stream
+// entry processed while no Icinga DB instance is responsible are not posted.
+func (w *Webhooks) SyncExtraStages(isResponsible func() bool) map[string]history.StageFunc {
+	stage := func(ctx context.Context, _ history.Sync, key string, in <-chan redis.XMessage, out chan<- redis.XMessage) error {
+		defer close(out)
+
+		for {
+			select {
+			case msg, ok := <-in:
+				if !ok {
+					return nil
+				}
+
+				if isResponsible() {
+					entity, err := makeHistoryEntity(key, msg.Values)
+					if err != nil {
+						w.logger.Errorw("Failed to create database.Entity out of Redis stream message",
+							zap.Error(err),
+							zap.String("key", key),
+							zap.String("id", msg.ID))
+					} else if err := w.Submit(ctx, entity); err != nil {
+						return err
+					}
+				}
+
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	extraStages := make(map[string]history.StageFunc)
+	for k := range historyStructPtrs {
+		extraStages[k] = stage
+	}
+
+	return extraStages
+}
+
+// Run posts the events queued by Submit until the context is canceled.
+func (w *Webhooks) Run(ctx context.Context) error {
+	defer periodic.Start(ctx, w.logger.Interval(), func(_ periodic.Tick) {
+		for _, wh := range w.webhooks {
+			if count := wh.posted.Reset(); count > 0 {
+				w.logger.Infof("Posted %d events to webhook %q", count, wh.config.Name)
+			}
+			if count := wh.dropped.Reset(); count > 0 {
+				w.logger.Warnf("Dropped %d events for webhook %q as it can't keep up", count, wh.config.Name)
+			}
+		}
+	}).Stop()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, wh := range w.webhooks {
+		g.Go(func() error {
+			for {
+				select {
+				case ev := <-wh.queue:
+					w.deliver(ctx, wh, ev)
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		})
+	}
+
+	return g.Wait()
+}
+
+// deliver posts the given event to the webhook, retrying failed requests until its retry timeout.
+func (w *Webhooks) deliver(ctx context.Context, wh *webhook, ev *WebhookEvent) {
+	body, err := wh.encode(ev)
+	if err != nil {
+		w.logger.Errorw("Cannot encode webhook event, skipping it",
+			zap.String("webhook", wh.config.Name),
+			zap.String("event", ev.Name),
+			zap.Error(err))
+		return
+	}
+
+	if body == nil {
+		return
+	}
+
+	err = retry.WithBackoff(
+		ctx,
+		func(ctx context.Context) error { return wh.post(ctx, body) },
+		func(err error) bool { return !errors.Is(err, errWebhookRejected) },
+		backoff.DefaultBackoff,
+		retry.Settings{
+			Timeout: wh.config.RetryTimeout,
+			OnRetryableError: func(elapsed time.Duration, attempt uint64, err, lastErr error) {
+				if lastErr == nil || err.Error() != lastErr.Error() {
+					w.logger.Warnw("Cannot post event to webhook, retrying",
+						zap.String("webhook", wh.config.Name),
+						zap.String("event", ev.Name),
+						zap.Uint64("attempt", attempt),
+						zap.Duration("elapsed", elapsed),
+						zap.Error(err))
+				}
+			},
+		},
+	)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Errorw("Cannot post event to webhook, dropping it",
+				zap.String("webhook", wh.config.Name),
+				zap.String("event", ev.Name),
+				zap.Error(err))
+		}
+
+		return
+	}
+
+	wh.posted.Add(1)
+}
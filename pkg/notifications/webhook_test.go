@@ -0,0 +1,160 @@
+package notifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/icinga/icingadb/pkg/common"
+	v1 "github.com/icinga/icingadb/pkg/icingadb/v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookFilter_match(t *testing.T) {
+	hostEvent := &WebhookEvent{Type: "state", Tags: map[string]string{"host": "web1"}}
+	serviceEvent := &WebhookEvent{Type: "downtime-start", Tags: map[string]string{"host": "web1", "service": "http"}}
+
+	subtests := []struct {
+		name   string
+		filter WebhookFilter
+		input  *WebhookEvent
+		match  bool
+	}{
+		{name: "empty", input: serviceEvent, match: true},
+		{name: "type", filter: WebhookFilter{Types: []string{"state"}}, input: hostEvent, match: true},
+		{name: "other-type", filter: WebhookFilter{Types: []string{"state"}}, input: serviceEvent, match: false},
+		{name: "host", filter: WebhookFilter{Hosts: []string{"web*"}}, input: serviceEvent, match: true},
+		{name: "other-host", filter: WebhookFilter{Hosts: []string{"db*"}}, input: hostEvent, match: false},
+		{name: "service", filter: WebhookFilter{Services: []string{"http", "ssh"}}, input: serviceEvent, match: true},
+		{name: "service-host", filter: WebhookFilter{Services: []string{"*"}}, input: hostEvent, match: false},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.match, st.filter.match(st.input))
+		})
+	}
+}
+
+func TestWebhooks_hardStateChanged(t *testing.T) {
+	w := &Webhooks{hardStates: make(map[string]uint8)}
+	id := types.Binary{1}
+
+	steps := []struct {
+		name     string
+		state    v1.State
+		expected bool
+	}{
+		{"unseen-recheck", v1.State{StateType: common.HardState, PreviousHardState: 0, HardState: 0}, false},
+		{"soft", v1.State{StateType: common.SoftState, PreviousHardState: 0, HardState: 0, SoftState: 2}, false},
+		{"hard", v1.State{StateType: common.HardState, PreviousHardState: 0, HardState: 2}, true},
+		{"recheck", v1.State{StateType: common.HardState, PreviousHardState: 0, HardState: 2}, false},
+		{"recovery", v1.State{StateType: common.HardState, PreviousHardState: 2, HardState: 0}, true},
+	}
+
+	for _, step := range steps {
+		require.Equal(t, step.expected, w.hardStateChanged(id, &step.state), step.name)
+	}
+
+	unseen := v1.State{StateType: common.HardState, PreviousHardState: 0, HardState: 2}
+	require.True(t, w.hardStateChanged(types.Binary{2}, &unseen), "unseen-change")
+}
+
+func TestWebhook_encode(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ev := &WebhookEvent{
+		Time:     ts,
+		Type:     "state",
+		Severity: "ok",
+		Name:     "web1: http",
+		Url:      "/icingadb/service?name=http",
+		Tags:     map[string]string{"host": "web1", "service": "http"},
+		Message:  "HTTP OK",
+	}
+
+	subtests := []struct {
+		name   string
+		config WebhookConfig
+		input  *WebhookEvent
+		output string
+	}{
+		{
+			name:   "json",
+			config: WebhookConfig{Format: WebhookFormatJson},
+			input:  ev,
+			output: `{"time":"2024-01-02T03:04:05Z","type":"state","severity":"ok","name":"web1: http",` +
+				`"url":"/icingadb/service?name=http","tags":{"host":"web1","service":"http"},` +
+				`"message":"HTTP OK","muted":false}`,
+		},
+		{
+			name:   "alertmanager",
+			config: WebhookConfig{Format: WebhookFormatAlertmanager},
+			input:  ev,
+			output: `[{"labels":{"alertname":"IcingaState","host":"web1","service":"http"},` +
+				`"annotations":{"description":"HTTP OK","severity":"ok","summary":"web1: http",` +
+				`"url":"/icingadb/service?name=http"},` +
+				`"startsAt":"2024-01-02T03:04:05Z","endsAt":"2024-01-02T03:04:05Z"}]`,
+		},
+		{
+			name:   "alertmanager-non-state",
+			config: WebhookConfig{Format: WebhookFormatAlertmanager},
+			input:  &WebhookEvent{Type: "downtime-start"},
+		},
+		{
+			name:   "template",
+			config: WebhookConfig{Name: "chat", Template: `{"text": {{ json (printf "%s is %s" .Name .Severity) }}}`},
+			input:  ev,
+			output: `{"text": "web1: http is ok"}`,
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			tmpl, err := st.config.parseTemplate()
+			require.NoError(t, err)
+
+			wh := &webhook{config: st.config, template: tmpl}
+			body, err := wh.encode(st.input)
+			require.NoError(t, err)
+			require.Equal(t, st.output, string(body))
+		})
+	}
+}
+
+func TestWebhook_post(t *testing.T) {
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		_, _ = mac.Write(body)
+		require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Icinga-Signature"))
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	wh := &webhook{
+		config: WebhookConfig{Url: server.URL, Secret: "s3cret", Headers: map[string]string{"Authorization": "Bearer token"}},
+		client: server.Client(),
+	}
+
+	require.NoError(t, wh.post(t.Context(), []byte(`{}`)))
+
+	status = http.StatusServiceUnavailable
+	err := wh.post(t.Context(), []byte(`{}`))
+	require.Error(t, err)
+	require.False(t, errors.Is(err, errWebhookRejected))
+
+	status = http.StatusBadRequest
+	require.ErrorIs(t, wh.post(t.Context(), []byte(`{}`)), errWebhookRejected)
+}
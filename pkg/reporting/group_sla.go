@@ -0,0 +1,145 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Mode specifies how the availability of group members is combined into the availability of the group.
+type Mode string
+
+const (
+	// ModeMean averages the availability of all members, each weighted equally.
+	ModeMean Mode = "mean"
+
+	// ModeAnyProblem considers the group to have a problem while at least one of its members has a problem.
+	ModeAnyProblem Mode = "any"
+
+	// ModeAllProblem considers the group to have a problem only while all of its members have a problem.
+	ModeAllProblem Mode = "all"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (m *Mode) UnmarshalText(text []byte) error {
+	switch mode := Mode(text); mode {
+	case ModeMean, ModeAnyProblem, ModeAllProblem:
+		*m = mode
+		return nil
+	default:
+		return fmt.Errorf("unknown aggregation mode %q", text)
+	}
+}
+
+// MemberSla is the availability of a single group member.
+type MemberSla struct {
+	Member
+
+	// Availability is the share of time without problems in percent.
+	// It is nil if there was no time to report on, e.g. because the member was pending during the whole period.
+	Availability *float64 `json:"availability"`
+
+	// TotalTime is the time which counts towards the SLA.
+	TotalTime time.Duration `json:"total_time"`
+
+	// ProblemTime is the time during which the member had a problem outside of downtimes.
+	ProblemTime time.Duration `json:"problem_time"`
+}
+
+// GroupSla is the availability of a group over a reporting period.
+type GroupSla struct {
+	Group Group     `json:"group"`
+	Mode  Mode      `json:"mode"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Availability is the share of time without problems in percent according to Mode.
+	// It is nil if there was no time to report on, e.g. because the group has no members.
+	Availability *float64 `json:"availability"`
+
+	// TotalTime is the time during which at least one member counted towards the SLA.
+	// It is zero for ModeMean as the mean is computed from the member availabilities.
+	TotalTime time.Duration `json:"total_time"`
+
+	// ProblemTime is the time during which the group had a problem according to Mode.
+	// It is zero for ModeMean as the mean is computed from the member availabilities.
+	ProblemTime time.Duration `json:"problem_time"`
+
+	Members []MemberSla `json:"members"`
+}
+
+// GroupSla computes the availability of the given group between start and end.
+//
+// Problem time is determined the same way as the get_sla_ok_percent SQL function does it for a single checkable:
+// Hosts have a problem in any hard state but UP, services in hard states CRITICAL and UNKNOWN.
+// Time spent in downtimes is never considered a problem and time spent in the pending state is not counted at all.
+// Members only count during the time they belong to the group according to the MembershipProvider.
+func (r *Reporter) GroupSla(ctx context.Context, group Group, start, end time.Time, mode Mode) (*GroupSla, error) {
+	switch mode {
+	case ModeMean, ModeAnyProblem, ModeAllProblem:
+	default:
+		return nil, errors.Errorf("unknown aggregation mode %q", mode)
+	}
+
+	period, err := periodOf(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := r.members.Members(ctx, group, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GroupSla{Group: group, Mode: mode, Start: start, End: end, Members: make([]MemberSla, 0, len(members))}
+	timelines := make([]timeline, 0, len(members))
+
+	for _, m := range members {
+		h, err := r.loadHistory(ctx, m.HostId, m.ServiceId, period)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't load SLA history of %q", m.Name)
+		}
+
+		t := h.timeline(period).restrict(mergeIntervals([]interval{m.window(period)}))
+		timelines = append(timelines, t)
+
+		total, problem := totalDuration(t.counted), totalDuration(t.problem)
+		result.Members = append(result.Members, MemberSla{
+			Member:       m,
+			Availability: percentage(total, problem),
+			TotalTime:    time.Duration(total) * time.Millisecond,
+			ProblemTime:  time.Duration(problem) * time.Millisecond,
+		})
+	}
+
+	switch mode {
+	case ModeMean:
+		var sum float64
+		var n int
+		for _, m := range result.Members {
+			if m.Availability != nil {
+				sum += *m.Availability
+				n++
+			}
+		}
+
+		if n > 0 {
+			mean := sum / float64(n)
+			result.Availability = &mean
+		}
+	default:
+		agg := aggregate(timelines)
+
+		problem := agg.anyProblem
+		if mode == ModeAllProblem {
+			problem = agg.allProblem
+		}
+
+		result.Availability = percentage(agg.total, problem)
+		result.TotalTime = time.Duration(agg.total) * time.Millisecond
+		result.ProblemTime = time.Duration(problem) * time.Millisecond
+	}
+
+	return result, nil
+}
@@ -0,0 +1,211 @@
+package reporting
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// IncidentStats summarizes the problem episodes of one or more checkables over a reporting period.
+//
+// A problem episode is a contiguous period during which a checkable was in a problem hard state, i.e.
+// any hard state but UP for hosts, CRITICAL or UNKNOWN for services. Consecutive problem states such as
+// DOWN followed by UNREACHABLE form a single episode. Episodes entirely covered by downtimes are considered
+// planned and are ignored, time spent in downtimes during other episodes does not count as outage.
+//
+// Episodes that were already ongoing at the start of the reporting period are included in Episodes,
+// but not in the mean values as their actual start is not known.
+// Mean values are zero if there was nothing to compute them from.
+type IncidentStats struct {
+	// Episodes is the number of problem episodes within the reporting period.
+	Episodes int `json:"episodes"`
+
+	// Ongoing is the number of episodes which had not yet recovered at the end of the reporting period.
+	Ongoing int `json:"ongoing"`
+
+	// Acknowledged is the number of episodes which were acknowledged.
+	Acknowledged int `json:"acknowledged"`
+
+	// MTTR is the mean time to recovery, i.e. the mean outage of recovered episodes.
+	MTTR time.Duration `json:"mttr"`
+
+	// MTBF is the mean time between failures, i.e. the mean time from the recovery of an episode
+	// to the start of the next episode of the same checkable.
+	MTBF time.Duration `json:"mtbf"`
+
+	// MTTA is the mean time to acknowledge, i.e. the mean time from the start of an episode to its first acknowledgement.
+	MTTA time.Duration `json:"mtta"`
+
+	// LongestOutage is the longest outage of any episode, including ongoing ones.
+	LongestOutage time.Duration `json:"longest_outage"`
+}
+
+// CheckableIncidentStats are the IncidentStats of a single checkable.
+type CheckableIncidentStats struct {
+	Member
+	IncidentStats
+}
+
+// IncidentReport contains the IncidentStats of some checkables and their combination over a reporting period.
+type IncidentReport struct {
+	Start      time.Time                `json:"start"`
+	End        time.Time                `json:"end"`
+	Total      IncidentStats            `json:"total"`
+	Checkables []CheckableIncidentStats `json:"checkables"`
+}
+
+// incidentAccumulator collects the figures of problem episodes to compute IncidentStats from.
+type incidentAccumulator struct {
+	episodes, ongoing, acknowledged int
+
+	ttrSum, ttrCount int64
+	tbfSum, tbfCount int64
+	ttaSum, ttaCount int64
+
+	longestOutage int64
+}
+
+// addEpisodes adds the problem episodes of a single checkable within period, given as sorted, disjoint problem
+// intervals regardless of downtimes, its downtimes, and the times acknowledgements were set in ascending order.
+func (a *incidentAccumulator) addEpisodes(period interval, episodes, downtimes []interval, acks []int64) {
+	downtimes = mergeIntervals(clipIntervals(downtimes, period))
+	var lastRecovery *int64
+
+	for _, e := range episodes {
+		outage := totalDuration(subtractIntervals([]interval{e}, downtimes))
+		if outage == 0 {
+			// Planned, i.e. entirely within downtimes.
+			continue
+		}
+
+		a.episodes++
+		a.longestOutage = max(a.longestOutage, outage)
+
+		// The episode may have started before the period, so its duration is unknown.
+		known := e.start > period.start
+		recovered := e.end < period.end
+
+		if !recovered {
+			a.ongoing++
+		}
+
+		if known && recovered {
+			a.ttrSum += outage
+			a.ttrCount++
+		}
+
+		if known && lastRecovery != nil {
+			a.tbfSum += e.start - *lastRecovery
+			a.tbfCount++
+		}
+
+		if recovered {
+			end := e.end
+			lastRecovery = &end
+		}
+
+		for _, ack := range acks {
+			if ack >= e.start && ack < e.end {
+				a.acknowledged++
+
+				if known {
+					a.ttaSum += ack - e.start
+					a.ttaCount++
+				}
+
+				break
+			}
+		}
+	}
+}
+
+// add adds the figures of another accumulator.
+func (a *incidentAccumulator) add(other incidentAccumulator) {
+	a.episodes += other.episodes
+	a.ongoing += other.ongoing
+	a.acknowledged += other.acknowledged
+	a.ttrSum += other.ttrSum
+	a.ttrCount += other.ttrCount
+	a.tbfSum += other.tbfSum
+	a.tbfCount += other.tbfCount
+	a.ttaSum += other.ttaSum
+	a.ttaCount += other.ttaCount
+	a.longestOutage = max(a.longestOutage, other.longestOutage)
+}
+
+// stats computes the IncidentStats from the collected figures.
+func (a incidentAccumulator) stats() IncidentStats {
+	mean := func(sum, count int64) time.Duration {
+		if count == 0 {
+			return 0
+		}
+
+		return time.Duration(sum/count) * time.Millisecond
+	}
+
+	return IncidentStats{
+		Episodes:      a.episodes,
+		Ongoing:       a.ongoing,
+		Acknowledged:  a.acknowledged,
+		MTTR:          mean(a.ttrSum, a.ttrCount),
+		MTBF:          mean(a.tbfSum, a.tbfCount),
+		MTTA:          mean(a.ttaSum, a.ttaCount),
+		LongestOutage: time.Duration(a.longestOutage) * time.Millisecond,
+	}
+}
+
+// IncidentStats computes the IncidentStats of the given checkables between start and end.
+// The Total is computed from the episodes of all checkables, not from their individual statistics.
+func (r *Reporter) IncidentStats(ctx context.Context, checkables []Member, start, end time.Time) (*IncidentReport, error) {
+	period, err := periodOf(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IncidentReport{Start: start, End: end, Checkables: make([]CheckableIncidentStats, 0, len(checkables))}
+	var total incidentAccumulator
+
+	for _, c := range checkables {
+		// Only consider the time the checkable was a member of the group, if any.
+		period := c.window(period)
+		if period.end <= period.start {
+			report.Checkables = append(report.Checkables, CheckableIncidentStats{Member: c})
+			continue
+		}
+
+		h, err := r.loadHistory(ctx, c.HostId, c.ServiceId, period)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't load SLA history of %q", c.Name)
+		}
+
+		acks, err := r.loadAcknowledgements(ctx, c, period)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't load acknowledgement history of %q", c.Name)
+		}
+
+		_, episodes := stateIntervals(period, h.initialState, h.events, h.isService)
+
+		var acc incidentAccumulator
+		acc.addEpisodes(period, episodes, h.downtimes, acks)
+		total.add(acc)
+
+		report.Checkables = append(report.Checkables, CheckableIncidentStats{Member: c, IncidentStats: acc.stats()})
+	}
+
+	report.Total = total.stats()
+
+	return report, nil
+}
+
+// loadAcknowledgements returns the times acknowledgements were set for the given checkable within period.
+func (r *Reporter) loadAcknowledgements(ctx context.Context, c Member, period interval) ([]int64, error) {
+	checkable, withArgs := checkableFilter(c.HostId, c.ServiceId)
+
+	var acks []int64
+	err := r.db.SelectContext(ctx, &acks, r.db.Rebind(
+		`SELECT set_time FROM acknowledgement_history WHERE `+checkable+
+			` AND set_time >= ? AND set_time < ? ORDER BY set_time`,
+	), withArgs(period.start, period.end)...)
+
+	return acks, errors.Wrap(err, "can't fetch acknowledgements")
+}
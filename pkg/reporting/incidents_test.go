@@ -0,0 +1,82 @@
+package reporting
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestIncidentAccumulator(t *testing.T) {
+	period := interval{0, 1000}
+
+	subtests := []struct {
+		name      string
+		episodes  []interval
+		downtimes []interval
+		acks      []int64
+		output    IncidentStats
+	}{
+		{name: "none"},
+		{
+			name:     "single",
+			episodes: []interval{{100, 200}},
+			acks:     []int64{50, 130, 150},
+			output: IncidentStats{
+				Episodes: 1, Acknowledged: 1,
+				MTTR: 100 * time.Millisecond, MTTA: 30 * time.Millisecond, LongestOutage: 100 * time.Millisecond,
+			},
+		},
+		{
+			name:     "multiple",
+			episodes: []interval{{100, 200}, {400, 700}, {900, 950}},
+			output: IncidentStats{
+				Episodes: 3,
+				MTTR:     150 * time.Millisecond, MTBF: 200 * time.Millisecond, LongestOutage: 300 * time.Millisecond,
+			},
+		},
+		{
+			name:     "ongoing",
+			episodes: []interval{{0, 100}, {300, 400}, {800, 1000}},
+			acks:     []int64{10, 850},
+			output: IncidentStats{
+				Episodes: 3, Ongoing: 1, Acknowledged: 2,
+				MTTR: 100 * time.Millisecond, MTBF: 300 * time.Millisecond, MTTA: 50 * time.Millisecond,
+				LongestOutage: 200 * time.Millisecond,
+			},
+		},
+		{
+			name:      "downtimes",
+			episodes:  []interval{{100, 200}, {400, 700}},
+			downtimes: []interval{{50, 250}, {400, 500}},
+			output: IncidentStats{
+				Episodes: 1,
+				MTTR:     200 * time.Millisecond, LongestOutage: 200 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			var acc incidentAccumulator
+			acc.addEpisodes(period, st.episodes, st.downtimes, st.acks)
+
+			require.Equal(t, st.output, acc.stats())
+		})
+	}
+}
+
+func TestIncidentAccumulator_Add(t *testing.T) {
+	period := interval{0, 1000}
+
+	var a, b, total incidentAccumulator
+	a.addEpisodes(period, []interval{{100, 200}}, nil, nil)
+	b.addEpisodes(period, []interval{{100, 400}, {500, 1000}}, nil, []int64{600})
+	total.add(a)
+	total.add(b)
+
+	require.Equal(t, IncidentStats{
+		Episodes: 3, Ongoing: 1, Acknowledged: 1,
+		MTTR: 200 * time.Millisecond, MTBF: 100 * time.Millisecond, MTTA: 100 * time.Millisecond,
+		LongestOutage: 500 * time.Millisecond,
+	}, total.stats())
+}
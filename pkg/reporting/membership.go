@@ -0,0 +1,121 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// GroupType is the type of group a report is computed for.
+type GroupType string
+
+const (
+	Hostgroup    GroupType = "hostgroup"
+	Servicegroup GroupType = "servicegroup"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (t *GroupType) UnmarshalText(text []byte) error {
+	switch gt := GroupType(text); gt {
+	case Hostgroup, Servicegroup:
+		*t = gt
+		return nil
+	default:
+		return fmt.Errorf("unknown group type %q", text)
+	}
+}
+
+// Group identifies a host or service group.
+type Group struct {
+	Type          GroupType    `json:"type"`
+	Id            types.Binary `json:"id"`
+	EnvironmentId types.Binary `json:"environment_id"`
+	Name          string       `json:"name"`
+}
+
+// Member is a checkable which belongs to a group during (parts of) the reporting period.
+type Member struct {
+	HostId    types.Binary `json:"host_id"`
+	ServiceId types.Binary `json:"service_id,omitempty"`
+	Name      string       `json:"name"`
+
+	// Since is the time the checkable joined the group. The zero value means before the reporting period.
+	Since time.Time `json:"since,omitzero"`
+
+	// Until is the time the checkable left the group. The zero value means after the reporting period.
+	Until time.Time `json:"until,omitzero"`
+}
+
+// IsService reports whether the member is a service.
+func (m Member) IsService() bool {
+	return m.ServiceId != nil
+}
+
+// window returns the part of period during which the checkable was a member.
+func (m Member) window(period interval) interval {
+	if !m.Since.IsZero() {
+		period.start = max(period.start, m.Since.UnixMilli())
+	}
+	if !m.Until.IsZero() {
+		period.end = min(period.end, m.Until.UnixMilli())
+	}
+
+	return period
+}
+
+// MembershipProvider returns the members of a group during a reporting period.
+//
+// Implementations which know about the configuration history can return checkables that left the group before
+// the end of the period and set Member.Since and Member.Until accordingly.
+type MembershipProvider interface {
+	Members(ctx context.Context, group Group, start, end time.Time) ([]Member, error)
+}
+
+// CurrentMembership is a MembershipProvider that uses the current group membership from the
+// hostgroup_member and servicegroup_member tables.
+//
+// Icinga DB does not keep a history of configuration changes. Thus, all current members are considered
+// to have been members for the whole reporting period and former members are not considered at all.
+type CurrentMembership struct {
+	db *database.DB
+}
+
+// NewCurrentMembership returns a new CurrentMembership.
+func NewCurrentMembership(db *database.DB) *CurrentMembership {
+	return &CurrentMembership{db: db}
+}
+
+// Members implements the MembershipProvider interface.
+func (c *CurrentMembership) Members(ctx context.Context, group Group, _, _ time.Time) ([]Member, error) {
+	var query string
+	switch group.Type {
+	case Hostgroup:
+		query = `SELECT h.id AS host_id, NULL AS service_id, h.name AS name
+FROM hostgroup_member m
+INNER JOIN host h ON h.id = m.host_id
+WHERE m.hostgroup_id = ?
+ORDER BY h.name`
+	case Servicegroup:
+		query = `SELECT s.host_id AS host_id, s.id AS service_id, CONCAT(h.name, '!', s.name) AS name
+FROM servicegroup_member m
+INNER JOIN service s ON s.id = m.service_id
+INNER JOIN host h ON h.id = s.host_id
+WHERE m.servicegroup_id = ?
+ORDER BY h.name, s.name`
+	default:
+		return nil, errors.Errorf("unknown group type %q", group.Type)
+	}
+
+	var members []Member
+	if err := c.db.SelectContext(ctx, &members, c.db.Rebind(query), group.Id); err != nil {
+		return nil, errors.Wrapf(err, "can't fetch members of %s %q", group.Type, group.Name)
+	}
+
+	return members, nil
+}
+
+// Assert interface compliance.
+var _ MembershipProvider = (*CurrentMembership)(nil)
@@ -0,0 +1,239 @@
+// Package reporting computes availability reports from the SLA history tables.
+package reporting
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/icinga/icinga-go-library/database"
+	"github.com/icinga/icinga-go-library/types"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Reporter computes reports from the database.
+type Reporter struct {
+	db      *database.DB
+	members MembershipProvider
+}
+
+// NewReporter returns a new Reporter. If members is nil, CurrentMembership is used.
+func NewReporter(db *database.DB, members MembershipProvider) *Reporter {
+	if members == nil {
+		members = NewCurrentMembership(db)
+	}
+
+	return &Reporter{db: db, members: members}
+}
+
+// Environment is an Icinga environment as stored in the environment table.
+type Environment struct {
+	Id   types.Binary `db:"id" json:"id"`
+	Name string       `db:"name" json:"name"`
+}
+
+// Environment returns the environment with the given name.
+// If name is empty and there is exactly one environment, that environment is returned.
+func (r *Reporter) Environment(ctx context.Context, name string) (*Environment, error) {
+	var envs []Environment
+	if err := r.db.SelectContext(ctx, &envs, "SELECT id, name FROM environment"); err != nil {
+		return nil, errors.Wrap(err, "can't fetch environments")
+	}
+
+	if name == "" {
+		if len(envs) == 1 {
+			return &envs[0], nil
+		}
+
+		return nil, errors.Errorf("there are %d environments, please specify one", len(envs))
+	}
+
+	for i := range envs {
+		if envs[i].Name == name {
+			return &envs[i], nil
+		}
+	}
+
+	return nil, errors.Errorf("environment %q not found", name)
+}
+
+// Group returns the group of the given type and name in the given environment.
+func (r *Reporter) Group(ctx context.Context, env *Environment, groupType GroupType, name string) (*Group, error) {
+	var table string
+	switch groupType {
+	case Hostgroup, Servicegroup:
+		table = string(groupType)
+	default:
+		return nil, errors.Errorf("unknown group type %q", groupType)
+	}
+
+	group := &Group{Type: groupType, EnvironmentId: env.Id, Name: name}
+	query := r.db.Rebind(fmt.Sprintf(`SELECT id FROM %s WHERE environment_id = ? AND name = ?`, table)) // #nosec G201 -- table is a constant
+
+	err := r.db.GetContext(ctx, &group.Id, query, env.Id, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Errorf("%s %q not found", groupType, name)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't fetch %s %q", groupType, name)
+	}
+
+	return group, nil
+}
+
+// checkableHistory is the SLA history of a single checkable within a period.
+type checkableHistory struct {
+	isService    bool
+	initialState uint8
+	events       []stateEvent
+	downtimes    []interval
+}
+
+// timeline computes the timeline of the checkable within period.
+func (h checkableHistory) timeline(period interval) timeline {
+	return buildTimeline(period, h.initialState, h.events, h.downtimes, h.isService)
+}
+
+// checkableFilter returns an SQL condition and its arguments which select the rows of the given checkable
+// from tables with host_id and service_id columns.
+func checkableFilter(hostId, serviceId types.Binary) (string, func(extra ...any) []any) {
+	condition := `host_id = ? AND service_id IS NULL`
+	args := []any{hostId}
+	if serviceId != nil {
+		condition = `host_id = ? AND service_id = ?`
+		args = append(args, serviceId)
+	}
+
+	return condition, func(extra ...any) []any {
+		return append(append([]any{}, args...), extra...)
+	}
+}
+
+// loadHistory loads the SLA history of a checkable within period.
+func (r *Reporter) loadHistory(ctx context.Context, hostId, serviceId types.Binary, period interval) (checkableHistory, error) {
+	isService := serviceId != nil
+	checkable, withArgs := checkableFilter(hostId, serviceId)
+
+	// Use the latest event at or before the beginning of the period as the initial state,
+	// the previous state of the first event after it, or the current state, just like get_sla_ok_percent does.
+	type query struct {
+		sql  string
+		args []any
+	}
+
+	current := query{`SELECT hard_state FROM host_state WHERE host_id = ?`, []any{hostId}}
+	if isService {
+		current = query{`SELECT hard_state FROM service_state WHERE service_id = ?`, []any{serviceId}}
+	}
+
+	var initial sql.Null[uint8]
+	for _, q := range []query{{
+		`SELECT hard_state FROM sla_history_state WHERE ` + checkable +
+			` AND event_time <= ? ORDER BY event_time DESC LIMIT 1`,
+		withArgs(period.start),
+	}, {
+		`SELECT previous_hard_state FROM sla_history_state WHERE ` + checkable +
+			` AND event_time > ? ORDER BY event_time ASC LIMIT 1`,
+		withArgs(period.start),
+	}, current} {
+		err := r.db.GetContext(ctx, &initial, r.db.Rebind(q.sql), q.args...)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return checkableHistory{}, errors.Wrap(err, "can't fetch initial state")
+		}
+
+		if initial.Valid {
+			break
+		}
+	}
+
+	var events []stateEvent
+	err := r.db.SelectContext(ctx, &events, r.db.Rebind(
+		`SELECT event_time, hard_state, previous_hard_state FROM sla_history_state WHERE `+checkable+
+			` AND event_time > ? AND event_time < ? ORDER BY event_time`,
+	), withArgs(period.start, period.end)...)
+	if err != nil {
+		return checkableHistory{}, errors.Wrap(err, "can't fetch state history")
+	}
+
+	var rows []struct {
+		Start int64 `db:"downtime_start"`
+		End   int64 `db:"downtime_end"`
+	}
+	err = r.db.SelectContext(ctx, &rows, r.db.Rebind(
+		`SELECT downtime_start, downtime_end FROM sla_history_downtime WHERE `+checkable+
+			` AND downtime_start < ? AND downtime_end >= ?`,
+	), withArgs(period.end, period.start)...)
+	if err != nil {
+		return checkableHistory{}, errors.Wrap(err, "can't fetch downtime history")
+	}
+
+	downtimes := make([]interval, 0, len(rows))
+	for _, row := range rows {
+		downtimes = append(downtimes, interval{row.Start, row.End})
+	}
+
+	return checkableHistory{
+		isService:    isService,
+		initialState: initial.V,
+		events:       events,
+		downtimes:    downtimes,
+	}, nil
+}
+
+// percentage returns the share of good time in total time in percent.
+// If total is zero, the result is nil as there is nothing to report on.
+func percentage(total, problem int64) *float64 {
+	if total <= 0 {
+		return nil
+	}
+
+	p := 100 * float64(total-problem) / float64(total)
+
+	return &p
+}
+
+// periodOf converts start and end to an interval and checks that it is not empty.
+func periodOf(start, end time.Time) (interval, error) {
+	period := interval{start.UnixMilli(), end.UnixMilli()}
+	if period.end <= period.start {
+		return interval{}, errors.New("end time must be greater than start time")
+	}
+
+	return period, nil
+}
+
+// Checkable returns the host or, if service is not empty, the service with the given name in the given environment.
+func (r *Reporter) Checkable(ctx context.Context, env *Environment, host, service string) (*Member, error) {
+	m := &Member{Name: host}
+
+	err := r.db.GetContext(ctx, &m.HostId, r.db.Rebind(
+		"SELECT id FROM host WHERE environment_id = ? AND name = ?",
+	), env.Id, host)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Errorf("host %q not found", host)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't fetch host %q", host)
+	}
+
+	if service != "" {
+		m.Name = host + "!" + service
+
+		err := r.db.GetContext(ctx, &m.ServiceId, r.db.Rebind(
+			"SELECT id FROM service WHERE host_id = ? AND name = ?",
+		), m.HostId, service)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Errorf("service %q not found", m.Name)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't fetch service %q", m.Name)
+		}
+	}
+
+	return m, nil
+}
+
+// Members returns the members of the given group between start and end according to the MembershipProvider.
+func (r *Reporter) Members(ctx context.Context, group Group, start, end time.Time) ([]Member, error) {
+	return r.members.Members(ctx, group, start, end)
+}
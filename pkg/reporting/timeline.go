@@ -0,0 +1,251 @@
+package reporting
+
+import (
+	"cmp"
+	"slices"
+)
+
+// interval is a half-open time range [start, end) in Unix milliseconds.
+type interval struct {
+	start int64
+	end   int64
+}
+
+// duration returns the length of the interval in milliseconds.
+func (i interval) duration() int64 {
+	return i.end - i.start
+}
+
+// mergeIntervals returns the union of the given intervals as a sorted list of disjoint, non-empty intervals.
+// Adjacent intervals are joined.
+func mergeIntervals(intervals []interval) []interval {
+	sorted := make([]interval, 0, len(intervals))
+	for _, i := range intervals {
+		if i.end > i.start {
+			sorted = append(sorted, i)
+		}
+	}
+
+	slices.SortFunc(sorted, func(a, b interval) int {
+		return cmp.Compare(a.start, b.start)
+	})
+
+	var merged []interval
+	for _, i := range sorted {
+		if n := len(merged); n > 0 && i.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, i.end)
+		} else {
+			merged = append(merged, i)
+		}
+	}
+
+	return merged
+}
+
+// subtractIntervals returns the parts of a not covered by b.
+// Both a and b must be sorted lists of disjoint intervals as returned by mergeIntervals.
+func subtractIntervals(a, b []interval) []interval {
+	var result []interval
+	j := 0
+
+	for _, i := range a {
+		for j < len(b) && b[j].end <= i.start {
+			j++
+		}
+
+		start := i.start
+		for k := j; k < len(b) && b[k].start < i.end; k++ {
+			if b[k].start > start {
+				result = append(result, interval{start, b[k].start})
+			}
+
+			start = max(start, b[k].end)
+		}
+
+		if start < i.end {
+			result = append(result, interval{start, i.end})
+		}
+	}
+
+	return result
+}
+
+// clipIntervals returns the parts of the given sorted, disjoint intervals which lie within window.
+func clipIntervals(intervals []interval, window interval) []interval {
+	var result []interval
+	for _, i := range intervals {
+		i.start = max(i.start, window.start)
+		i.end = min(i.end, window.end)
+		if i.end > i.start {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+// totalDuration returns the sum of the durations of the given disjoint intervals.
+func totalDuration(intervals []interval) (total int64) {
+	for _, i := range intervals {
+		total += i.duration()
+	}
+
+	return total
+}
+
+// timeline describes the availability of a single checkable over a reporting period.
+type timeline struct {
+	// counted is the time which counts towards the SLA, i.e. the checkable was a group member and had a known state.
+	counted []interval
+
+	// problem is the time during which the checkable had a problem outside of downtimes. It is a subset of counted.
+	problem []interval
+}
+
+// stateEvent is a hard state change as stored in the sla_history_state table.
+type stateEvent struct {
+	EventTime         int64 `db:"event_time"`
+	HardState         uint8 `db:"hard_state"`
+	PreviousHardState uint8 `db:"previous_hard_state"`
+}
+
+// pendingState is the hard state value Icinga 2 uses for checkables that have not been checked yet.
+const pendingState = 99
+
+// isProblem reports whether the given hard state counts as a problem.
+// For hosts, everything but UP (0) is a problem, for services, everything but OK (0) and WARNING (1).
+func isProblem(hardState uint8, isService bool) bool {
+	if hardState == pendingState {
+		return false
+	}
+
+	if isService {
+		return hardState > 1
+	}
+
+	return hardState > 0
+}
+
+// buildTimeline computes the timeline of a checkable within period from its initial hard state,
+// the state events strictly within the period ordered by event time, and its downtimes.
+//
+// This follows the semantics of the get_sla_ok_percent SQL function: Time spent in the pending state is not
+// counted at all and problem time during downtimes is not considered a problem.
+func buildTimeline(period interval, initialState uint8, events []stateEvent, downtimes []interval, isService bool) timeline {
+	counted, problem := stateIntervals(period, initialState, events, isService)
+
+	return timeline{
+		counted: counted,
+		problem: subtractIntervals(problem, mergeIntervals(clipIntervals(downtimes, period))),
+	}
+}
+
+// stateIntervals returns the time within period during which the state of a checkable was known
+// and the time during which it was in a problem state, regardless of downtimes.
+// The arguments are the same as for buildTimeline.
+func stateIntervals(period interval, initialState uint8, events []stateEvent, isService bool) (counted, problem []interval) {
+	lastState := initialState
+	lastTime := period.start
+
+	segment := func(end int64, pending bool) {
+		if end <= lastTime {
+			return
+		}
+
+		if !pending {
+			counted = append(counted, interval{lastTime, end})
+
+			if isProblem(lastState, isService) {
+				problem = append(problem, interval{lastTime, end})
+			}
+		}
+
+		lastTime = end
+	}
+
+	for _, e := range events {
+		if e.EventTime <= period.start || e.EventTime >= period.end {
+			continue
+		}
+
+		segment(e.EventTime, e.PreviousHardState == pendingState)
+		lastState = e.HardState
+	}
+
+	segment(period.end, false)
+
+	return mergeIntervals(counted), mergeIntervals(problem)
+}
+
+// restrict limits the timeline to the given sorted list of disjoint membership intervals.
+func (t timeline) restrict(membership []interval) timeline {
+	outside := subtractIntervals(t.counted, membership)
+
+	return timeline{
+		counted: subtractIntervals(t.counted, outside),
+		problem: subtractIntervals(t.problem, outside),
+	}
+}
+
+// aggregation holds the result of combining multiple timelines.
+type aggregation struct {
+	// total is the time during which at least one timeline counted.
+	total int64
+
+	// anyProblem is the time during which at least one counted timeline had a problem.
+	anyProblem int64
+
+	// allProblem is the time during which all counted timelines had a problem.
+	allProblem int64
+}
+
+// aggregate sweeps over all given timelines at once and determines for how long any or all of them had a problem.
+// Timelines that do not count at a given point in time are ignored for that point.
+func aggregate(timelines []timeline) aggregation {
+	type boundary struct {
+		time    int64
+		counted int
+		problem int
+	}
+
+	var boundaries []boundary
+	for _, t := range timelines {
+		for _, i := range t.counted {
+			boundaries = append(boundaries, boundary{i.start, 1, 0}, boundary{i.end, -1, 0})
+		}
+
+		for _, i := range t.problem {
+			boundaries = append(boundaries, boundary{i.start, 0, 1}, boundary{i.end, 0, -1})
+		}
+	}
+
+	slices.SortFunc(boundaries, func(a, b boundary) int {
+		return cmp.Compare(a.time, b.time)
+	})
+
+	var agg aggregation
+	var counted, problem int
+
+	for i, b := range boundaries {
+		if i > 0 && b.time > boundaries[i-1].time {
+			d := b.time - boundaries[i-1].time
+
+			if counted > 0 {
+				agg.total += d
+
+				if problem > 0 {
+					agg.anyProblem += d
+				}
+
+				if problem == counted {
+					agg.allProblem += d
+				}
+			}
+		}
+
+		counted += b.counted
+		problem += b.problem
+	}
+
+	return agg
+}
@@ -0,0 +1,163 @@
+package reporting
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMergeIntervals(t *testing.T) {
+	subtests := []struct {
+		name   string
+		input  []interval
+		output []interval
+	}{
+		{"nil", nil, nil},
+		{"empty-interval", []interval{{5, 5}}, nil},
+		{"single", []interval{{1, 2}}, []interval{{1, 2}}},
+		{"disjoint", []interval{{5, 6}, {1, 2}}, []interval{{1, 2}, {5, 6}}},
+		{"adjacent", []interval{{1, 2}, {2, 3}}, []interval{{1, 3}}},
+		{"overlapping", []interval{{1, 4}, {2, 3}, {3, 6}}, []interval{{1, 6}}},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.output, mergeIntervals(st.input))
+		})
+	}
+}
+
+func TestSubtractIntervals(t *testing.T) {
+	subtests := []struct {
+		name   string
+		a      []interval
+		b      []interval
+		output []interval
+	}{
+		{"nothing", []interval{{1, 10}}, nil, []interval{{1, 10}}},
+		{"everything", []interval{{1, 10}}, []interval{{0, 11}}, nil},
+		{"middle", []interval{{1, 10}}, []interval{{3, 5}}, []interval{{1, 3}, {5, 10}}},
+		{"edges", []interval{{1, 10}}, []interval{{0, 2}, {9, 12}}, []interval{{2, 9}}},
+		{"multiple", []interval{{1, 5}, {6, 10}}, []interval{{4, 7}}, []interval{{1, 4}, {7, 10}}},
+		{"spanning", []interval{{1, 3}, {4, 6}, {7, 9}}, []interval{{2, 8}}, []interval{{1, 2}, {8, 9}}},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.output, subtractIntervals(st.a, st.b))
+		})
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	period := interval{0, 100}
+
+	subtests := []struct {
+		name      string
+		initial   uint8
+		events    []stateEvent
+		downtimes []interval
+		isService bool
+		output    timeline
+	}{
+		{
+			name:   "always-up",
+			output: timeline{counted: []interval{{0, 100}}},
+		},
+		{
+			name:    "always-down",
+			initial: 1,
+			output:  timeline{counted: []interval{{0, 100}}, problem: []interval{{0, 100}}},
+		},
+		{
+			name:      "service-warning-is-ok",
+			initial:   1,
+			isService: true,
+			output:    timeline{counted: []interval{{0, 100}}},
+		},
+		{
+			name:    "state-changes",
+			initial: 0,
+			events:  []stateEvent{{20, 1, 0}, {50, 0, 1}, {80, 2, 0}},
+			output:  timeline{counted: []interval{{0, 100}}, problem: []interval{{20, 50}, {80, 100}}},
+		},
+		{
+			name:      "downtime",
+			initial:   1,
+			downtimes: []interval{{-10, 10}, {40, 60}, {90, 200}},
+			output:    timeline{counted: []interval{{0, 100}}, problem: []interval{{10, 40}, {60, 90}}},
+		},
+		{
+			name:    "pending",
+			initial: pendingState,
+			events:  []stateEvent{{30, 1, pendingState}, {60, 0, 1}},
+			output:  timeline{counted: []interval{{30, 100}}, problem: []interval{{30, 60}}},
+		},
+		{
+			name:    "events-outside-period",
+			initial: 1,
+			events:  []stateEvent{{0, 0, 1}, {100, 0, 1}},
+			output:  timeline{counted: []interval{{0, 100}}, problem: []interval{{0, 100}}},
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.output, buildTimeline(period, st.initial, st.events, st.downtimes, st.isService))
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	subtests := []struct {
+		name      string
+		timelines []timeline
+		output    aggregation
+	}{
+		{"none", nil, aggregation{}},
+		{
+			name: "single",
+			timelines: []timeline{
+				{counted: []interval{{0, 100}}, problem: []interval{{10, 30}}},
+			},
+			output: aggregation{total: 100, anyProblem: 20, allProblem: 20},
+		},
+		{
+			name: "overlapping-problems",
+			timelines: []timeline{
+				{counted: []interval{{0, 100}}, problem: []interval{{10, 30}}},
+				{counted: []interval{{0, 100}}, problem: []interval{{20, 40}}},
+			},
+			output: aggregation{total: 100, anyProblem: 30, allProblem: 10},
+		},
+		{
+			name: "membership-changes",
+			timelines: []timeline{
+				{counted: []interval{{0, 50}}, problem: []interval{{0, 50}}},
+				{counted: []interval{{40, 100}}},
+			},
+			output: aggregation{total: 100, anyProblem: 50, allProblem: 40},
+		},
+		{
+			name: "gap",
+			timelines: []timeline{
+				{counted: []interval{{0, 10}}, problem: []interval{{0, 10}}},
+				{counted: []interval{{20, 30}}},
+			},
+			output: aggregation{total: 20, anyProblem: 10, allProblem: 10},
+		},
+	}
+
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			require.Equal(t, st.output, aggregate(st.timelines))
+		})
+	}
+}
+
+func TestTimeline_Restrict(t *testing.T) {
+	tl := timeline{counted: []interval{{0, 100}}, problem: []interval{{10, 30}, {60, 80}}}
+
+	require.Equal(t,
+		timeline{counted: []interval{{20, 70}}, problem: []interval{{20, 30}, {60, 70}}},
+		tl.restrict([]interval{{20, 70}}))
+}
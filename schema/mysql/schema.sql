@@ -539,6 +539,11 @@ CREATE TABLE icingadb_instance (
   endpoint_id binary(20) DEFAULT NULL COMMENT 'endpoint.id',
   heartbeat bigint unsigned NOT NULL COMMENT '*nix timestamp',
   responsible enum('n', 'y') NOT NULL,
+  priority smallint unsigned NOT NULL DEFAULT 0,
+  standby enum('n', 'y') NOT NULL DEFAULT 'n',
+  clock_skew_redis bigint NOT NULL DEFAULT 0 COMMENT 'milliseconds',
+  clock_skew_database bigint NOT NULL DEFAULT 0 COMMENT 'milliseconds',
+  clock_skew_icinga2 bigint NOT NULL DEFAULT 0 COMMENT 'milliseconds',
 
   icinga2_version varchar(255) NOT NULL,
   icinga2_start_time bigint unsigned NOT NULL,
@@ -1408,6 +1413,115 @@ CREATE TABLE dependency_edge (
   UNIQUE INDEX idx_dependency_edge_from_node_to_node_id (from_node_id, to_node_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
 
+CREATE TABLE perfdata_series (
+  id binary(20) NOT NULL COMMENT 'sha1(environment.id + host.id + service.id + label)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  object_type enum('host', 'service') NOT NULL,
+  host_id binary(20) NOT NULL COMMENT 'host.id',
+  service_id binary(20) DEFAULT NULL COMMENT 'service.id',
+
+  label varchar(255) NOT NULL,
+  unit varchar(255) NOT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_series_host_service_id (host_id, service_id) COMMENT 'Performance data series of a host/service'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE perfdata_sample (
+  id binary(20) NOT NULL COMMENT 'sha1(perfdata_series.id + sample_time)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  series_id binary(20) NOT NULL COMMENT 'perfdata_series.id',
+
+  sample_time bigint unsigned NOT NULL COMMENT 'unix timestamp of the check result',
+  value double NOT NULL,
+  warn varchar(255) DEFAULT NULL COMMENT 'warning threshold range',
+  crit varchar(255) DEFAULT NULL COMMENT 'critical threshold range',
+  min double DEFAULT NULL,
+  max double DEFAULT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_sample_series_sample_time (series_id, sample_time) COMMENT 'Performance data graphs',
+  INDEX idx_perfdata_sample_env_sample_time (environment_id, sample_time) COMMENT 'Filter for perfdata rollups and retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE perfdata_hourly (
+  id binary(20) NOT NULL COMMENT 'sha1(perfdata_series.id + bucket_start)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  series_id binary(20) NOT NULL COMMENT 'perfdata_series.id',
+
+  bucket_start bigint unsigned NOT NULL COMMENT 'unix timestamp of the beginning of the hour',
+  sample_count int unsigned NOT NULL,
+  min_value double NOT NULL,
+  max_value double NOT NULL,
+  avg_value double NOT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_hourly_series_bucket_start (series_id, bucket_start) COMMENT 'Performance data graphs',
+  INDEX idx_perfdata_hourly_env_bucket_start (environment_id, bucket_start) COMMENT 'Filter for perfdata rollups and retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE perfdata_daily (
+  id binary(20) NOT NULL COMMENT 'sha1(perfdata_series.id + bucket_start)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  series_id binary(20) NOT NULL COMMENT 'perfdata_series.id',
+
+  bucket_start bigint unsigned NOT NULL COMMENT 'unix timestamp of the beginning of the day (UTC)',
+  sample_count int unsigned NOT NULL,
+  min_value double NOT NULL,
+  max_value double NOT NULL,
+  avg_value double NOT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_daily_series_bucket_start (series_id, bucket_start) COMMENT 'Performance data graphs',
+  INDEX idx_perfdata_daily_env_bucket_start (environment_id, bucket_start) COMMENT 'Filter for perfdata rollups and retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE notifications_checkpoint (
+  id binary(20) NOT NULL COMMENT 'history.id',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  history_type enum('acknowledgement', 'downtime', 'flapping', 'comment', 'notification') NOT NULL,
+  submit_time bigint unsigned NOT NULL COMMENT 'unix timestamp the event was submitted to Icinga Notifications',
+
+  PRIMARY KEY (id),
+
+  INDEX idx_notifications_checkpoint_submit_time (submit_time) COMMENT 'Filter for checkpoint cleanup'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE notifications_outbox (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  object_id binary(20) NOT NULL COMMENT 'host.id or service.id',
+  object_type enum('host', 'service') NOT NULL,
+  state longtext NOT NULL COMMENT 'JSON-encoded host or service state',
+  enqueue_time bigint unsigned NOT NULL,
+  attempts int unsigned NOT NULL DEFAULT 0,
+  last_attempt_time bigint unsigned DEFAULT NULL,
+  last_error text DEFAULT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_notifications_outbox_environment_id (environment_id, id) COMMENT 'Filter for queued states of an environment'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE icingadb_ha_history (
+  id binary(16) NOT NULL COMMENT 'UUIDv4',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  instance_id binary(16) NOT NULL COMMENT 'icingadb_instance.id',
+  endpoint_id binary(20) DEFAULT NULL COMMENT 'endpoint.id',
+  event_type enum('takeover', 'handover') NOT NULL,
+  reason varchar(255) NOT NULL,
+  event_time bigint unsigned NOT NULL,
+  sync_duration bigint unsigned DEFAULT NULL COMMENT 'milliseconds until the config sync after a takeover finished',
+
+  PRIMARY KEY (id),
+
+  INDEX idx_icingadb_ha_history_env_event_time (environment_id, event_time) COMMENT 'Filter for history retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
 CREATE TABLE icingadb_schema (
   id int unsigned NOT NULL AUTO_INCREMENT,
   version smallint unsigned NOT NULL,
@@ -0,0 +1,4 @@
+ALTER TABLE icingadb_instance
+  ADD COLUMN clock_skew_redis bigint NOT NULL DEFAULT 0 COMMENT 'milliseconds',
+  ADD COLUMN clock_skew_database bigint NOT NULL DEFAULT 0 COMMENT 'milliseconds',
+  ADD COLUMN clock_skew_icinga2 bigint NOT NULL DEFAULT 0 COMMENT 'milliseconds';
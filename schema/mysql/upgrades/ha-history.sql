@@ -0,0 +1,14 @@
+CREATE TABLE icingadb_ha_history (
+  id binary(16) NOT NULL COMMENT 'UUIDv4',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  instance_id binary(16) NOT NULL COMMENT 'icingadb_instance.id',
+  endpoint_id binary(20) DEFAULT NULL COMMENT 'endpoint.id',
+  event_type enum('takeover', 'handover') NOT NULL,
+  reason varchar(255) NOT NULL,
+  event_time bigint unsigned NOT NULL,
+  sync_duration bigint unsigned DEFAULT NULL COMMENT 'milliseconds until the config sync after a takeover finished',
+
+  PRIMARY KEY (id),
+
+  INDEX idx_icingadb_ha_history_env_event_time (environment_id, event_time) COMMENT 'Filter for history retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
@@ -0,0 +1 @@
+ALTER TABLE icingadb_instance ADD COLUMN priority smallint unsigned NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE icingadb_instance ADD COLUMN standby enum('n', 'y') NOT NULL DEFAULT 'n';
@@ -0,0 +1 @@
+ALTER TABLE notifications_checkpoint MODIFY COLUMN history_type enum('acknowledgement', 'downtime', 'flapping', 'comment', 'notification') NOT NULL;
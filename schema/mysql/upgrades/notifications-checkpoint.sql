@@ -0,0 +1,10 @@
+CREATE TABLE notifications_checkpoint (
+  id binary(20) NOT NULL COMMENT 'history.id',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  history_type enum('acknowledgement', 'downtime', 'flapping', 'comment', 'notification') NOT NULL,
+  submit_time bigint unsigned NOT NULL COMMENT 'unix timestamp the event was submitted to Icinga Notifications',
+
+  PRIMARY KEY (id),
+
+  INDEX idx_notifications_checkpoint_submit_time (submit_time) COMMENT 'Filter for checkpoint cleanup'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
@@ -0,0 +1,15 @@
+CREATE TABLE notifications_outbox (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  object_id binary(20) NOT NULL COMMENT 'host.id or service.id',
+  object_type enum('host', 'service') NOT NULL,
+  state longtext NOT NULL COMMENT 'JSON-encoded host or service state',
+  enqueue_time bigint unsigned NOT NULL,
+  attempts int unsigned NOT NULL DEFAULT 0,
+  last_attempt_time bigint unsigned DEFAULT NULL,
+  last_error text DEFAULT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_notifications_outbox_environment_id (environment_id, id) COMMENT 'Filter for queued states of an environment'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
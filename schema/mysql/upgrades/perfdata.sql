@@ -0,0 +1,66 @@
+CREATE TABLE perfdata_series (
+  id binary(20) NOT NULL COMMENT 'sha1(environment.id + host.id + service.id + label)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  object_type enum('host', 'service') NOT NULL,
+  host_id binary(20) NOT NULL COMMENT 'host.id',
+  service_id binary(20) DEFAULT NULL COMMENT 'service.id',
+
+  label varchar(255) NOT NULL,
+  unit varchar(255) NOT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_series_host_service_id (host_id, service_id) COMMENT 'Performance data series of a host/service'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE perfdata_sample (
+  id binary(20) NOT NULL COMMENT 'sha1(perfdata_series.id + sample_time)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  series_id binary(20) NOT NULL COMMENT 'perfdata_series.id',
+
+  sample_time bigint unsigned NOT NULL COMMENT 'unix timestamp of the check result',
+  value double NOT NULL,
+  warn varchar(255) DEFAULT NULL COMMENT 'warning threshold range',
+  crit varchar(255) DEFAULT NULL COMMENT 'critical threshold range',
+  min double DEFAULT NULL,
+  max double DEFAULT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_sample_series_sample_time (series_id, sample_time) COMMENT 'Performance data graphs',
+  INDEX idx_perfdata_sample_env_sample_time (environment_id, sample_time) COMMENT 'Filter for perfdata rollups and retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE perfdata_hourly (
+  id binary(20) NOT NULL COMMENT 'sha1(perfdata_series.id + bucket_start)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  series_id binary(20) NOT NULL COMMENT 'perfdata_series.id',
+
+  bucket_start bigint unsigned NOT NULL COMMENT 'unix timestamp of the beginning of the hour',
+  sample_count int unsigned NOT NULL,
+  min_value double NOT NULL,
+  max_value double NOT NULL,
+  avg_value double NOT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_hourly_series_bucket_start (series_id, bucket_start) COMMENT 'Performance data graphs',
+  INDEX idx_perfdata_hourly_env_bucket_start (environment_id, bucket_start) COMMENT 'Filter for perfdata rollups and retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
+
+CREATE TABLE perfdata_daily (
+  id binary(20) NOT NULL COMMENT 'sha1(perfdata_series.id + bucket_start)',
+  environment_id binary(20) NOT NULL COMMENT 'environment.id',
+  series_id binary(20) NOT NULL COMMENT 'perfdata_series.id',
+
+  bucket_start bigint unsigned NOT NULL COMMENT 'unix timestamp of the beginning of the day (UTC)',
+  sample_count int unsigned NOT NULL,
+  min_value double NOT NULL,
+  max_value double NOT NULL,
+  avg_value double NOT NULL,
+
+  PRIMARY KEY (id),
+
+  INDEX idx_perfdata_daily_series_bucket_start (series_id, bucket_start) COMMENT 'Performance data graphs',
+  INDEX idx_perfdata_daily_env_bucket_start (environment_id, bucket_start) COMMENT 'Filter for perfdata rollups and retention'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin ROW_FORMAT=DYNAMIC;
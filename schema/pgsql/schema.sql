@@ -28,6 +28,7 @@ CREATE TYPE notification_type AS ENUM ( 'downtime_start', 'downtime_end', 'downt
 -- mechanics, downtimes are semi-automatic, require user action (or configuration) and change mechanics, acks are pure
 -- user actions and change mechanics.
 CREATE TYPE history_type AS ENUM ( 'state_change', 'ack_clear', 'downtime_end', 'flapping_end', 'comment_remove', 'comment_add', 'flapping_start', 'downtime_start', 'ack_set', 'notification' );
+CREATE TYPE notifications_checkpoint_history_type AS ENUM ( 'acknowledgement', 'downtime', 'flapping', 'comment', 'notification' );
 
 CREATE OR REPLACE FUNCTION get_sla_ok_percent(
   in_host_id bytea20,
@@ -790,6 +791,11 @@ CREATE TABLE icingadb_instance (
   endpoint_id bytea20 DEFAULT NULL,
   heartbeat biguint NOT NULL,
   responsible boolenum NOT NULL DEFAULT 'n',
+  priority smalluint NOT NULL DEFAULT 0,
+  standby boolenum NOT NULL DEFAULT 'n',
+  clock_skew_redis bigint NOT NULL DEFAULT 0,
+  clock_skew_database bigint NOT NULL DEFAULT 0,
+  clock_skew_icinga2 bigint NOT NULL DEFAULT 0,
 
   icinga2_version varchar(255) NOT NULL,
   icinga2_start_time biguint NOT NULL,
@@ -816,6 +822,9 @@ COMMENT ON COLUMN icingadb_instance.id IS 'UUIDv4';
 COMMENT ON COLUMN icingadb_instance.environment_id IS 'environment.id';
 COMMENT ON COLUMN icingadb_instance.endpoint_id IS 'endpoint.id';
 COMMENT ON COLUMN icingadb_instance.heartbeat IS '*nix timestamp';
+COMMENT ON COLUMN icingadb_instance.clock_skew_redis IS 'milliseconds';
+COMMENT ON COLUMN icingadb_instance.clock_skew_database IS 'milliseconds';
+COMMENT ON COLUMN icingadb_instance.clock_skew_icinga2 IS 'milliseconds';
 
 CREATE TABLE checkcommand (
   id bytea20 NOT NULL,
@@ -2289,6 +2298,202 @@ COMMENT ON COLUMN dependency_edge.from_node_id IS 'dependency_node.id';
 COMMENT ON COLUMN dependency_edge.to_node_id IS 'dependency_node.id';
 COMMENT ON COLUMN dependency_edge.dependency_edge_state_id IS 'sha1(dependency_edge_state.id)';
 
+CREATE TABLE perfdata_series (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  object_type checkable_type NOT NULL,
+  host_id bytea20 NOT NULL,
+  service_id bytea20 DEFAULT NULL,
+
+  label varchar(255) NOT NULL,
+  unit varchar(255) NOT NULL,
+
+  CONSTRAINT pk_perfdata_series PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_series ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_series ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_series ALTER COLUMN host_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_series ALTER COLUMN service_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_series_host_service_id ON perfdata_series(host_id, service_id);
+
+COMMENT ON COLUMN perfdata_series.id IS 'sha1(environment.id + host.id + service.id + label)';
+COMMENT ON COLUMN perfdata_series.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_series.host_id IS 'host.id';
+COMMENT ON COLUMN perfdata_series.service_id IS 'service.id';
+
+COMMENT ON INDEX idx_perfdata_series_host_service_id IS 'Performance data series of a host/service';
+
+CREATE TABLE perfdata_sample (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  series_id bytea20 NOT NULL,
+
+  sample_time biguint NOT NULL,
+  value double precision NOT NULL,
+  warn varchar(255) DEFAULT NULL,
+  crit varchar(255) DEFAULT NULL,
+  min double precision DEFAULT NULL,
+  max double precision DEFAULT NULL,
+
+  CONSTRAINT pk_perfdata_sample PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_sample ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_sample ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_sample ALTER COLUMN series_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_sample_series_sample_time ON perfdata_sample(series_id, sample_time);
+CREATE INDEX idx_perfdata_sample_env_sample_time ON perfdata_sample(environment_id, sample_time);
+
+COMMENT ON COLUMN perfdata_sample.id IS 'sha1(perfdata_series.id + sample_time)';
+COMMENT ON COLUMN perfdata_sample.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_sample.series_id IS 'perfdata_series.id';
+COMMENT ON COLUMN perfdata_sample.sample_time IS 'unix timestamp of the check result';
+COMMENT ON COLUMN perfdata_sample.warn IS 'warning threshold range';
+COMMENT ON COLUMN perfdata_sample.crit IS 'critical threshold range';
+
+COMMENT ON INDEX idx_perfdata_sample_series_sample_time IS 'Performance data graphs';
+COMMENT ON INDEX idx_perfdata_sample_env_sample_time IS 'Filter for perfdata rollups and retention';
+
+CREATE TABLE perfdata_hourly (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  series_id bytea20 NOT NULL,
+
+  bucket_start biguint NOT NULL,
+  sample_count uint NOT NULL,
+  min_value double precision NOT NULL,
+  max_value double precision NOT NULL,
+  avg_value double precision NOT NULL,
+
+  CONSTRAINT pk_perfdata_hourly PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_hourly ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_hourly ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_hourly ALTER COLUMN series_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_hourly_series_bucket_start ON perfdata_hourly(series_id, bucket_start);
+CREATE INDEX idx_perfdata_hourly_env_bucket_start ON perfdata_hourly(environment_id, bucket_start);
+
+COMMENT ON COLUMN perfdata_hourly.id IS 'sha1(perfdata_series.id + bucket_start)';
+COMMENT ON COLUMN perfdata_hourly.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_hourly.series_id IS 'perfdata_series.id';
+COMMENT ON COLUMN perfdata_hourly.bucket_start IS 'unix timestamp of the beginning of the hour';
+
+COMMENT ON INDEX idx_perfdata_hourly_series_bucket_start IS 'Performance data graphs';
+COMMENT ON INDEX idx_perfdata_hourly_env_bucket_start IS 'Filter for perfdata rollups and retention';
+
+CREATE TABLE perfdata_daily (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  series_id bytea20 NOT NULL,
+
+  bucket_start biguint NOT NULL,
+  sample_count uint NOT NULL,
+  min_value double precision NOT NULL,
+  max_value double precision NOT NULL,
+  avg_value double precision NOT NULL,
+
+  CONSTRAINT pk_perfdata_daily PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_daily ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_daily ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_daily ALTER COLUMN series_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_daily_series_bucket_start ON perfdata_daily(series_id, bucket_start);
+CREATE INDEX idx_perfdata_daily_env_bucket_start ON perfdata_daily(environment_id, bucket_start);
+
+COMMENT ON COLUMN perfdata_daily.id IS 'sha1(perfdata_series.id + bucket_start)';
+COMMENT ON COLUMN perfdata_daily.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_daily.series_id IS 'perfdata_series.id';
+COMMENT ON COLUMN perfdata_daily.bucket_start IS 'unix timestamp of the beginning of the day (UTC)';
+
+COMMENT ON INDEX idx_perfdata_daily_series_bucket_start IS 'Performance data graphs';
+COMMENT ON INDEX idx_perfdata_daily_env_bucket_start IS 'Filter for perfdata rollups and retention';
+
+CREATE TABLE notifications_checkpoint (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  history_type notifications_checkpoint_history_type NOT NULL,
+  submit_time biguint NOT NULL,
+
+  CONSTRAINT pk_notifications_checkpoint PRIMARY KEY (id)
+);
+
+ALTER TABLE notifications_checkpoint ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE notifications_checkpoint ALTER COLUMN environment_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_notifications_checkpoint_submit_time ON notifications_checkpoint(submit_time);
+
+COMMENT ON COLUMN notifications_checkpoint.id IS 'history.id';
+COMMENT ON COLUMN notifications_checkpoint.environment_id IS 'environment.id';
+COMMENT ON COLUMN notifications_checkpoint.submit_time IS 'unix timestamp the event was submitted to Icinga Notifications';
+
+COMMENT ON INDEX idx_notifications_checkpoint_submit_time IS 'Filter for checkpoint cleanup';
+
+CREATE SEQUENCE notifications_outbox_id_seq;
+
+CREATE TABLE notifications_outbox (
+  id biguint NOT NULL DEFAULT nextval('notifications_outbox_id_seq'),
+  environment_id bytea20 NOT NULL,
+  object_id bytea20 NOT NULL,
+  object_type checkable_type NOT NULL,
+  state text NOT NULL,
+  enqueue_time biguint NOT NULL,
+  attempts uint NOT NULL DEFAULT 0,
+  last_attempt_time biguint DEFAULT NULL,
+  last_error text DEFAULT NULL,
+
+  CONSTRAINT pk_notifications_outbox PRIMARY KEY (id)
+);
+
+ALTER SEQUENCE notifications_outbox_id_seq OWNED BY notifications_outbox.id;
+
+ALTER TABLE notifications_outbox ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE notifications_outbox ALTER COLUMN object_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_notifications_outbox_environment_id ON notifications_outbox(environment_id, id);
+
+COMMENT ON COLUMN notifications_outbox.environment_id IS 'environment.id';
+COMMENT ON COLUMN notifications_outbox.object_id IS 'host.id or service.id';
+COMMENT ON COLUMN notifications_outbox.state IS 'JSON-encoded host or service state';
+
+COMMENT ON INDEX idx_notifications_outbox_environment_id IS 'Filter for queued states of an environment';
+
+CREATE TYPE ha_event_type AS ENUM ( 'takeover', 'handover' );
+
+CREATE TABLE icingadb_ha_history (
+  id bytea16 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  instance_id bytea16 NOT NULL,
+  endpoint_id bytea20 DEFAULT NULL,
+  event_type ha_event_type NOT NULL,
+  reason varchar(255) NOT NULL,
+  event_time biguint NOT NULL,
+  sync_duration biguint DEFAULT NULL,
+
+  CONSTRAINT pk_icingadb_ha_history PRIMARY KEY (id)
+);
+
+ALTER TABLE icingadb_ha_history ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE icingadb_ha_history ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE icingadb_ha_history ALTER COLUMN instance_id SET STORAGE PLAIN;
+ALTER TABLE icingadb_ha_history ALTER COLUMN endpoint_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_icingadb_ha_history_env_event_time ON icingadb_ha_history(environment_id, event_time);
+
+COMMENT ON COLUMN icingadb_ha_history.id IS 'UUIDv4';
+COMMENT ON COLUMN icingadb_ha_history.environment_id IS 'environment.id';
+COMMENT ON COLUMN icingadb_ha_history.instance_id IS 'icingadb_instance.id';
+COMMENT ON COLUMN icingadb_ha_history.endpoint_id IS 'endpoint.id';
+COMMENT ON COLUMN icingadb_ha_history.sync_duration IS 'milliseconds until the config sync after a takeover finished';
+
+COMMENT ON INDEX idx_icingadb_ha_history_env_event_time IS 'Filter for history retention';
+
 CREATE SEQUENCE icingadb_schema_id_seq;
 
 CREATE TABLE icingadb_schema (
@@ -0,0 +1,8 @@
+ALTER TABLE icingadb_instance
+  ADD COLUMN clock_skew_redis bigint NOT NULL DEFAULT 0,
+  ADD COLUMN clock_skew_database bigint NOT NULL DEFAULT 0,
+  ADD COLUMN clock_skew_icinga2 bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN icingadb_instance.clock_skew_redis IS 'milliseconds';
+COMMENT ON COLUMN icingadb_instance.clock_skew_database IS 'milliseconds';
+COMMENT ON COLUMN icingadb_instance.clock_skew_icinga2 IS 'milliseconds';
@@ -0,0 +1,29 @@
+CREATE TYPE ha_event_type AS ENUM ( 'takeover', 'handover' );
+
+CREATE TABLE icingadb_ha_history (
+  id bytea16 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  instance_id bytea16 NOT NULL,
+  endpoint_id bytea20 DEFAULT NULL,
+  event_type ha_event_type NOT NULL,
+  reason varchar(255) NOT NULL,
+  event_time biguint NOT NULL,
+  sync_duration biguint DEFAULT NULL,
+
+  CONSTRAINT pk_icingadb_ha_history PRIMARY KEY (id)
+);
+
+ALTER TABLE icingadb_ha_history ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE icingadb_ha_history ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE icingadb_ha_history ALTER COLUMN instance_id SET STORAGE PLAIN;
+ALTER TABLE icingadb_ha_history ALTER COLUMN endpoint_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_icingadb_ha_history_env_event_time ON icingadb_ha_history(environment_id, event_time);
+
+COMMENT ON COLUMN icingadb_ha_history.id IS 'UUIDv4';
+COMMENT ON COLUMN icingadb_ha_history.environment_id IS 'environment.id';
+COMMENT ON COLUMN icingadb_ha_history.instance_id IS 'icingadb_instance.id';
+COMMENT ON COLUMN icingadb_ha_history.endpoint_id IS 'endpoint.id';
+COMMENT ON COLUMN icingadb_ha_history.sync_duration IS 'milliseconds until the config sync after a takeover finished';
+
+COMMENT ON INDEX idx_icingadb_ha_history_env_event_time IS 'Filter for history retention';
@@ -0,0 +1 @@
+ALTER TABLE icingadb_instance ADD COLUMN priority smalluint NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE icingadb_instance ADD COLUMN standby boolenum NOT NULL DEFAULT 'n';
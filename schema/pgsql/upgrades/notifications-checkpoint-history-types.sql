@@ -0,0 +1,2 @@
+ALTER TYPE notifications_checkpoint_history_type ADD VALUE IF NOT EXISTS 'comment';
+ALTER TYPE notifications_checkpoint_history_type ADD VALUE IF NOT EXISTS 'notification';
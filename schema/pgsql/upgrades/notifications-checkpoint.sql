@@ -0,0 +1,21 @@
+CREATE TYPE notifications_checkpoint_history_type AS ENUM ( 'acknowledgement', 'downtime', 'flapping', 'comment', 'notification' );
+
+CREATE TABLE notifications_checkpoint (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  history_type notifications_checkpoint_history_type NOT NULL,
+  submit_time biguint NOT NULL,
+
+  CONSTRAINT pk_notifications_checkpoint PRIMARY KEY (id)
+);
+
+ALTER TABLE notifications_checkpoint ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE notifications_checkpoint ALTER COLUMN environment_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_notifications_checkpoint_submit_time ON notifications_checkpoint(submit_time);
+
+COMMENT ON COLUMN notifications_checkpoint.id IS 'history.id';
+COMMENT ON COLUMN notifications_checkpoint.environment_id IS 'environment.id';
+COMMENT ON COLUMN notifications_checkpoint.submit_time IS 'unix timestamp the event was submitted to Icinga Notifications';
+
+COMMENT ON INDEX idx_notifications_checkpoint_submit_time IS 'Filter for checkpoint cleanup';
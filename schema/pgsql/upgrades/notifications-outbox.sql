@@ -0,0 +1,28 @@
+CREATE SEQUENCE notifications_outbox_id_seq;
+
+CREATE TABLE notifications_outbox (
+  id biguint NOT NULL DEFAULT nextval('notifications_outbox_id_seq'),
+  environment_id bytea20 NOT NULL,
+  object_id bytea20 NOT NULL,
+  object_type checkable_type NOT NULL,
+  state text NOT NULL,
+  enqueue_time biguint NOT NULL,
+  attempts uint NOT NULL DEFAULT 0,
+  last_attempt_time biguint DEFAULT NULL,
+  last_error text DEFAULT NULL,
+
+  CONSTRAINT pk_notifications_outbox PRIMARY KEY (id)
+);
+
+ALTER SEQUENCE notifications_outbox_id_seq OWNED BY notifications_outbox.id;
+
+ALTER TABLE notifications_outbox ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE notifications_outbox ALTER COLUMN object_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_notifications_outbox_environment_id ON notifications_outbox(environment_id, id);
+
+COMMENT ON COLUMN notifications_outbox.environment_id IS 'environment.id';
+COMMENT ON COLUMN notifications_outbox.object_id IS 'host.id or service.id';
+COMMENT ON COLUMN notifications_outbox.state IS 'JSON-encoded host or service state';
+
+COMMENT ON INDEX idx_notifications_outbox_environment_id IS 'Filter for queued states of an environment';
@@ -0,0 +1,116 @@
+CREATE TABLE perfdata_series (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  object_type checkable_type NOT NULL,
+  host_id bytea20 NOT NULL,
+  service_id bytea20 DEFAULT NULL,
+
+  label varchar(255) NOT NULL,
+  unit varchar(255) NOT NULL,
+
+  CONSTRAINT pk_perfdata_series PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_series ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_series ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_series ALTER COLUMN host_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_series ALTER COLUMN service_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_series_host_service_id ON perfdata_series(host_id, service_id);
+
+COMMENT ON COLUMN perfdata_series.id IS 'sha1(environment.id + host.id + service.id + label)';
+COMMENT ON COLUMN perfdata_series.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_series.host_id IS 'host.id';
+COMMENT ON COLUMN perfdata_series.service_id IS 'service.id';
+
+COMMENT ON INDEX idx_perfdata_series_host_service_id IS 'Performance data series of a host/service';
+
+CREATE TABLE perfdata_sample (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  series_id bytea20 NOT NULL,
+
+  sample_time biguint NOT NULL,
+  value double precision NOT NULL,
+  warn varchar(255) DEFAULT NULL,
+  crit varchar(255) DEFAULT NULL,
+  min double precision DEFAULT NULL,
+  max double precision DEFAULT NULL,
+
+  CONSTRAINT pk_perfdata_sample PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_sample ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_sample ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_sample ALTER COLUMN series_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_sample_series_sample_time ON perfdata_sample(series_id, sample_time);
+CREATE INDEX idx_perfdata_sample_env_sample_time ON perfdata_sample(environment_id, sample_time);
+
+COMMENT ON COLUMN perfdata_sample.id IS 'sha1(perfdata_series.id + sample_time)';
+COMMENT ON COLUMN perfdata_sample.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_sample.series_id IS 'perfdata_series.id';
+COMMENT ON COLUMN perfdata_sample.sample_time IS 'unix timestamp of the check result';
+COMMENT ON COLUMN perfdata_sample.warn IS 'warning threshold range';
+COMMENT ON COLUMN perfdata_sample.crit IS 'critical threshold range';
+
+COMMENT ON INDEX idx_perfdata_sample_series_sample_time IS 'Performance data graphs';
+COMMENT ON INDEX idx_perfdata_sample_env_sample_time IS 'Filter for perfdata rollups and retention';
+
+CREATE TABLE perfdata_hourly (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  series_id bytea20 NOT NULL,
+
+  bucket_start biguint NOT NULL,
+  sample_count uint NOT NULL,
+  min_value double precision NOT NULL,
+  max_value double precision NOT NULL,
+  avg_value double precision NOT NULL,
+
+  CONSTRAINT pk_perfdata_hourly PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_hourly ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_hourly ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_hourly ALTER COLUMN series_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_hourly_series_bucket_start ON perfdata_hourly(series_id, bucket_start);
+CREATE INDEX idx_perfdata_hourly_env_bucket_start ON perfdata_hourly(environment_id, bucket_start);
+
+COMMENT ON COLUMN perfdata_hourly.id IS 'sha1(perfdata_series.id + bucket_start)';
+COMMENT ON COLUMN perfdata_hourly.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_hourly.series_id IS 'perfdata_series.id';
+COMMENT ON COLUMN perfdata_hourly.bucket_start IS 'unix timestamp of the beginning of the hour';
+
+COMMENT ON INDEX idx_perfdata_hourly_series_bucket_start IS 'Performance data graphs';
+COMMENT ON INDEX idx_perfdata_hourly_env_bucket_start IS 'Filter for perfdata rollups and retention';
+
+CREATE TABLE perfdata_daily (
+  id bytea20 NOT NULL,
+  environment_id bytea20 NOT NULL,
+  series_id bytea20 NOT NULL,
+
+  bucket_start biguint NOT NULL,
+  sample_count uint NOT NULL,
+  min_value double precision NOT NULL,
+  max_value double precision NOT NULL,
+  avg_value double precision NOT NULL,
+
+  CONSTRAINT pk_perfdata_daily PRIMARY KEY (id)
+);
+
+ALTER TABLE perfdata_daily ALTER COLUMN id SET STORAGE PLAIN;
+ALTER TABLE perfdata_daily ALTER COLUMN environment_id SET STORAGE PLAIN;
+ALTER TABLE perfdata_daily ALTER COLUMN series_id SET STORAGE PLAIN;
+
+CREATE INDEX idx_perfdata_daily_series_bucket_start ON perfdata_daily(series_id, bucket_start);
+CREATE INDEX idx_perfdata_daily_env_bucket_start ON perfdata_daily(environment_id, bucket_start);
+
+COMMENT ON COLUMN perfdata_daily.id IS 'sha1(perfdata_series.id + bucket_start)';
+COMMENT ON COLUMN perfdata_daily.environment_id IS 'environment.id';
+COMMENT ON COLUMN perfdata_daily.series_id IS 'perfdata_series.id';
+COMMENT ON COLUMN perfdata_daily.bucket_start IS 'unix timestamp of the beginning of the day (UTC)';
+
+COMMENT ON INDEX idx_perfdata_daily_series_bucket_start IS 'Performance data graphs';
+COMMENT ON INDEX idx_perfdata_daily_env_bucket_start IS 'Filter for perfdata rollups and retention';